@@ -2,14 +2,38 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
 	"go.uber.org/zap"
 	"net/http"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/helpers/rbac"
 	"xm-task/log"
+	"xm-task/smodels"
 )
 
 func (api *API) AuthMiddleware() gin.HandlerFunc {
+	return api.authenticate(api.services.ExtractTokenMetadata)
+}
+
+// StreamAuthMiddleware accepts stream tokens passed with the token parameter or the stream_token cookie
+// besides the Authorization header, since EventSource and browser WebSockets cannot set headers.
+func (api *API) StreamAuthMiddleware() gin.HandlerFunc {
+	return api.authenticate(func(c *gin.Context) (smodels.AccessDetails, error) {
+		if c.GetHeader("Authorization") != "" {
+			return api.services.ExtractTokenMetadata(c)
+		}
+		token := c.Query(StreamTokenParam)
+		if token == "" {
+			token, _ = c.Cookie(StreamTokenCookie)
+		}
+		return api.services.ExtractStreamTokenMetadata(token)
+	})
+}
+
+func (api *API) authenticate(extract func(c *gin.Context) (smodels.AccessDetails, error)) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ad, err := api.services.ExtractTokenMetadata(c)
+		ad, err := extract(c)
 		if err != nil {
 			log.Error("[api] AuthMiddleware: ExtractTokenMetadata", zap.Error(err))
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
@@ -25,15 +49,79 @@ func (api *API) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		user, err := api.services.GetUserByEmail(email)
+		// the user acts within the organization of the token, removed members are rejected
+		user, err := api.services.GetMember(email, uuid.FromStringOrNil(ad.OrganizationID))
 		if err != nil {
-			log.Error("[api] AuthMiddleware: GetUserByEmail", zap.Error(err))
+			log.Error("[api] AuthMiddleware: GetMember", zap.Error(err))
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
+		// the role is taken from the token, tokens minted before a role change have to be refreshed
+		if ad.Role != user.Role {
+			log.Error("[api] AuthMiddleware: outdated role", zap.String("email", email),
+				zap.String("token_role", ad.Role), zap.String("role", user.Role))
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "outdated token role"})
+			c.Abort()
+			return
+		}
+
 		c.Set("user", user)
+		c.Set("role", ad.Role)
+		c.Set("access", ad)
 		c.Next()
 	}
 }
+
+// RequirePermission lets through the roles granting the permission, it has to follow AuthMiddleware.
+func (api *API) RequirePermission(permission rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if !rbac.Can(role, permission) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":      local.Forbidden,
+				"reason":     local.ReasonMissingPermission,
+				"permission": permission,
+				"role":       role,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireOrganization lets through the members of the organization, it has to follow AuthMiddleware.
+func (api *API) RequireOrganization(orgID uuid.UUID) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if currentUser(c).OrganizationID != orgID {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":        local.Forbidden,
+				"reason":       local.ReasonOrganizationRequired,
+				"organization": orgID.String(),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// organizationOf returns the organization of the signed in user,
+// anonymous requests to public routes see the default organization.
+func organizationOf(c *gin.Context) uuid.UUID {
+	if user := currentUser(c); user.ID != uuid.Nil {
+		return user.OrganizationID
+	}
+	return dmodels.DefaultOrganizationID
+}
+
+// currentUser returns the user stored by AuthMiddleware.
+func currentUser(c *gin.Context) dmodels.User {
+	user, _ := c.Get("user")
+	u, _ := user.(dmodels.User)
+	return u
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
 	"xm-task/conf"
 )
 
@@ -17,3 +18,10 @@ func (api *API) Health(c *gin.Context) {
 		"status": status,
 	})
 }
+
+func uuidString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
@@ -1,15 +1,30 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
 	"go.uber.org/zap"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"xm-task/dmodels"
 	local "xm-task/helpers/errors"
 	"xm-task/log"
+	"xm-task/services"
 	"xm-task/smodels"
 )
 
 func (api *API) CreateCompany(c *gin.Context) {
+	var params smodels.CompanyCreateParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] CreateCompany: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
 	var company smodels.Company
 	if err := c.ShouldBindJSON(&company); err != nil {
 		log.Error("[api] CreateCompany: ShouldBindJSON", zap.Error(err))
@@ -23,82 +38,325 @@ func (api *API) CreateCompany(c *gin.Context) {
 		return
 	}
 
-	dbCompany, err := api.services.CreateCompany(company)
+	dbCompany, err := api.services.CreateCompany(company, params.AllowDuplicates, currentUser(c))
 	if err != nil {
 		log.Error("[api] CreateCompany: CreateCompany", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": local.ServiceError})
+		var duplicates *services.DuplicatesError
+		if errors.As(err, &duplicates) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      local.Conflict,
+				"reason":     local.ReasonPossibleDuplicates,
+				"duplicates": similarCompanies(duplicates.Companies),
+			})
+			return
+		}
+		c.JSON(serviceError(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, smodels.Company{
-		ID:          dbCompany.ID.String(),
-		Name:        dbCompany.Name,
-		Description: dbCompany.Description,
-		Employees:   dbCompany.Employees,
-		Registered:  dbCompany.Registered,
-		Type:        company.Type,
-	})
+	c.Header("ETag", etag(dbCompany.Version))
+
+	c.JSON(http.StatusOK, companyResponse(dbCompany))
 }
 
 func (api *API) PatchCompany(c *gin.Context) {
 	companyID := c.Param("id")
+	version, ok := api.ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		log.Error("[api] PatchCompany: GetRawData", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	company, err := api.services.PatchCompany(companyID, c.ContentType(), patch, version, currentUser(c))
+	if err != nil {
+		log.Error("[api] PatchCompany: PatchCompany", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
 
-	var updatedCompany smodels.Company
-	if err := c.ShouldBindJSON(&updatedCompany); err != nil {
-		log.Error("[api] PatchCompany: ShouldBindJSON", zap.Error(err))
+	c.Header("ETag", etag(company.Version))
+
+	c.JSON(http.StatusOK, companyResponse(company))
+}
+
+func (api *API) BatchCompanies(c *gin.Context) {
+	var batch smodels.CompanyBatch
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		log.Error("[api] BatchCompanies: ShouldBindJSON", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
 		return
 	}
 
-	if err := updatedCompany.Validate(); err != nil {
-		log.Error("[api] PatchCompany: Validate", zap.Error(err))
+	if err := batch.Validate(); err != nil {
+		log.Error("[api] BatchCompanies: Validate", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	updatedCompany.ID = companyID
-	dbCompany, err := api.services.UpdateCompany(updatedCompany)
+	results, err := api.services.BatchCompanies(batch, currentUser(c))
 	if err != nil {
-		log.Error("[api] PatchCompany: UpdateCompany", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": local.ServiceError})
+		log.Error("[api] BatchCompanies: BatchCompanies", zap.Error(err))
+		c.JSON(serviceError(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, smodels.Company{
-		ID:          dbCompany.ID.String(),
-		Name:        dbCompany.Name,
-		Description: dbCompany.Description,
-		Employees:   dbCompany.Employees,
-		Registered:  dbCompany.Registered,
-		Type:        updatedCompany.Type,
-	})
+	resp := smodels.CompanyBatchResponse{
+		Success: true,
+		Results: make([]smodels.CompanyBatchResult, 0, len(results)),
+		Errors:  make([]smodels.CompanyBatchResult, 0),
+	}
+	for i, result := range results {
+		item := smodels.CompanyBatchResult{
+			Index:  i,
+			Op:     result.Op,
+			ID:     batch.Operations[i].ID,
+			Status: http.StatusOK,
+		}
+		if result.Company.ID != uuid.Nil {
+			item.ID = result.Company.ID.String()
+		}
+		if result.Err != nil {
+			status, body := serviceError(result.Err)
+			item.Status = status
+			item.Error = fmt.Sprint(body["error"])
+			resp.Success = false
+			resp.Errors = append(resp.Errors, item)
+		} else if result.Op != dmodels.BatchOpDelete {
+			item.Version = result.Company.Version
+		}
+		resp.Results = append(resp.Results, item)
+	}
+
+	status := http.StatusOK
+	if !resp.Success {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, resp)
 }
 
 func (api *API) GetCompany(c *gin.Context) {
-	companyID := c.Param("id")
-	company, err := api.services.GetCompanyByID(companyID)
+	params, ok := companyEmbedParams(c)
+	if !ok {
+		return
+	}
+
+	company, err := api.services.GetCompanyByID(c.Param("id"), organizationOf(c))
 	if err != nil {
 		log.Error("[api] GetCompany: GetCompanyByID", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": local.ServiceError})
+		c.JSON(serviceError(err))
+		return
+	}
+
+	api.showCompany(c, company, params)
+}
+
+// GetCompanyBySlug responds like GetCompany, former slugs of renamed companies are redirected to the current one.
+func (api *API) GetCompanyBySlug(c *gin.Context) {
+	params, ok := companyEmbedParams(c)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, smodels.Company{
+	slug := c.Param("slug")
+	company, err := api.services.GetCompanyBySlug(slug, organizationOf(c))
+	if err != nil {
+		log.Error("[api] GetCompanyBySlug: GetCompanyBySlug", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	if company.Slug != slug {
+		location := url.URL{Path: path.Join(path.Dir(c.Request.URL.Path), company.Slug), RawQuery: c.Request.URL.RawQuery}
+		c.Redirect(http.StatusMovedPermanently, location.String())
+		return
+	}
+
+	api.showCompany(c, company, params)
+}
+
+func companyEmbedParams(c *gin.Context) (smodels.CompanyEmbedParams, bool) {
+	var params smodels.CompanyEmbedParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] companyEmbedParams: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return params, false
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] companyEmbedParams: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return params, false
+	}
+
+	// Contacts and addresses are only shown to signed in users.
+	if params.Any() && currentUser(c).ID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": local.UnauthorizedErr})
+		return params, false
+	}
+
+	return params, true
+}
+
+// companyResponse is the company as returned by the company routes.
+func companyResponse(company dmodels.CompanyShow) smodels.Company {
+	return smodels.Company{
 		ID:          company.ID.String(),
 		Name:        company.Name,
 		Description: company.Description,
 		Employees:   company.Employees,
 		Registered:  company.Registered,
 		Type:        company.Type,
-	})
+		DeletedAt:   company.DeletedAt,
+		OwnerID:     uuidString(company.OwnerID),
+		ParentID:    uuidString(company.ParentID),
+		Slug:        company.Slug,
+		Attributes:  company.Attributes,
+		LogoURL:     companyLogoURL(company.ID, company.LogoHash),
+	}
+}
+
+// showCompany responds with a single company along with the requested embeddings.
+func (api *API) showCompany(c *gin.Context, company dmodels.CompanyShow, params smodels.CompanyEmbedParams) {
+	// The version does not cover the embedded resources, so they are always sent in full.
+	c.Header("ETag", etag(company.Version))
+	if !params.Any() && notModified(c, company.Version) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	resp := companyResponse(company)
+	if err := api.embedCompany(&resp, params, c); err != nil {
+		log.Error("[api] showCompany: embedCompany", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *API) ListCompanies(c *gin.Context) {
+	api.listCompanies(c, false, "")
+}
+
+func (api *API) ListTrashedCompanies(c *gin.Context) {
+	api.listCompanies(c, true, "")
+}
+
+// listCompanies responds with a page of live or trashed companies, a non-empty parent limits
+// the listing to its direct subsidiaries.
+func (api *API) listCompanies(c *gin.Context, trashed bool, parentID string) {
+	var params smodels.CompanyListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] ListCompanies: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+	params.Trashed = trashed
+	params.ParentID = parentID
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] ListCompanies: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !resolveFilter(c, &params.CompanyFilterParams) {
+		return
+	}
+
+	page, err := api.services.ListCompanies(params)
+	if err != nil {
+		log.Error("[api] ListCompanies: ListCompanies", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	list := smodels.CompanyList{
+		Companies: make([]smodels.Company, 0, len(page.Companies)),
+		Total:     page.Total,
+		Limit:     params.Limit,
+		Offset:    params.Offset,
+	}
+	for _, company := range page.Companies {
+		list.Companies = append(list.Companies, companyResponse(company))
+	}
+
+	if params.Offset != nil {
+		list.Links = offsetLinks(c.Request.URL, *params.Offset, params.Limit, page.Total)
+	} else {
+		if page.NextCursor != "" {
+			list.Links.Next = pageLink(c.Request.URL, "cursor", page.NextCursor)
+		}
+		if page.PrevCursor != "" {
+			list.Links.Prev = pageLink(c.Request.URL, "cursor", page.PrevCursor)
+		}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (api *API) SearchCompanies(c *gin.Context) {
+	var params smodels.CompanySearchParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] SearchCompanies: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] SearchCompanies: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !resolveFilter(c, &params.CompanyFilterParams) {
+		return
+	}
+
+	results, total, err := api.services.SearchCompanies(params)
+	if err != nil {
+		log.Error("[api] SearchCompanies: SearchCompanies", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": local.ServiceError})
+		return
+	}
+
+	list := smodels.CompanySearchList{
+		Results: make([]smodels.CompanySearchResult, 0, len(results)),
+		Total:   total,
+		Limit:   params.Limit,
+		Offset:  params.Offset,
+	}
+	for _, result := range results {
+		list.Results = append(list.Results, smodels.CompanySearchResult{
+			Company: companyResponse(result.CompanyShow),
+			Rank:    result.Rank,
+			Highlights: smodels.Highlights{
+				Name:        result.NameHighlight,
+				Description: result.Snippet,
+			},
+		})
+	}
+
+	list.Links = offsetLinks(c.Request.URL, params.Offset, params.Limit, total)
+
+	c.JSON(http.StatusOK, list)
 }
 
 func (api *API) DeleteCompany(c *gin.Context) {
 	companyID := c.Param("id")
-	err := api.services.DeleteCompanyByID(companyID)
+	version, ok := api.ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	err := api.services.DeleteCompanyByID(companyID, version, currentUser(c))
 	if err != nil {
 		log.Error("[api] DeleteCompany: DeleteCompanyByID", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": local.ServiceError})
+		c.JSON(serviceError(err))
 		return
 	}
 
@@ -106,3 +364,84 @@ func (api *API) DeleteCompany(c *gin.Context) {
 		"success": true,
 	})
 }
+
+func (api *API) RestoreCompany(c *gin.Context) {
+	companyID := c.Param("id")
+	company, err := api.services.RestoreCompanyByID(companyID, currentUser(c))
+	if err != nil {
+		log.Error("[api] RestoreCompany: RestoreCompanyByID", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.Header("ETag", etag(company.Version))
+
+	c.JSON(http.StatusOK, companyResponse(company))
+}
+
+func (api *API) TransferCompany(c *gin.Context) {
+	companyID := c.Param("id")
+	version, ok := api.ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var transfer smodels.CompanyTransfer
+	if err := c.ShouldBindJSON(&transfer); err != nil {
+		log.Error("[api] TransferCompany: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	company, err := api.services.TransferCompany(companyID, transfer.OwnerEmail, version, currentUser(c))
+	if err != nil {
+		log.Error("[api] TransferCompany: TransferCompany", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.Header("ETag", etag(company.Version))
+
+	c.JSON(http.StatusOK, companyResponse(company))
+}
+
+// resolveFilter scopes the listing to the organization of the caller and turns owner=me
+// into the id of the signed in user, anonymous requests get 401 for the latter.
+func resolveFilter(c *gin.Context, params *smodels.CompanyFilterParams) bool {
+	params.OrganizationID = organizationOf(c).String()
+	if params.Owner != smodels.OwnerMe {
+		return true
+	}
+
+	user := currentUser(c)
+	if user.ID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": local.UnauthorizedErr})
+		return false
+	}
+
+	params.OwnerID = user.ID.String()
+	return true
+}
+
+// pageLink returns the request URL with a single pagination parameter replaced.
+func pageLink(u *url.URL, key, value string) string {
+	query := u.Query()
+	query.Set(key, value)
+	link := url.URL{Path: u.Path, RawQuery: query.Encode()}
+	return link.String()
+}
+
+func offsetLinks(u *url.URL, offset, limit int, total int64) smodels.Links {
+	var links smodels.Links
+	if int64(offset+limit) < total {
+		links.Next = pageLink(u, "offset", strconv.Itoa(offset+limit))
+	}
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links.Prev = pageLink(u, "offset", strconv.Itoa(prev))
+	}
+	return links
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/conf"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+// multipartOverhead is allowed on top of the file size for the rest of the multipart body.
+const multipartOverhead = 1 << 20
+
+func (api *API) ListCompanyAttachments(c *gin.Context) {
+	attachments, err := api.services.ListCompanyAttachments(c.Param("id"), organizationOf(c))
+	if err != nil {
+		log.Error("[api] ListCompanyAttachments: ListCompanyAttachments", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := make([]smodels.CompanyAttachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		resp = append(resp, companyAttachment(attachment))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *API) GetCompanyAttachment(c *gin.Context) {
+	attachment, err := api.services.GetCompanyAttachment(c.Param("id"), c.Param("attachment"), organizationOf(c))
+	if err != nil {
+		log.Error("[api] GetCompanyAttachment: GetCompanyAttachment", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyAttachment(attachment))
+}
+
+// DownloadCompanyAttachment streams the content with the checksum in the Digest header (RFC 3230).
+func (api *API) DownloadCompanyAttachment(c *gin.Context) {
+	attachment, content, err := api.services.OpenCompanyAttachment(c.Param("id"), c.Param("attachment"), organizationOf(c))
+	if err != nil {
+		log.Error("[api] DownloadCompanyAttachment: OpenCompanyAttachment", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+	defer content.Close()
+
+	c.Header("ETag", fmt.Sprintf(`"%s"`, attachment.Checksum))
+	if sum, err := hex.DecodeString(attachment.Checksum); err == nil {
+		c.Header("Digest", "sha-256="+base64.StdEncoding.EncodeToString(sum))
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+	})
+}
+
+// UploadCompanyAttachment takes a multipart "file" of up to Attachments.MaxFileSize bytes.
+func (api *API) UploadCompanyAttachment(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, api.maxAttachmentSize()+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		log.Error("[api] UploadCompanyAttachment: FormFile", zap.Error(err))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": local.RequestTooLarge})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+	if fileHeader.Size > api.maxAttachmentSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": local.RequestTooLarge})
+		return
+	}
+
+	fileName, err := smodels.AttachmentFileName(fileHeader.Filename)
+	if err != nil {
+		log.Error("[api] UploadCompanyAttachment: AttachmentFileName", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Error("[api] UploadCompanyAttachment: Open", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": local.ServiceError})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := api.services.UploadCompanyAttachment(c.Param("id"), fileName, file, currentUser(c))
+	if err != nil {
+		log.Error("[api] UploadCompanyAttachment: UploadCompanyAttachment", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyAttachment(attachment))
+}
+
+func (api *API) DeleteCompanyAttachment(c *gin.Context) {
+	err := api.services.DeleteCompanyAttachment(c.Param("id"), c.Param("attachment"), currentUser(c))
+	if err != nil {
+		log.Error("[api] DeleteCompanyAttachment: DeleteCompanyAttachment", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+func (api *API) maxAttachmentSize() int64 {
+	if api.cfg.Attachments.MaxFileSize > 0 {
+		return api.cfg.Attachments.MaxFileSize
+	}
+	return conf.DefaultAttachmentsMaxFileSize
+}
+
+func companyAttachment(attachment dmodels.CompanyAttachment) smodels.CompanyAttachment {
+	resp := smodels.CompanyAttachment{
+		ID:          attachment.ID.String(),
+		FileName:    attachment.FileName,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Checksum:    attachment.Checksum,
+		AuthorEmail: attachment.AuthorEmail,
+		CreatedAt:   attachment.CreatedAt,
+	}
+	if attachment.AuthorID.Valid {
+		resp.AuthorID = attachment.AuthorID.UUID.String()
+	}
+	return resp
+}
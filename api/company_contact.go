@@ -0,0 +1,243 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+func (api *API) ListCompanyContacts(c *gin.Context) {
+	contacts, err := api.services.ListCompanyContacts(c.Param("id"), organizationOf(c))
+	if err != nil {
+		log.Error("[api] ListCompanyContacts: ListCompanyContacts", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := make([]smodels.CompanyContact, 0, len(contacts))
+	for _, contact := range contacts {
+		resp = append(resp, companyContact(contact))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *API) GetCompanyContact(c *gin.Context) {
+	contact, err := api.services.GetCompanyContact(c.Param("id"), c.Param("contact"), organizationOf(c))
+	if err != nil {
+		log.Error("[api] GetCompanyContact: GetCompanyContact", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyContact(contact))
+}
+
+func (api *API) CreateCompanyContact(c *gin.Context) {
+	var contact smodels.CompanyContact
+	if err := c.ShouldBindJSON(&contact); err != nil {
+		log.Error("[api] CreateCompanyContact: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := contact.Validate(); err != nil {
+		log.Error("[api] CreateCompanyContact: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := api.services.CreateCompanyContact(c.Param("id"), contact, currentUser(c))
+	if err != nil {
+		log.Error("[api] CreateCompanyContact: CreateCompanyContact", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyContact(created))
+}
+
+func (api *API) UpdateCompanyContact(c *gin.Context) {
+	var contact smodels.CompanyContact
+	if err := c.ShouldBindJSON(&contact); err != nil {
+		log.Error("[api] UpdateCompanyContact: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := contact.Validate(); err != nil {
+		log.Error("[api] UpdateCompanyContact: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := api.services.UpdateCompanyContact(c.Param("id"), c.Param("contact"), contact, currentUser(c))
+	if err != nil {
+		log.Error("[api] UpdateCompanyContact: UpdateCompanyContact", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyContact(updated))
+}
+
+func (api *API) DeleteCompanyContact(c *gin.Context) {
+	err := api.services.DeleteCompanyContact(c.Param("id"), c.Param("contact"), currentUser(c))
+	if err != nil {
+		log.Error("[api] DeleteCompanyContact: DeleteCompanyContact", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+func (api *API) ListCompanyAddresses(c *gin.Context) {
+	addresses, err := api.services.ListCompanyAddresses(c.Param("id"), organizationOf(c))
+	if err != nil {
+		log.Error("[api] ListCompanyAddresses: ListCompanyAddresses", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := make([]smodels.CompanyAddress, 0, len(addresses))
+	for _, address := range addresses {
+		resp = append(resp, companyAddress(address))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *API) GetCompanyAddress(c *gin.Context) {
+	address, err := api.services.GetCompanyAddress(c.Param("id"), c.Param("address"), organizationOf(c))
+	if err != nil {
+		log.Error("[api] GetCompanyAddress: GetCompanyAddress", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyAddress(address))
+}
+
+func (api *API) CreateCompanyAddress(c *gin.Context) {
+	var address smodels.CompanyAddress
+	if err := c.ShouldBindJSON(&address); err != nil {
+		log.Error("[api] CreateCompanyAddress: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := address.Validate(); err != nil {
+		log.Error("[api] CreateCompanyAddress: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := api.services.CreateCompanyAddress(c.Param("id"), address, currentUser(c))
+	if err != nil {
+		log.Error("[api] CreateCompanyAddress: CreateCompanyAddress", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyAddress(created))
+}
+
+func (api *API) UpdateCompanyAddress(c *gin.Context) {
+	var address smodels.CompanyAddress
+	if err := c.ShouldBindJSON(&address); err != nil {
+		log.Error("[api] UpdateCompanyAddress: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := address.Validate(); err != nil {
+		log.Error("[api] UpdateCompanyAddress: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := api.services.UpdateCompanyAddress(c.Param("id"), c.Param("address"), address, currentUser(c))
+	if err != nil {
+		log.Error("[api] UpdateCompanyAddress: UpdateCompanyAddress", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyAddress(updated))
+}
+
+func (api *API) DeleteCompanyAddress(c *gin.Context) {
+	err := api.services.DeleteCompanyAddress(c.Param("id"), c.Param("address"), currentUser(c))
+	if err != nil {
+		log.Error("[api] DeleteCompanyAddress: DeleteCompanyAddress", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// embedCompany adds the requested related resources to the company response.
+func (api *API) embedCompany(company *smodels.Company, params smodels.CompanyEmbedParams, c *gin.Context) error {
+	if params.PrimaryContact {
+		contact, err := api.services.PrimaryCompanyContact(company.ID, organizationOf(c))
+		if err != nil {
+			return err
+		}
+		if contact != nil {
+			embedded := companyContact(*contact)
+			company.PrimaryContact = &embedded
+		}
+	}
+
+	if params.PrimaryAddress {
+		address, err := api.services.PrimaryCompanyAddress(company.ID, organizationOf(c))
+		if err != nil {
+			return err
+		}
+		if address != nil {
+			embedded := companyAddress(*address)
+			company.PrimaryAddress = &embedded
+		}
+	}
+
+	return nil
+}
+
+func companyContact(contact dmodels.CompanyContact) smodels.CompanyContact {
+	return smodels.CompanyContact{
+		ID:        contact.ID.String(),
+		Name:      contact.Name,
+		Email:     contact.Email,
+		Phone:     contact.Phone,
+		Position:  contact.Position,
+		Primary:   contact.Primary,
+		CreatedAt: contact.CreatedAt,
+		UpdatedAt: contact.UpdatedAt,
+	}
+}
+
+func companyAddress(address dmodels.CompanyAddress) smodels.CompanyAddress {
+	return smodels.CompanyAddress{
+		ID:         address.ID.String(),
+		Line1:      address.Line1,
+		Line2:      address.Line2,
+		City:       address.City,
+		Region:     address.Region,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+		Primary:    address.Primary,
+		CreatedAt:  address.CreatedAt,
+		UpdatedAt:  address.UpdatedAt,
+	}
+}
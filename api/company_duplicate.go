@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+// ListCompanyDuplicates reports the clusters of live companies with similar names.
+func (api *API) ListCompanyDuplicates(c *gin.Context) {
+	var params smodels.CompanyDuplicatesParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] ListCompanyDuplicates: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] ListCompanyDuplicates: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	clusters, truncated, err := api.services.CompanyDuplicates(organizationOf(c), params.Threshold)
+	if err != nil {
+		log.Error("[api] ListCompanyDuplicates: CompanyDuplicates", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := smodels.CompanyDuplicates{
+		Clusters:  make([]smodels.CompanyDuplicateCluster, 0, len(clusters)),
+		Truncated: truncated,
+	}
+	for _, cluster := range clusters {
+		resp.Clusters = append(resp.Clusters, smodels.CompanyDuplicateCluster{
+			Companies:  similarCompanies(cluster.Companies),
+			Similarity: cluster.Similarity,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func similarCompanies(companies []dmodels.SimilarCompany) []smodels.SimilarCompany {
+	resp := make([]smodels.SimilarCompany, 0, len(companies))
+	for _, company := range companies {
+		resp = append(resp, smodels.SimilarCompany{
+			ID:         company.ID.String(),
+			Name:       company.Name,
+			Slug:       company.Slug,
+			Similarity: company.Similarity,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+// ExportCompanies streams companies in the format given by the "format" parameter or the
+// Accept header. An error which happens after the first byte was sent just cuts the body,
+// the client can resume with "after" set to the id of the last received company.
+func (api *API) ExportCompanies(c *gin.Context) {
+	var params smodels.CompanyExportParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] ExportCompanies: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if params.Format == "" {
+		params.Format = smodels.ExportFormat(c.NegotiateFormat(smodels.ExportContentTypes...))
+		if params.Format == "" {
+			c.JSON(http.StatusNotAcceptable, gin.H{"error": local.NotAcceptable})
+			return
+		}
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] ExportCompanies: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !resolveFilter(c, &params.CompanyFilterParams) {
+		return
+	}
+
+	c.Header("Content-Type", smodels.ExportContentType(params.Format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="companies.%s"`, params.Format))
+
+	summary, err := api.services.ExportCompanies(c.Writer, params)
+	if err != nil {
+		log.Error("[api] ExportCompanies: ExportCompanies", zap.Error(err), zap.String("last", summary.Last))
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.JSON(serviceError(err))
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"xm-task/conf"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/services"
+	"xm-task/smodels"
+)
+
+const (
+	LastEventIDHeader = "Last-Event-ID"
+	// StreamTokenParam and StreamTokenCookie pass stream tokens, see StreamAuthMiddleware.
+	StreamTokenParam  = "token"
+	StreamTokenCookie = "stream_token"
+
+	// feedRetry is how long EventSource clients wait before resuming a closed stream.
+	feedRetry        = time.Second * 3
+	feedWriteTimeout = time.Second * 10
+	// feedReadLimit limits the messages of WebSocket clients, they are not expected to send any.
+	feedReadLimit = 512
+	// streamPath limits the stream token cookie to the streams.
+	streamPath = "/auth/companies/stream"
+)
+
+// StreamCompanyEvents sends the company events as Server-Sent Events. Every event has its id,
+// so that EventSource resumes the stream with the Last-Event-ID header after a disconnect.
+func (api *API) StreamCompanyEvents(c *gin.Context) {
+	sub, ok := api.subscribeCompanyEvents(c, "StreamCompanyEvents")
+	if !ok {
+		return
+	}
+	defer api.services.UnsubscribeCompanyEvents(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// proxies should not buffer the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", feedRetry.Milliseconds()); err != nil {
+		return
+	}
+	if sub.Reset {
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: {}\n\n", smodels.EventFeedReset); err != nil {
+			return
+		}
+	}
+	for _, event := range sub.Backlog {
+		if err := writeServerSentEvent(w, companyEvent(event)); err != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(api.feedHeartbeat())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if sub.Replayed(event.ID) {
+				continue
+			}
+			if err := writeServerSentEvent(w, companyEvent(event)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		w.Flush()
+	}
+}
+
+// StreamCompanyEventsWebSocket sends the company events as JSON messages over a WebSocket. Clients resume the feed
+// with the last_event_id parameter, the connection is closed with 1013 when they have to.
+func (api *API) StreamCompanyEventsWebSocket(c *gin.Context) {
+	sub, ok := api.subscribeCompanyEvents(c, "StreamCompanyEventsWebSocket")
+	if !ok {
+		return
+	}
+	defer api.services.UnsubscribeCompanyEvents(sub)
+
+	upgrader := websocket.Upgrader{CheckOrigin: api.allowedOrigin}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the handshake is answered by the upgrader
+		log.Error("[api] StreamCompanyEventsWebSocket: Upgrade", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	heartbeat := api.feedHeartbeat()
+	// clients only send control frames, reading answers them and notices when the client is gone
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		conn.SetReadLimit(feedReadLimit)
+		_ = conn.SetReadDeadline(time.Now().Add(heartbeat * 2))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(heartbeat * 2))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(v interface{}) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(feedWriteTimeout))
+		return conn.WriteJSON(v)
+	}
+	if sub.Reset {
+		if err := write(gin.H{"event": smodels.EventFeedReset}); err != nil {
+			return
+		}
+	}
+	for _, event := range sub.Backlog {
+		if err := write(companyEvent(event)); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "resume the feed")
+				_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(feedWriteTimeout))
+				return
+			}
+			if sub.Replayed(event.ID) {
+				continue
+			}
+			if err := write(companyEvent(event)); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(feedWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// CreateStreamToken returns a token opening the streams of the organization for a short while, it is set
+// as a cookie sent with the stream requests only as well.
+func (api *API) CreateStreamToken(c *gin.Context) {
+	access, _ := c.Get("access")
+	ad, _ := access.(smodels.AccessDetails)
+	token, err := api.services.CreateStreamToken(ad)
+	if err != nil {
+		log.Error("[api] CreateStreamToken: CreateStreamToken", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	maxAge := int(time.Until(time.Unix(token.ExpiresAt, 0)).Seconds())
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(StreamTokenCookie, token.Token, maxAge, streamPath, "", c.Request.TLS != nil, true)
+	c.JSON(http.StatusOK, token)
+}
+
+// subscribeCompanyEvents subscribes to the events of the organization, the Last-Event-ID header
+// takes precedence over the last_event_id parameter.
+func (api *API) subscribeCompanyEvents(c *gin.Context, handler string) (*services.CompanyFeedSubscription, bool) {
+	var params smodels.CompanyFeedParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] "+handler+": ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return nil, false
+	}
+	if header := c.GetHeader(LastEventIDHeader); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			log.Error("[api] "+handler+": ParseInt", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("incorrect %s", LastEventIDHeader)})
+			return nil, false
+		}
+		params.LastEventID = id
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] "+handler+": Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	sub, err := api.services.SubscribeCompanyEvents(params, organizationOf(c))
+	if err != nil {
+		log.Error("[api] "+handler+": SubscribeCompanyEvents", zap.Error(err))
+		c.JSON(serviceError(err))
+		return nil, false
+	}
+	return sub, true
+}
+
+// allowedOrigin applies the CORS origins to WebSocket handshakes, which are not subject to CORS.
+func (api *API) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range api.cfg.API.CORSAllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func (api *API) feedHeartbeat() time.Duration {
+	if api.cfg.Feed.Heartbeat > 0 {
+		return api.cfg.Feed.Heartbeat
+	}
+	return conf.DefaultFeedHeartbeat
+}
+
+func writeServerSentEvent(w io.Writer, event smodels.CompanyEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Event, data)
+	return err
+}
+
+func companyEvent(event dmodels.CompanyEvent) smodels.CompanyEvent {
+	return smodels.CompanyEvent{
+		ID:        event.ID,
+		Event:     event.Event,
+		CompanyID: event.CompanyID.String(),
+		Type:      event.Type,
+		Version:   event.Version,
+		CreatedAt: event.CreatedAt,
+	}
+}
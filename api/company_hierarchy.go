@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+func (api *API) GetCompanyAncestors(c *gin.Context) {
+	var params smodels.CompanyTreeParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] GetCompanyAncestors: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] GetCompanyAncestors: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	nodes, err := api.services.CompanyAncestors(c.Param("id"), params.Depth, organizationOf(c))
+	if err != nil {
+		log.Error("[api] GetCompanyAncestors: CompanyAncestors", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := make([]smodels.CompanyNode, 0, len(nodes))
+	for _, node := range nodes {
+		resp = append(resp, companyNode(node))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *API) ListCompanyChildren(c *gin.Context) {
+	companyID := c.Param("id")
+	if _, err := api.services.GetCompanyByID(companyID, organizationOf(c)); err != nil {
+		log.Error("[api] ListCompanyChildren: GetCompanyByID", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	api.listCompanies(c, false, companyID)
+}
+
+func (api *API) GetCompanySubtree(c *gin.Context) {
+	var params smodels.CompanyTreeParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] GetCompanySubtree: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] GetCompanySubtree: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	nodes, truncated, err := api.services.CompanySubtree(c.Param("id"), params.Depth, organizationOf(c))
+	if err != nil {
+		log.Error("[api] GetCompanySubtree: CompanySubtree", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := smodels.CompanySubtree{
+		Companies: make([]smodels.CompanyNode, 0, len(nodes)),
+		Truncated: truncated,
+	}
+	for _, node := range nodes {
+		resp.Companies = append(resp.Companies, companyNode(node))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *API) GetCompanyGroup(c *gin.Context) {
+	companyID := c.Param("id")
+	group, err := api.services.CompanyGroup(companyID, organizationOf(c))
+	if err != nil {
+		log.Error("[api] GetCompanyGroup: CompanyGroup", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, smodels.CompanyGroup{
+		ID:        companyID,
+		Companies: group.Companies,
+		Employees: group.Employees,
+		Depth:     group.Depth,
+	})
+}
+
+func (api *API) SetCompanyParent(c *gin.Context) {
+	companyID := c.Param("id")
+	version, ok := api.ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var parent smodels.CompanyParent
+	if err := c.ShouldBindJSON(&parent); err != nil {
+		log.Error("[api] SetCompanyParent: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := parent.Validate(); err != nil {
+		log.Error("[api] SetCompanyParent: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var parentID string
+	if parent.ParentID != nil {
+		parentID = *parent.ParentID
+	}
+
+	company, err := api.services.SetCompanyParent(companyID, parentID, version, currentUser(c))
+	if err != nil {
+		log.Error("[api] SetCompanyParent: SetCompanyParent", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.Header("ETag", etag(company.Version))
+
+	c.JSON(http.StatusOK, companyResponse(company))
+}
+
+func companyNode(node dmodels.CompanyNode) smodels.CompanyNode {
+	return smodels.CompanyNode{
+		Company: companyResponse(node.CompanyShow),
+		Depth:   node.Depth,
+	}
+}
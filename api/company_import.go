@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/conf"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+// ImportCompanies takes a multipart "file" and streams back {"rows": [...], "summary": {...}}.
+// The rows are written as soon as each chunk is processed, an error which happens after
+// that is reported in the "error" field next to the summary.
+func (api *API) ImportCompanies(c *gin.Context) {
+	maxSize := api.cfg.Import.MaxFileSize
+	if maxSize <= 0 {
+		maxSize = conf.DefaultImportMaxFileSize
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+
+	var params smodels.CompanyImportParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] ImportCompanies: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+	params.Mapping = c.QueryMap("mapping")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		log.Error("[api] ImportCompanies: FormFile", zap.Error(err))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": local.RequestTooLarge})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+	if params.Format == "" {
+		params.Format = smodels.ImportFormat(fileHeader.Filename)
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] ImportCompanies: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Error("[api] ImportCompanies: Open", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": local.ServiceError})
+		return
+	}
+	defer file.Close()
+
+	w := importWriter{c: c, encoder: json.NewEncoder(c.Writer)}
+	summary, err := api.services.ImportCompanies(file, params, currentUser(c), w.row)
+	if err != nil {
+		log.Error("[api] ImportCompanies: ImportCompanies", zap.Error(err))
+		if !w.started {
+			c.JSON(serviceError(err))
+			return
+		}
+	}
+
+	w.finish(summary, err)
+}
+
+// importWriter writes the import report piece by piece.
+type importWriter struct {
+	c       *gin.Context
+	encoder *json.Encoder
+	started bool
+}
+
+func (w *importWriter) start() {
+	w.c.Header("Content-Type", "application/json; charset=utf-8")
+	w.c.Status(http.StatusOK)
+	w.c.Writer.WriteString(`{"rows":[`)
+	w.started = true
+}
+
+func (w *importWriter) row(row smodels.CompanyImportRow) error {
+	if !w.started {
+		w.start()
+	} else if _, err := w.c.Writer.WriteString(","); err != nil {
+		return err
+	}
+	return w.encoder.Encode(row)
+}
+
+func (w *importWriter) finish(summary smodels.CompanyImportSummary, err error) {
+	if !w.started {
+		w.start()
+	}
+	w.c.Writer.WriteString(`],"summary":`)
+	w.encoder.Encode(summary)
+	if err != nil {
+		_, body := serviceError(err)
+		w.c.Writer.WriteString(`,"error":`)
+		w.encoder.Encode(body["error"])
+	}
+	w.c.Writer.WriteString("}")
+}
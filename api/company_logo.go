@@ -0,0 +1,152 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+	"xm-task/conf"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+// logoCacheControl lets clients and proxies keep logos for good, the hash in the URL changes with the logo.
+const logoCacheControl = "public, max-age=31536000, immutable"
+
+func (api *API) GetCompanyLogo(c *gin.Context) {
+	logo, err := api.services.GetCompanyLogo(c.Param("id"), organizationOf(c))
+	if err != nil {
+		log.Error("[api] GetCompanyLogo: GetCompanyLogo", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyLogo(logo))
+}
+
+// ServeCompanyLogo serves the logos without authentication, the URLs are only known through the companies.
+func (api *API) ServeCompanyLogo(c *gin.Context) {
+	var size int
+	if param := c.Param("size"); param != smodels.LogoOriginal {
+		var err error
+		if size, err = strconv.Atoi(param); err != nil || size <= 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": local.NotFound})
+			return
+		}
+	}
+
+	tag := fmt.Sprintf(`"%s-%s"`, c.Param("hash"), c.Param("size"))
+	logo, content, err := api.services.OpenCompanyLogo(c.Param("id"), c.Param("hash"), size)
+	if err != nil {
+		log.Error("[api] ServeCompanyLogo: OpenCompanyLogo", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+	defer content.Close()
+
+	c.Header("Cache-Control", logoCacheControl)
+	c.Header("ETag", tag)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if c.GetHeader("If-None-Match") == tag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, -1, logo.ContentType, content, nil)
+}
+
+// UploadCompanyLogo takes a multipart "file" of up to Logos.MaxFileSize bytes.
+func (api *API) UploadCompanyLogo(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, api.maxLogoSize()+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		log.Error("[api] UploadCompanyLogo: FormFile", zap.Error(err))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": local.RequestTooLarge})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+	if fileHeader.Size > api.maxLogoSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": local.RequestTooLarge})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Error("[api] UploadCompanyLogo: Open", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": local.ServiceError})
+		return
+	}
+	defer file.Close()
+
+	logo, err := api.services.UploadCompanyLogo(c.Param("id"), file, currentUser(c))
+	if err != nil {
+		log.Error("[api] UploadCompanyLogo: UploadCompanyLogo", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyLogo(logo))
+}
+
+func (api *API) DeleteCompanyLogo(c *gin.Context) {
+	err := api.services.DeleteCompanyLogo(c.Param("id"), currentUser(c))
+	if err != nil {
+		log.Error("[api] DeleteCompanyLogo: DeleteCompanyLogo", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+func (api *API) maxLogoSize() int64 {
+	if api.cfg.Logos.MaxFileSize > 0 {
+		return api.cfg.Logos.MaxFileSize
+	}
+	return conf.DefaultLogosMaxFileSize
+}
+
+// companyLogoURL returns the URL of the original logo, or nothing for companies without a logo.
+func companyLogoURL(companyID uuid.UUID, hash string) string {
+	if hash == "" {
+		return ""
+	}
+	return logoURL(companyID, hash, smodels.LogoOriginal)
+}
+
+func logoURL(companyID uuid.UUID, hash, size string) string {
+	return fmt.Sprintf("/companies/%s/logo/%s/%s", companyID, hash, size)
+}
+
+func companyLogo(logo dmodels.CompanyLogo) smodels.CompanyLogo {
+	resp := smodels.CompanyLogo{
+		URL:         logoURL(logo.CompanyID, logo.Hash, smodels.LogoOriginal),
+		Thumbnails:  make(map[string]string, len(logo.Sizes)),
+		ContentType: logo.ContentType,
+		Width:       logo.Width,
+		Height:      logo.Height,
+		Size:        logo.Size,
+		CreatedAt:   logo.CreatedAt,
+	}
+	for _, size := range logo.Sizes {
+		s := strconv.FormatInt(size, 10)
+		resp.Thumbnails[s] = logoURL(logo.CompanyID, logo.Hash, s)
+	}
+	if logo.AuthorID.Valid {
+		resp.AuthorID = logo.AuthorID.UUID.String()
+	}
+	return resp
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+func (api *API) ListCompanyRevisions(c *gin.Context) {
+	companyID := c.Param("id")
+	revisions, err := api.services.ListCompanyRevisions(companyID, currentUser(c).OrganizationID)
+	if err != nil {
+		log.Error("[api] ListCompanyRevisions: ListCompanyRevisions", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := make([]smodels.CompanyRevision, 0, len(revisions))
+	for _, rev := range revisions {
+		resp = append(resp, revisionResponse(rev))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *API) GetCompanyRevision(c *gin.Context) {
+	revision, err := strconv.ParseUint(c.Param("revision"), 10, 64)
+	if err != nil {
+		log.Error("[api] GetCompanyRevision: ParseUint", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	rev, err := api.services.GetCompanyRevision(c.Param("id"), currentUser(c).OrganizationID, revision)
+	if err != nil {
+		log.Error("[api] GetCompanyRevision: GetCompanyRevision", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, revisionResponse(rev))
+}
+
+func (api *API) DiffCompanyRevisions(c *gin.Context) {
+	from, errFrom := strconv.ParseUint(c.Query("from"), 10, 64)
+	to, errTo := strconv.ParseUint(c.Query("to"), 10, 64)
+	if errFrom != nil || errTo != nil {
+		log.Error("[api] DiffCompanyRevisions: ParseUint", zap.Error(fmt.Errorf("incorrect revisions %q and %q", c.Query("from"), c.Query("to"))))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to revisions should be specified"})
+		return
+	}
+
+	diff, err := api.services.DiffCompanyRevisions(c.Param("id"), currentUser(c).OrganizationID, from, to)
+	if err != nil {
+		log.Error("[api] DiffCompanyRevisions: DiffCompanyRevisions", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+func (api *API) RevertCompany(c *gin.Context) {
+	revision, err := strconv.ParseUint(c.Param("revision"), 10, 64)
+	if err != nil {
+		log.Error("[api] RevertCompany: ParseUint", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	company, err := api.services.RevertCompany(c.Param("id"), revision, currentUser(c))
+	if err != nil {
+		log.Error("[api] RevertCompany: RevertCompany", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.Header("ETag", etag(company.Version))
+
+	c.JSON(http.StatusOK, companyResponse(company))
+}
+
+func revisionResponse(rev dmodels.CompanyRevision) smodels.CompanyRevision {
+	resp := smodels.CompanyRevision{
+		Revision:     rev.Revision,
+		Action:       rev.Action,
+		AuthorEmail:  rev.AuthorEmail,
+		RevertedFrom: rev.RevertedFrom,
+		CreatedAt:    rev.CreatedAt,
+		Company: companyResponse(dmodels.CompanyShow{
+			ID:          rev.CompanyID,
+			Name:        rev.Snapshot.Name,
+			Description: rev.Snapshot.Description,
+			Employees:   rev.Snapshot.Employees,
+			Registered:  rev.Snapshot.Registered,
+			Type:        rev.Snapshot.Type,
+			DeletedAt:   rev.Snapshot.DeletedAt,
+			OwnerID:     rev.Snapshot.OwnerID,
+			ParentID:    rev.Snapshot.ParentID,
+			Attributes:  rev.Snapshot.Attributes,
+		}),
+	}
+	if rev.AuthorID.Valid {
+		resp.AuthorID = rev.AuthorID.UUID.String()
+	}
+	return resp
+}
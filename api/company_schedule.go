@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/services"
+	"xm-task/smodels"
+)
+
+func (api *API) ListCompanySchedules(c *gin.Context) {
+	var params smodels.CompanyScheduleListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] ListCompanySchedules: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] ListCompanySchedules: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedules, err := api.services.ListCompanySchedules(c.Param("id"), params.Status, organizationOf(c))
+	if err != nil {
+		log.Error("[api] ListCompanySchedules: ListCompanySchedules", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := make([]smodels.CompanySchedule, 0, len(schedules))
+	for _, schedule := range schedules {
+		resp = append(resp, companySchedule(schedule))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *API) GetCompanySchedule(c *gin.Context) {
+	schedule, err := api.services.GetCompanySchedule(c.Param("id"), c.Param("schedule"), organizationOf(c))
+	if err != nil {
+		log.Error("[api] GetCompanySchedule: GetCompanySchedule", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companySchedule(schedule))
+}
+
+// CreateCompanySchedule schedules a change of the company, pending changes which touch the same fields
+// are reported as conflicts.
+func (api *API) CreateCompanySchedule(c *gin.Context) {
+	var schedule smodels.CompanySchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		log.Error("[api] CreateCompanySchedule: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := schedule.Validate(); err != nil {
+		log.Error("[api] CreateCompanySchedule: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := api.services.CreateCompanySchedule(c.Param("id"), schedule, currentUser(c))
+	if err != nil {
+		log.Error("[api] CreateCompanySchedule: CreateCompanySchedule", zap.Error(err))
+		var conflict *services.ScheduleConflictError
+		if errors.As(err, &conflict) {
+			conflicts := make([]smodels.ScheduleConflict, 0, len(conflict.Schedules))
+			for _, schedule := range conflict.Schedules {
+				conflicts = append(conflicts, smodels.ScheduleConflict{
+					ID:     schedule.ID.String(),
+					Fields: schedule.Fields,
+				})
+			}
+			c.JSON(http.StatusConflict, gin.H{
+				"error":     local.Conflict,
+				"reason":    local.ReasonScheduleConflict,
+				"conflicts": conflicts,
+			})
+			return
+		}
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companySchedule(created))
+}
+
+func (api *API) CancelCompanySchedule(c *gin.Context) {
+	schedule, err := api.services.CancelCompanySchedule(c.Param("id"), c.Param("schedule"), currentUser(c))
+	if err != nil {
+		log.Error("[api] CancelCompanySchedule: CancelCompanySchedule", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companySchedule(schedule))
+}
+
+func companySchedule(schedule dmodels.CompanySchedule) smodels.CompanySchedule {
+	resp := smodels.CompanySchedule{
+		ID:          schedule.ID.String(),
+		EffectiveAt: schedule.EffectiveAt,
+		Changes:     schedule.Changes,
+		Fields:      schedule.Fields,
+		Status:      schedule.Status,
+		Error:       schedule.Error,
+		AuthorEmail: schedule.AuthorEmail,
+		CreatedAt:   schedule.CreatedAt,
+		FinishedAt:  schedule.FinishedAt,
+	}
+	if schedule.AuthorID.Valid {
+		resp.AuthorID = schedule.AuthorID.UUID.String()
+	}
+	return resp
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+func (api *API) GetCompanyStats(c *gin.Context) {
+	var params smodels.CompanyStatsParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] GetCompanyStats: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] GetCompanyStats: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !resolveFilter(c, &params.CompanyFilterParams) {
+		return
+	}
+
+	stats, err := api.services.CompanyStats(params)
+	if err != nil {
+		log.Error("[api] GetCompanyStats: CompanyStats", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyStats(stats, params.WindowNames()))
+}
+
+func companyStats(stats dmodels.CompanyStats, windows []string) smodels.CompanyStats {
+	resp := smodels.CompanyStats{
+		Total:  stats.Total,
+		ByType: make([]smodels.CompanyTypeCount, 0, len(stats.ByType)),
+		ByRegistered: smodels.CompanyRegisteredCount{
+			Registered:   stats.Registered,
+			Unregistered: stats.Unregistered,
+		},
+		ByEmployees: make([]smodels.CompanyBucketCount, 0, len(stats.ByEmployees)),
+		Windows:     make([]smodels.CompanyWindowCount, 0, len(stats.Windows)),
+		ComputedAt:  stats.ComputedAt,
+	}
+
+	for _, count := range stats.ByType {
+		resp.ByType = append(resp.ByType, smodels.CompanyTypeCount{Type: count.Type, Count: count.Count})
+	}
+
+	buckets := make(map[string]dmodels.EmployeeBucket, len(dmodels.EmployeeBuckets))
+	for _, bucket := range dmodels.EmployeeBuckets {
+		buckets[bucket.Name] = bucket
+	}
+	for _, count := range stats.ByEmployees {
+		resp.ByEmployees = append(resp.ByEmployees, smodels.CompanyBucketCount{
+			Bucket: count.Bucket,
+			Min:    buckets[count.Bucket].Min,
+			Max:    buckets[count.Bucket].Max,
+			Count:  count.Count,
+		})
+	}
+
+	for i, count := range stats.Windows {
+		window := smodels.CompanyWindowCount{
+			Since:   count.Since,
+			Created: count.Created,
+			Deleted: count.Deleted,
+		}
+		if i < len(windows) {
+			window.Window = windows[i]
+		}
+		resp.Windows = append(resp.Windows, window)
+	}
+
+	return resp
+}
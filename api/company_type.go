@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+// ListCompanyTypes returns the types which can be assigned to companies.
+func (api *API) ListCompanyTypes(c *gin.Context) {
+	api.listCompanyTypes(c, false)
+}
+
+// ListAllCompanyTypes returns deprecated types as well, along with the number of their companies.
+func (api *API) ListAllCompanyTypes(c *gin.Context) {
+	api.listCompanyTypes(c, true)
+}
+
+func (api *API) listCompanyTypes(c *gin.Context, withDeprecated bool) {
+	types, err := api.services.ListCompanyTypes(withDeprecated)
+	if err != nil {
+		log.Error("[api] ListCompanyTypes: ListCompanyTypes", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := make([]smodels.CompanyType, 0, len(types))
+	for _, ct := range types {
+		item := companyTypeResponse(ct)
+		if withDeprecated {
+			companies := ct.Companies
+			item.Companies = &companies
+		}
+		resp = append(resp, item)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *API) CreateCompanyType(c *gin.Context) {
+	var ct smodels.CompanyType
+	if err := c.ShouldBindJSON(&ct); err != nil {
+		log.Error("[api] CreateCompanyType: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := ct.Validate(); err != nil {
+		log.Error("[api] CreateCompanyType: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := api.services.CreateCompanyType(ct)
+	if err != nil {
+		log.Error("[api] CreateCompanyType: CreateCompanyType", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyTypeResponse(created))
+}
+
+func (api *API) UpdateCompanyType(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		log.Error("[api] UpdateCompanyType: ParseUint", zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": local.NotFound})
+		return
+	}
+
+	var update smodels.CompanyTypeUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		log.Error("[api] UpdateCompanyType: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := update.Validate(); err != nil {
+		log.Error("[api] UpdateCompanyType: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ct, err := api.services.UpdateCompanyType(id, update)
+	if err != nil {
+		log.Error("[api] UpdateCompanyType: UpdateCompanyType", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, companyTypeResponse(ct))
+}
+
+func (api *API) MergeCompanyTypes(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		log.Error("[api] MergeCompanyTypes: ParseUint", zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": local.NotFound})
+		return
+	}
+
+	var merge smodels.CompanyTypeMerge
+	if err := c.ShouldBindJSON(&merge); err != nil {
+		log.Error("[api] MergeCompanyTypes: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	target, moved, err := api.services.MergeCompanyTypes(id, merge.Into, currentUser(c))
+	if err != nil {
+		log.Error("[api] MergeCompanyTypes: MergeCompanyTypes", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, smodels.CompanyTypeMergeResult{
+		Type:  companyTypeResponse(target),
+		Moved: moved,
+	})
+}
+
+func companyTypeResponse(ct dmodels.CompanyType) smodels.CompanyType {
+	return smodels.CompanyType{
+		ID:           ct.ID,
+		Name:         ct.Name,
+		Deprecated:   ct.Deprecated(),
+		DeprecatedAt: ct.DeprecatedAt,
+		// the schema is public, clients need it to build forms for the attributes
+		AttributesSchema: ct.AttributesSchema,
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	local "xm-task/helpers/errors"
+)
+
+// serviceError maps an error returned by services to the response status and body.
+func serviceError(err error) (int, gin.H) {
+	switch {
+	case errors.Is(err, local.ErrNotFound):
+		return http.StatusNotFound, gin.H{"error": local.NotFound}
+	case errors.Is(err, local.ErrNotMember):
+		return http.StatusForbidden, gin.H{"error": local.Forbidden, "reason": local.ReasonNotMember}
+	case errors.Is(err, local.ErrForbidden):
+		return http.StatusForbidden, gin.H{"error": local.Forbidden, "reason": local.ReasonNotOwner}
+	case errors.Is(err, local.ErrHasSubsidiaries):
+		return http.StatusConflict, gin.H{"error": local.Conflict, "reason": local.ReasonHasSubsidiaries}
+	case errors.Is(err, local.ErrRequestInProgress):
+		return http.StatusConflict, gin.H{"error": local.Conflict, "reason": local.ReasonRequestInProgress}
+	case errors.Is(err, local.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity, gin.H{"error": local.Unprocessable, "reason": local.ReasonIdempotencyKeyReused}
+	case errors.Is(err, local.ErrPossibleDuplicates):
+		return http.StatusConflict, gin.H{"error": local.Conflict, "reason": local.ReasonPossibleDuplicates}
+	case errors.Is(err, local.ErrScheduleConflict):
+		return http.StatusConflict, gin.H{"error": local.Conflict, "reason": local.ReasonScheduleConflict}
+	case errors.Is(err, local.ErrConflict):
+		return http.StatusConflict, gin.H{"error": local.Conflict}
+	case errors.Is(err, local.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, gin.H{"error": local.PreconditionFailed}
+	case errors.Is(err, local.ErrValidation):
+		return http.StatusBadRequest, gin.H{"error": err.Error()}
+	case errors.Is(err, local.ErrUnprocessable):
+		return http.StatusUnprocessableEntity, gin.H{"error": err.Error()}
+	case errors.Is(err, local.ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType, gin.H{"error": local.UnsupportedMediaType}
+	case errors.Is(err, local.ErrRequestTooLarge):
+		return http.StatusRequestEntityTooLarge, gin.H{"error": local.RequestTooLarge}
+	case errors.Is(err, local.ErrFailedDependency):
+		return http.StatusFailedDependency, gin.H{"error": local.FailedDependency}
+	case errors.Is(err, local.ErrUnavailable):
+		return http.StatusServiceUnavailable, gin.H{"error": local.UnavailableErr}
+	case errors.Is(err, local.ErrInvalidCursor):
+		return http.StatusBadRequest, gin.H{"error": local.ErrInvalidCursor.Error()}
+	}
+	return http.StatusInternalServerError, gin.H{"error": local.ServiceError}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	local "xm-task/helpers/errors"
+)
+
+// etag formats a company version as a strong entity tag.
+func etag(version uint64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ifMatchVersion returns the company version expected by the If-Match header,
+// zero means any version. The response is written when the request has to be rejected.
+func (api *API) ifMatchVersion(c *gin.Context) (uint64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		if api.cfg.API.RequireIfMatch {
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": local.PreconditionRequired})
+			return 0, false
+		}
+		return 0, true
+	}
+	if header == "*" {
+		return 0, true
+	}
+
+	// If-Match uses strong comparison, so weak tags never match
+	if !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": local.PreconditionFailed})
+		return 0, false
+	}
+	version, err := strconv.ParseUint(strings.Trim(header, `"`), 10, 64)
+	if err != nil || version == 0 {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": local.PreconditionFailed})
+		return 0, false
+	}
+
+	return version, true
+}
+
+// notModified reports whether the If-None-Match header matches the current version.
+func notModified(c *gin.Context, version uint64) bool {
+	current := etag(version)
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/conf"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	// maxIdempotentRequestSize bounds the bodies read to fingerprint requests, batches have the largest ones.
+	// Imports and uploads are streamed, they do not take keys.
+	maxIdempotentRequestSize = 8 << 20
+)
+
+// replayedHeaders are stored along with the response, the rest is set by the router again.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location", "Content-Disposition"}
+
+// Idempotency replays the stored response to a mutating request retried with the same Idempotency-Key,
+// it has to follow AuthMiddleware since keys belong to users. The first request holds the key until it
+// is answered, concurrent retries get 409 meanwhile. Server errors and responses larger than
+// Idempotency.MaxResponseSize are not stored, the request is handled again on retry.
+func (api *API) Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !mutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("%s should be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength),
+			})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentRequestSize))
+		if err != nil {
+			log.Error("[api] Idempotency: ReadAll", zap.Error(err))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": local.RequestTooLarge})
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+			}
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		user := currentUser(c)
+		fingerprint := requestFingerprint(c.Request, user, body)
+
+		stored, err := api.services.BeginIdempotentRequest(user.ID, key, fingerprint)
+		if err != nil {
+			log.Error("[api] Idempotency: BeginIdempotentRequest", zap.Error(err))
+			c.JSON(serviceError(err))
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			replay(c, *stored)
+			c.Abort()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer, limit: api.maxResponseSize()}
+		c.Writer = w
+
+		completed := false
+		defer func() {
+			// a panicking handler leaves the key to retries instead of holding it until the lock times out
+			if !completed {
+				if err := api.services.ReleaseIdempotentRequest(user.ID, key, fingerprint); err != nil {
+					log.Error("[api] Idempotency: ReleaseIdempotentRequest", zap.Error(err))
+				}
+			}
+		}()
+
+		c.Next()
+
+		if w.Status() >= http.StatusInternalServerError || w.overflow {
+			return
+		}
+
+		status := w.Status()
+		headers := dmodels.JSONObject{}
+		for _, header := range replayedHeaders {
+			if value := w.Header().Get(header); value != "" {
+				headers[header] = value
+			}
+		}
+		err = api.services.CompleteIdempotentRequest(dmodels.IdempotencyKey{
+			UserID:      user.ID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			StatusCode:  &status,
+			Headers:     headers,
+			Body:        w.body.Bytes(),
+		})
+		if err != nil {
+			log.Error("[api] Idempotency: CompleteIdempotentRequest", zap.Error(err))
+			return
+		}
+		completed = true
+	}
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestFingerprint hashes everything which makes the request, the organization included,
+// since the same user may send the key again after switching organizations.
+func requestFingerprint(r *http.Request, user dmodels.User, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n%s\n%s\n%s\n", r.Method, r.URL.RequestURI(), user.OrganizationID,
+		r.Header.Get("Content-Type"), r.Header.Get("If-Match"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(c *gin.Context, stored dmodels.IdempotencyKey) {
+	for header, value := range stored.Headers {
+		if s, ok := value.(string); ok {
+			c.Header(header, s)
+		}
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+
+	c.Status(*stored.StatusCode)
+	if len(stored.Body) > 0 {
+		if _, err := c.Writer.Write(stored.Body); err != nil {
+			log.Error("[api] Idempotency: Write", zap.Error(err))
+		}
+	}
+}
+
+func (api *API) maxResponseSize() int {
+	if api.cfg.Idempotency.MaxResponseSize > 0 {
+		return int(api.cfg.Idempotency.MaxResponseSize)
+	}
+	return conf.DefaultIdempotencyMaxResponseSize
+}
+
+// recordingWriter keeps a copy of the response body up to the limit.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *recordingWriter) record(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > w.limit {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
 	"go.uber.org/zap"
 	"net/http"
 	local "xm-task/helpers/errors"
@@ -36,10 +37,10 @@ func (api *API) SignIn(c *gin.Context) {
 		return
 	}
 
-	td, err := api.services.CreateToken(user.Email)
+	td, err := api.services.CreateToken(user.Email, uuid.Nil)
 	if err != nil {
 		log.Error("[api] SignIn: CreateToken", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": local.ServiceError})
+		c.JSON(serviceError(err))
 		return
 	}
 
@@ -50,13 +51,7 @@ func (api *API) SignIn(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":         true,
-		"access_token":    td.AccessToken,
-		"refresh_token":   td.RefreshToken,
-		"access_expired":  td.AtExpires,
-		"refresh_expired": td.RtExpires,
-	})
+	c.JSON(http.StatusOK, tokenResponse(td))
 }
 
 func (api *API) Refresh(c *gin.Context) {
@@ -67,13 +62,7 @@ func (api *API) Refresh(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":         true,
-		"access_token":    td.AccessToken,
-		"refresh_token":   td.RefreshToken,
-		"access_expired":  td.AtExpires,
-		"refresh_expired": td.RtExpires,
-	})
+	c.JSON(http.StatusOK, tokenResponse(td))
 }
 
 func (api *API) LogOut(c *gin.Context) {
@@ -104,3 +93,14 @@ func (api *API) LogOut(c *gin.Context) {
 		"success": true,
 	})
 }
+
+func tokenResponse(td smodels.TokenDetails) gin.H {
+	return gin.H{
+		"success":         true,
+		"access_token":    td.AccessToken,
+		"refresh_token":   td.RefreshToken,
+		"access_expired":  td.AtExpires,
+		"refresh_expired": td.RtExpires,
+		"organization_id": td.OrganizationID,
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"xm-task/conf"
+	"xm-task/dmodels"
+	"xm-task/helpers/rbac"
 	"xm-task/log"
 	"xm-task/services"
 )
@@ -67,33 +69,133 @@ func (api *API) initialize() {
 		AllowOrigins:     api.cfg.API.CORSAllowedOrigins,
 		AllowCredentials: true,
 		AllowMethods: []string{
-			http.MethodPost, http.MethodHead, http.MethodGet, http.MethodOptions, http.MethodPut, http.MethodDelete,
+			http.MethodPost, http.MethodHead, http.MethodGet, http.MethodOptions, http.MethodPut, http.MethodPatch, http.MethodDelete,
 		},
 		AllowHeaders: []string{
 			"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token",
 			"Authorization", "User-Env", "Access-Control-Request-Headers", "Access-Control-Request-Method",
+			"If-Match", "If-None-Match", IdempotencyKeyHeader, LastEventIDHeader,
 		},
+		ExposeHeaders: []string{"ETag", "Content-Disposition", "Digest", IdempotentReplayedHeader},
 	}))
 
 	// public routes
 	api.router.GET("/", api.Index)
 	api.router.GET("/health", api.Health)
 
+	api.router.GET("/companies", api.ListCompanies)
+	api.router.GET("/companies/search", api.SearchCompanies)
+	api.router.GET("/companies/stats", api.GetCompanyStats)
 	api.router.GET("/companies/:id", api.GetCompany)
+	api.router.GET("/companies/by-slug/:slug", api.GetCompanyBySlug)
+	api.router.GET("/companies/:id/ancestors", api.GetCompanyAncestors)
+	api.router.GET("/companies/:id/children", api.ListCompanyChildren)
+	api.router.GET("/companies/:id/subtree", api.GetCompanySubtree)
+	api.router.GET("/companies/:id/group", api.GetCompanyGroup)
+	api.router.GET("/companies/:id/logo/:hash/:size", api.ServeCompanyLogo)
+	api.router.GET("/company-types", api.ListCompanyTypes)
 	api.router.POST("/sign-in", api.SignIn)
 	api.router.POST("/refresh", api.Refresh)
 
-	// protected routes
+	// protected routes, the streams accept stream tokens besides the Authorization header. They are not public,
+	// anonymous clients could take every subscription of the instance.
+	streamAuth := api.StreamAuthMiddleware()
+	streamRead := api.RequirePermission(rbac.CompaniesRead)
+	api.router.GET("/auth/companies/stream", streamAuth, streamRead, api.StreamCompanyEvents)
+	api.router.GET("/auth/companies/stream/ws", streamAuth, streamRead, api.StreamCompanyEventsWebSocket)
+
 	authGroup := api.router.Group("/auth")
 	authGroup.Use(api.AuthMiddleware())
 	{
-		authGroup.POST("/companies", api.CreateCompany)
-		authGroup.PATCH("/companies/:id", api.PatchCompany)
-		authGroup.DELETE("/companies/:id", api.DeleteCompany)
+		read := api.RequirePermission(rbac.CompaniesRead)
+		write := api.RequirePermission(rbac.CompaniesWrite)
+		// company changes can be retried with an Idempotency-Key, the other routes return tokens
+		// and secrets, which are not to be stored
+		idempotent := api.Idempotency()
+
+		authGroup.GET("/companies", read, api.ListCompanies)
+		authGroup.GET("/companies/search", read, api.SearchCompanies)
+		authGroup.GET("/companies/stats", read, api.GetCompanyStats)
+		authGroup.POST("/companies/stream/token", read, api.CreateStreamToken)
+		authGroup.GET("/companies/duplicates", read, api.ListCompanyDuplicates)
+		authGroup.GET("/companies/:id", read, api.GetCompany)
+		authGroup.GET("/companies/by-slug/:slug", read, api.GetCompanyBySlug)
+		authGroup.GET("/companies/:id/ancestors", read, api.GetCompanyAncestors)
+		authGroup.GET("/companies/:id/children", read, api.ListCompanyChildren)
+		authGroup.GET("/companies/:id/subtree", read, api.GetCompanySubtree)
+		authGroup.GET("/companies/:id/group", read, api.GetCompanyGroup)
+		authGroup.PUT("/companies/:id/parent", write, idempotent, api.SetCompanyParent)
+		authGroup.GET("/companies/:id/contacts", read, api.ListCompanyContacts)
+		authGroup.POST("/companies/:id/contacts", write, idempotent, api.CreateCompanyContact)
+		authGroup.GET("/companies/:id/contacts/:contact", read, api.GetCompanyContact)
+		authGroup.PUT("/companies/:id/contacts/:contact", write, idempotent, api.UpdateCompanyContact)
+		authGroup.DELETE("/companies/:id/contacts/:contact", write, idempotent, api.DeleteCompanyContact)
+		authGroup.GET("/companies/:id/addresses", read, api.ListCompanyAddresses)
+		authGroup.POST("/companies/:id/addresses", write, idempotent, api.CreateCompanyAddress)
+		authGroup.GET("/companies/:id/addresses/:address", read, api.GetCompanyAddress)
+		authGroup.PUT("/companies/:id/addresses/:address", write, idempotent, api.UpdateCompanyAddress)
+		authGroup.DELETE("/companies/:id/addresses/:address", write, idempotent, api.DeleteCompanyAddress)
+		authGroup.POST("/companies", write, idempotent, api.CreateCompany)
+		authGroup.POST("/companies/batch", write, idempotent, api.BatchCompanies)
+		authGroup.POST("/companies/import", write, api.ImportCompanies)
+		authGroup.GET("/companies/export", read, api.ExportCompanies)
+		authGroup.PATCH("/companies/:id", write, idempotent, api.PatchCompany)
+		authGroup.DELETE("/companies/:id", write, idempotent, api.DeleteCompany)
+		authGroup.GET("/companies/trash", read, api.ListTrashedCompanies)
+		authGroup.POST("/companies/:id/restore", write, idempotent, api.RestoreCompany)
+		authGroup.POST("/companies/:id/transfer", write, idempotent, api.TransferCompany)
+		authGroup.GET("/companies/:id/attachments", read, api.ListCompanyAttachments)
+		authGroup.POST("/companies/:id/attachments", write, api.UploadCompanyAttachment)
+		authGroup.GET("/companies/:id/attachments/:attachment", read, api.GetCompanyAttachment)
+		authGroup.GET("/companies/:id/attachments/:attachment/content", read, api.DownloadCompanyAttachment)
+		authGroup.DELETE("/companies/:id/attachments/:attachment", write, idempotent, api.DeleteCompanyAttachment)
+		authGroup.GET("/companies/:id/logo", read, api.GetCompanyLogo)
+		authGroup.PUT("/companies/:id/logo", write, api.UploadCompanyLogo)
+		authGroup.DELETE("/companies/:id/logo", write, idempotent, api.DeleteCompanyLogo)
+		authGroup.GET("/companies/:id/schedules", read, api.ListCompanySchedules)
+		authGroup.POST("/companies/:id/schedules", write, idempotent, api.CreateCompanySchedule)
+		authGroup.GET("/companies/:id/schedules/:schedule", read, api.GetCompanySchedule)
+		authGroup.DELETE("/companies/:id/schedules/:schedule", write, idempotent, api.CancelCompanySchedule)
+		authGroup.GET("/companies/:id/revisions", read, api.ListCompanyRevisions)
+		authGroup.GET("/companies/:id/revisions/diff", read, api.DiffCompanyRevisions)
+		authGroup.GET("/companies/:id/revisions/:revision", read, api.GetCompanyRevision)
+		authGroup.POST("/companies/:id/revisions/:revision/revert", write, idempotent, api.RevertCompany)
+
+		authGroup.GET("/organizations", api.ListOrganizations)
+		authGroup.POST("/organizations", api.CreateOrganization)
+		authGroup.POST("/organizations/:id/switch", api.SwitchOrganization)
+
+		manageWebhooks := api.RequirePermission(rbac.WebhooksManage)
+		authGroup.GET("/webhooks", manageWebhooks, api.ListWebhooks)
+		authGroup.POST("/webhooks", manageWebhooks, api.CreateWebhook)
+		authGroup.GET("/webhooks/:id", manageWebhooks, api.GetWebhook)
+		authGroup.PATCH("/webhooks/:id", manageWebhooks, api.UpdateWebhook)
+		authGroup.DELETE("/webhooks/:id", manageWebhooks, api.DeleteWebhook)
+		authGroup.GET("/webhooks/:id/deliveries", manageWebhooks, api.ListWebhookDeliveries)
+		authGroup.GET("/webhooks/:id/deliveries/:delivery", manageWebhooks, api.GetWebhookDelivery)
+		authGroup.POST("/webhooks/:id/deliveries/:delivery/redeliver", manageWebhooks, api.RedeliverWebhookDelivery)
 
 		authGroup.POST("/logout", api.LogOut)
 	}
 
+	adminGroup := authGroup.Group("/admin")
+	{
+		// company types are shared by every organization, so admins of the default one manage them
+		defaultOrg := api.RequireOrganization(dmodels.DefaultOrganizationID)
+		manageTypes := api.RequirePermission(rbac.CompanyTypesManage)
+		manageUsers := api.RequirePermission(rbac.UsersManage)
+
+		adminGroup.GET("/company-types", defaultOrg, manageTypes, api.ListAllCompanyTypes)
+		adminGroup.POST("/company-types", defaultOrg, manageTypes, api.CreateCompanyType)
+		adminGroup.PATCH("/company-types/:id", defaultOrg, manageTypes, api.UpdateCompanyType)
+		adminGroup.POST("/company-types/:id/merge", defaultOrg, manageTypes, api.MergeCompanyTypes)
+
+		adminGroup.GET("/users", manageUsers, api.ListUsers)
+		adminGroup.PUT("/users/:email/role", manageUsers, api.SetUserRole)
+		adminGroup.DELETE("/users/:email", manageUsers, api.RemoveUser)
+
+	}
+
 	api.server = &http.Server{Addr: fmt.Sprintf(":%d", api.cfg.API.ListenOnPort), Handler: api.router}
 }
 
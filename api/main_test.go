@@ -7,13 +7,19 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	regen "github.com/zach-klippenstein/goregen"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 	"xm-task/api"
 	"xm-task/conf"
 	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/helpers/rbac"
 	"xm-task/services"
 	"xm-task/smodels"
 )
@@ -108,7 +114,7 @@ func TestCreateCompanyIntegration(t *testing.T) {
 	})
 
 	// checks protection of non-existing types
-	t.Run("it should return 500 status code", func(t *testing.T) {
+	t.Run("it should return 400 status code", func(t *testing.T) {
 		user := smodels.User{
 			Email:    "email12@gmail.com",
 			Password: "23rwgfds",
@@ -147,7 +153,7 @@ func TestCreateCompanyIntegration(t *testing.T) {
 
 		defer resp.Body.Close()
 
-		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 	})
 }
 
@@ -326,3 +332,832 @@ func TestSignInIntegration(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 	})
 }
+
+const testPassword = "23rwgfds"
+
+func testConfig() conf.Config {
+	return conf.Config{
+		API: conf.API{
+			ListenOnPort:       7777,
+			CORSAllowedOrigins: []string{"*"},
+		},
+		Postgres: conf.Postgres{
+			Host:     "localhost",
+			Port:     "5432",
+			User:     "postgres",
+			Password: "1234",
+			Database: "xm-test",
+			SSLMode:  "disable",
+		},
+	}
+}
+
+// startServer serves the API, wrap replaces methods of the service when it is given.
+func startServer(t *testing.T, wrap func(services.Service) services.Service) (*httptest.Server, services.Service) {
+	return startConfiguredServer(t, testConfig(), wrap)
+}
+
+func startConfiguredServer(t *testing.T, cfg conf.Config, wrap func(services.Service) services.Service) (*httptest.Server, services.Service) {
+	d, err := dao.New(cfg, false)
+	require.NoError(t, err)
+
+	var service services.Service
+	service, err = services.NewService(cfg, d)
+	require.NoError(t, err)
+	if wrap != nil {
+		service = wrap(service)
+	}
+
+	a, err := api.NewAPI(cfg, service)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(a.Router())
+	t.Cleanup(ts.Close)
+	return ts, service
+}
+
+// randomEmail returns the email of a user which is registered on sign in.
+func randomEmail(t *testing.T) string {
+	name, err := regen.Generate("[a-z0-9]{15}")
+	require.NoError(t, err)
+	return name + "@gmail.com"
+}
+
+func randomName(t *testing.T) string {
+	name, err := regen.Generate("[a-zA-Z0-9]{15}")
+	require.NoError(t, err)
+	return name
+}
+
+func signIn(t *testing.T, ts *httptest.Server, email string) string {
+	resp := doRequest(t, ts, http.MethodPost, "/sign-in", "", smodels.User{Email: email, Password: testPassword}, nil)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var tt smodels.TestTokenDetails
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tt))
+	return tt.AccessToken
+}
+
+// doRequest sends the body as JSON, the token is omitted when empty.
+func doRequest(t *testing.T, ts *httptest.Server, method, path, token string, body interface{}, headers map[string]string) *http.Response {
+	var reader io.Reader
+	if body != nil {
+		requestBody, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(requestBody)
+	}
+
+	r, err := http.NewRequest(method, ts.URL+path, reader)
+	require.NoError(t, err)
+	if body != nil {
+		r.Header.Add("Content-Type", "application/json")
+	}
+	if token != "" {
+		r.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(r)
+	require.NoError(t, err)
+	return resp
+}
+
+// decode reads the JSON body and closes it.
+func decode(t *testing.T, resp *http.Response, v interface{}) {
+	defer resp.Body.Close()
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+}
+
+func createCompany(t *testing.T, ts *httptest.Server, token string) smodels.Company {
+	resp := doRequest(t, ts, http.MethodPost, "/auth/companies", token, smodels.Company{
+		Name:       randomName(t),
+		Employees:  100,
+		Registered: true,
+		Type:       "Corporations",
+	}, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var company smodels.Company
+	decode(t, resp, &company)
+	return company
+}
+
+// createOrganization creates an organization administered by the user and returns the token switched to it.
+func createOrganization(t *testing.T, ts *httptest.Server, token string) (string, string) {
+	resp := doRequest(t, ts, http.MethodPost, "/auth/organizations", token, smodels.Organization{Name: randomName(t)}, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var org smodels.Organization
+	decode(t, resp, &org)
+
+	return org.ID, switchOrganization(t, ts, token, org.ID)
+}
+
+func switchOrganization(t *testing.T, ts *httptest.Server, token, orgID string) string {
+	resp := doRequest(t, ts, http.MethodPost, "/auth/organizations/"+orgID+"/switch", token, nil, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var tt smodels.TestTokenDetails
+	decode(t, resp, &tt)
+	return tt.AccessToken
+}
+
+// blockingService holds CreateCompany until release is closed.
+type blockingService struct {
+	services.Service
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingService) CreateCompany(company smodels.Company, allowDuplicates bool, user dmodels.User) (dmodels.CompanyShow, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return s.Service.CreateCompany(company, allowDuplicates, user)
+}
+
+func TestIdempotencyIntegration(t *testing.T) {
+	ts, _ := startServer(t, nil)
+	token := signIn(t, ts, randomEmail(t))
+
+	// checks that a retry with the same key replays the stored response without creating the company again
+	t.Run("it should replay the response", func(t *testing.T) {
+		key := randomName(t)
+		company := smodels.Company{Name: randomName(t), Employees: 10, Registered: true, Type: "Corporations"}
+
+		first := doRequest(t, ts, http.MethodPost, "/auth/companies", token, company, map[string]string{api.IdempotencyKeyHeader: key})
+		require.Equal(t, http.StatusOK, first.StatusCode)
+		var created smodels.Company
+		decode(t, first, &created)
+
+		retry := doRequest(t, ts, http.MethodPost, "/auth/companies", token, company, map[string]string{api.IdempotencyKeyHeader: key})
+		require.Equal(t, http.StatusOK, retry.StatusCode)
+		assert.Equal(t, "true", retry.Header.Get(api.IdempotentReplayedHeader))
+		var replayed smodels.Company
+		decode(t, retry, &replayed)
+		assert.Equal(t, created, replayed)
+	})
+
+	// checks that the key cannot be reused for another request
+	t.Run("it should return 422 status code", func(t *testing.T) {
+		key := randomName(t)
+
+		first := doRequest(t, ts, http.MethodPost, "/auth/companies", token,
+			smodels.Company{Name: randomName(t), Employees: 10, Registered: true, Type: "Corporations"},
+			map[string]string{api.IdempotencyKeyHeader: key})
+		first.Body.Close()
+		require.Equal(t, http.StatusOK, first.StatusCode)
+
+		reused := doRequest(t, ts, http.MethodPost, "/auth/companies", token,
+			smodels.Company{Name: randomName(t), Employees: 10, Registered: true, Type: "Corporations"},
+			map[string]string{api.IdempotencyKeyHeader: key})
+		assert.Equal(t, http.StatusUnprocessableEntity, reused.StatusCode)
+		var body map[string]string
+		decode(t, reused, &body)
+		assert.Equal(t, local.ReasonIdempotencyKeyReused, body["reason"])
+	})
+}
+
+func TestIdempotencyInProgressIntegration(t *testing.T) {
+	blocking := &blockingService{started: make(chan struct{}, 1), release: make(chan struct{})}
+	ts, _ := startServer(t, func(s services.Service) services.Service {
+		blocking.Service = s
+		return blocking
+	})
+	token := signIn(t, ts, randomEmail(t))
+
+	// checks that a retry sent while the first request is handled is rejected
+	t.Run("it should return 409 status code", func(t *testing.T) {
+		key := randomName(t)
+		company := smodels.Company{Name: randomName(t), Employees: 10, Registered: true, Type: "Corporations"}
+
+		requestBody, err := json.Marshal(company)
+		require.NoError(t, err)
+		r, err := http.NewRequest(http.MethodPost, ts.URL+"/auth/companies", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		r.Header.Add("Content-Type", "application/json")
+		r.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		r.Header.Add(api.IdempotencyKeyHeader, key)
+
+		done := make(chan *http.Response)
+		go func() {
+			// failures are reported by the receiving side
+			resp, _ := http.DefaultClient.Do(r)
+			done <- resp
+		}()
+		<-blocking.started
+
+		retry := doRequest(t, ts, http.MethodPost, "/auth/companies", token, company, map[string]string{api.IdempotencyKeyHeader: key})
+		assert.Equal(t, http.StatusConflict, retry.StatusCode)
+		var body map[string]string
+		decode(t, retry, &body)
+		assert.Equal(t, local.ReasonRequestInProgress, body["reason"])
+
+		close(blocking.release)
+		first := <-done
+		require.NotNil(t, first)
+		first.Body.Close()
+		assert.Equal(t, http.StatusOK, first.StatusCode)
+	})
+}
+
+func TestOrganizationIsolationIntegration(t *testing.T) {
+	ts, _ := startServer(t, nil)
+	company := createCompany(t, ts, signIn(t, ts, randomEmail(t)))
+
+	// the other user registers within the default organization as well and administers a new one
+	defaultToken := signIn(t, ts, randomEmail(t))
+	orgID, otherToken := createOrganization(t, ts, defaultToken)
+
+	// checks that companies of other organizations are reported as missing by every route
+	t.Run("it should return 404 status code", func(t *testing.T) {
+		for _, r := range []struct {
+			method, path string
+			body         interface{}
+			headers      map[string]string
+		}{
+			{method: http.MethodGet, path: "/auth/companies/" + company.ID},
+			{method: http.MethodPatch, path: "/auth/companies/" + company.ID,
+				body: map[string]interface{}{"employees": 1}, headers: map[string]string{"Content-Type": smodels.MergePatchContentType}},
+			{method: http.MethodDelete, path: "/auth/companies/" + company.ID},
+			{method: http.MethodGet, path: "/auth/companies/" + company.ID + "/revisions"},
+		} {
+			resp := doRequest(t, ts, r.method, r.path, otherToken, r.body, r.headers)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode, "%s %s", r.method, r.path)
+		}
+
+		resp := doRequest(t, ts, http.MethodPost, "/auth/companies/batch", otherToken, smodels.CompanyBatch{
+			Mode: smodels.BatchModeBestEffort,
+			Operations: []smodels.CompanyBatchOperation{
+				{Op: dmodels.BatchOpUpdate, ID: company.ID, Company: json.RawMessage(`{"employees": 1}`)},
+				{Op: dmodels.BatchOpDelete, ID: company.ID},
+			},
+		}, nil)
+		require.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+		var batch smodels.CompanyBatchResponse
+		decode(t, resp, &batch)
+		require.Len(t, batch.Errors, 2)
+		for _, result := range batch.Errors {
+			assert.Equal(t, http.StatusNotFound, result.Status)
+		}
+	})
+
+	// checks that exports leave out companies of other organizations
+	t.Run("it should not export other organizations", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodGet, "/auth/companies/export?format=ndjson&name_prefix="+company.Name, otherToken, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.NotContains(t, string(body), company.ID)
+
+		resp = doRequest(t, ts, http.MethodGet, "/auth/companies/export?format=ndjson&name_prefix="+company.Name, defaultToken, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), company.ID)
+	})
+
+	// checks that switching the organization changes the visible companies
+	t.Run("it should switch the organization", func(t *testing.T) {
+		switched := switchOrganization(t, ts, otherToken, dmodels.DefaultOrganizationID.String())
+		resp := doRequest(t, ts, http.MethodGet, "/auth/companies/"+company.ID, switched, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		switched = switchOrganization(t, ts, switched, orgID)
+		resp = doRequest(t, ts, http.MethodGet, "/auth/companies/"+company.ID, switched, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	// checks that company types are only managed within the default organization, even by admins of others
+	t.Run("it should return 403 status code", func(t *testing.T) {
+		for _, r := range []struct{ method, path string }{
+			{http.MethodGet, "/auth/admin/company-types"},
+			{http.MethodPost, "/auth/admin/company-types"},
+			{http.MethodPatch, "/auth/admin/company-types/1"},
+			{http.MethodPost, "/auth/admin/company-types/1/merge"},
+		} {
+			resp := doRequest(t, ts, r.method, r.path, otherToken, nil, nil)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode, "%s %s", r.method, r.path)
+			var body map[string]interface{}
+			decode(t, resp, &body)
+			assert.Equal(t, local.ReasonOrganizationRequired, body["reason"])
+		}
+	})
+}
+
+func TestCompanySchedulesIntegration(t *testing.T) {
+	ts, _ := startServer(t, nil)
+	token := signIn(t, ts, randomEmail(t))
+	company := createCompany(t, ts, token)
+	path := "/auth/companies/" + company.ID + "/schedules"
+
+	// checks that pending changes of the same fields conflict even when they take effect at different times
+	t.Run("it should return 409 status code", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodPost, path, token, smodels.CompanySchedule{
+			EffectiveAt: time.Now().Add(time.Hour),
+			Changes:     map[string]interface{}{"attributes": map[string]interface{}{"vat_id": "DE123456789"}},
+		}, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var first smodels.CompanySchedule
+		decode(t, resp, &first)
+
+		resp = doRequest(t, ts, http.MethodPost, path, token, smodels.CompanySchedule{
+			EffectiveAt: time.Now().Add(time.Hour * 2),
+			Changes:     map[string]interface{}{"attributes": nil},
+		}, nil)
+		require.Equal(t, http.StatusConflict, resp.StatusCode)
+		var body struct {
+			Reason    string                     `json:"reason"`
+			Conflicts []smodels.ScheduleConflict `json:"conflicts"`
+		}
+		decode(t, resp, &body)
+		assert.Equal(t, local.ReasonScheduleConflict, body.Reason)
+		require.Len(t, body.Conflicts, 1)
+		assert.Equal(t, first.ID, body.Conflicts[0].ID)
+	})
+
+	// checks that changes of other fields do not conflict
+	t.Run("it should return 200 status code", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodPost, path, token, smodels.CompanySchedule{
+			EffectiveAt: time.Now().Add(time.Hour * 2),
+			Changes:     map[string]interface{}{"employees_count": 200},
+		}, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestPermissionsIntegration(t *testing.T) {
+	ts, service := startServer(t, nil)
+	email := randomEmail(t)
+	editorToken := signIn(t, ts, email)
+	company := createCompany(t, ts, editorToken)
+
+	_, err := service.SetMemberRole(email, rbac.RoleViewer, dmodels.User{OrganizationID: dmodels.DefaultOrganizationID})
+	require.NoError(t, err)
+
+	// checks that tokens minted before the role change are rejected
+	t.Run("it should return 401 status code", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodGet, "/auth/companies/"+company.ID, editorToken, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	// checks that viewers can read but not write
+	t.Run("it should return 403 status code", func(t *testing.T) {
+		viewerToken := signIn(t, ts, email)
+		require.NotEqual(t, editorToken, viewerToken)
+
+		resp := doRequest(t, ts, http.MethodGet, "/auth/companies/"+company.ID, viewerToken, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = doRequest(t, ts, http.MethodDelete, "/auth/companies/"+company.ID, viewerToken, nil, nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		var body map[string]interface{}
+		decode(t, resp, &body)
+		assert.Equal(t, local.ReasonMissingPermission, body["reason"])
+		assert.Equal(t, string(rbac.CompaniesWrite), body["permission"])
+	})
+}
+
+func TestSearchCompaniesIntegration(t *testing.T) {
+	ts, _ := startServer(t, nil)
+	token := signIn(t, ts, randomEmail(t))
+
+	word := randomName(t)
+	resp := doRequest(t, ts, http.MethodPost, "/auth/companies", token, smodels.Company{
+		Name:        randomName(t),
+		Description: `<script>alert("` + word + `")</script> & ` + word,
+		Employees:   10,
+		Type:        "Corporations",
+	}, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// checks that only the highlights are markup
+	t.Run("it should escape the highlights", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodGet, "/auth/companies/search?q="+word, token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var list smodels.CompanySearchList
+		decode(t, resp, &list)
+
+		require.Len(t, list.Results, 1)
+		snippet := list.Results[0].Highlights.Description
+		assert.NotContains(t, snippet, "<script>")
+		assert.Contains(t, snippet, "&lt;script&gt;")
+		assert.Contains(t, snippet, "<mark>"+word+"</mark>")
+	})
+
+	// checks that the total does not depend on the page
+	t.Run("it should count the matches past the last page", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodGet, "/auth/companies/search?offset=10&q="+word, token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var list smodels.CompanySearchList
+		decode(t, resp, &list)
+
+		assert.Empty(t, list.Results)
+		assert.Equal(t, int64(1), list.Total)
+	})
+}
+
+func TestCompanyContactsIntegration(t *testing.T) {
+	ts, _ := startServer(t, nil)
+	token := signIn(t, ts, randomEmail(t))
+	company := createCompany(t, ts, token)
+	path := "/auth/companies/" + company.ID
+
+	// checks that only assigned ISO 3166-1 alpha-2 codes are accepted
+	t.Run("it should return 400 status code", func(t *testing.T) {
+		for _, country := range []string{"XX", "DEU", "UK"} {
+			resp := doRequest(t, ts, http.MethodPost, path+"/addresses", token, smodels.CompanyAddress{
+				Line1: "Unter den Linden 1", City: "Berlin", Country: country,
+			}, nil)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, country)
+		}
+	})
+
+	// checks that flagging another contact or address as primary moves the flag over
+	t.Run("it should keep a single primary", func(t *testing.T) {
+		var contacts []smodels.CompanyContact
+		for _, name := range []string{"Jane Doe", "John Doe"} {
+			resp := doRequest(t, ts, http.MethodPost, path+"/contacts", token,
+				smodels.CompanyContact{Name: name, Primary: true}, nil)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			var contact smodels.CompanyContact
+			decode(t, resp, &contact)
+			contacts = append(contacts, contact)
+		}
+		resp := doRequest(t, ts, http.MethodGet, path+"/contacts/"+contacts[0].ID, token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var first smodels.CompanyContact
+		decode(t, resp, &first)
+		assert.False(t, first.Primary)
+
+		var addresses []smodels.CompanyAddress
+		for _, city := range []string{"Berlin", "Hamburg"} {
+			resp := doRequest(t, ts, http.MethodPost, path+"/addresses", token,
+				smodels.CompanyAddress{Line1: "Hauptstrasse 1", City: city, Country: "DE", Primary: true}, nil)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			var address smodels.CompanyAddress
+			decode(t, resp, &address)
+			addresses = append(addresses, address)
+		}
+		resp = doRequest(t, ts, http.MethodGet, path+"/addresses/"+addresses[0].ID, token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var firstAddress smodels.CompanyAddress
+		decode(t, resp, &firstAddress)
+		assert.False(t, firstAddress.Primary)
+	})
+
+	// checks that the primary contact and address are embedded on request
+	t.Run("it should embed the primary contact and address", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodGet, path+"?embed=primary_contact,primary_address", token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var embedded smodels.Company
+		decode(t, resp, &embedded)
+
+		require.NotNil(t, embedded.PrimaryContact)
+		assert.Equal(t, "John Doe", embedded.PrimaryContact.Name)
+		require.NotNil(t, embedded.PrimaryAddress)
+		assert.Equal(t, "Hamburg", embedded.PrimaryAddress.City)
+
+		resp = doRequest(t, ts, http.MethodGet, path, token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var plain smodels.Company
+		decode(t, resp, &plain)
+		assert.Nil(t, plain.PrimaryContact)
+		assert.Nil(t, plain.PrimaryAddress)
+	})
+}
+
+func TestCompanySlugsIntegration(t *testing.T) {
+	ts, _ := startServer(t, nil)
+	token := signIn(t, ts, randomEmail(t))
+	word, err := regen.Generate("[a-z]{8}")
+	require.NoError(t, err)
+
+	create := func(name string) *http.Response {
+		return doRequest(t, ts, http.MethodPost, "/auth/companies?allow_duplicates=true", token,
+			smodels.Company{Name: name, Employees: 10, Type: "Corporations"}, nil)
+	}
+
+	// checks that names are unique regardless of the case
+	t.Run("it should return 409 status code", func(t *testing.T) {
+		resp := create(word + " Acme")
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = create(strings.ToUpper(word + " Acme"))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	// checks that slugs are made of the names and numbered when taken
+	t.Run("it should generate unique slugs", func(t *testing.T) {
+		var slugs []string
+		for _, name := range []string{word + " Corp", word + " Corp."} {
+			resp := create(name)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			var company smodels.Company
+			decode(t, resp, &company)
+			slugs = append(slugs, company.Slug)
+		}
+		assert.Equal(t, []string{word + "-corp", word + "-corp-2"}, slugs)
+	})
+
+	// checks that former slugs redirect to the current one after a rename
+	t.Run("it should return 301 status code", func(t *testing.T) {
+		resp := create(word + " Old")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var company smodels.Company
+		decode(t, resp, &company)
+
+		resp = doRequest(t, ts, http.MethodPatch, "/auth/companies/"+company.ID, token,
+			map[string]interface{}{"name": word + " New"}, map[string]string{"Content-Type": smodels.MergePatchContentType})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var renamed smodels.Company
+		decode(t, resp, &renamed)
+		assert.Equal(t, word+"-new", renamed.Slug)
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		redirect, err := client.Get(ts.URL + "/companies/by-slug/" + company.Slug)
+		require.NoError(t, err)
+		redirect.Body.Close()
+		assert.Equal(t, http.StatusMovedPermanently, redirect.StatusCode)
+		assert.Equal(t, "/companies/by-slug/"+renamed.Slug, redirect.Header.Get("Location"))
+	})
+}
+
+func TestCompanyOwnershipIntegration(t *testing.T) {
+	ts, _ := startServer(t, nil)
+	ownerToken := signIn(t, ts, randomEmail(t))
+	otherEmail := randomEmail(t)
+	otherToken := signIn(t, ts, otherEmail)
+	company := createCompany(t, ts, ownerToken)
+	path := "/auth/companies/" + company.ID
+	mergePatch := map[string]string{"Content-Type": smodels.MergePatchContentType}
+
+	// checks that editors cannot change the companies of other owners
+	t.Run("it should return 403 status code", func(t *testing.T) {
+		for _, method := range []string{http.MethodPatch, http.MethodDelete} {
+			resp := doRequest(t, ts, method, path, otherToken, map[string]interface{}{"employees_count": 1}, mergePatch)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode, method)
+			var body map[string]interface{}
+			decode(t, resp, &body)
+			assert.Equal(t, local.ReasonNotOwner, body["reason"], method)
+		}
+	})
+
+	// checks that the owner can change the company
+	t.Run("it should let the owner patch the company", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodPatch, path, ownerToken, map[string]interface{}{"employees_count": 1}, mergePatch)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var patched smodels.Company
+		decode(t, resp, &patched)
+		assert.Equal(t, uint64(1), patched.Employees)
+	})
+
+	// checks that the company is handed over to the new owner only
+	t.Run("it should transfer the company", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodPost, path+"/transfer", ownerToken, smodels.CompanyTransfer{OwnerEmail: otherEmail}, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var transferred smodels.Company
+		decode(t, resp, &transferred)
+		assert.NotEmpty(t, transferred.OwnerID)
+		assert.NotEqual(t, company.OwnerID, transferred.OwnerID)
+
+		resp = doRequest(t, ts, http.MethodPatch, path, ownerToken, map[string]interface{}{"employees_count": 2}, mergePatch)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = doRequest(t, ts, http.MethodPatch, path, otherToken, map[string]interface{}{"employees_count": 2}, mergePatch)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	// checks that the owner can delete the company
+	t.Run("it should let the owner delete the company", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodDelete, path, otherToken, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestRevertCompanyIntegration(t *testing.T) {
+	ts, _ := startServer(t, nil)
+	token := signIn(t, ts, randomEmail(t))
+	resp := doRequest(t, ts, http.MethodPost, "/auth/companies", token, smodels.Company{
+		Name:        randomName(t),
+		Description: "description",
+		Employees:   100,
+		Registered:  true,
+		Type:        "Corporations",
+	}, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var company smodels.Company
+	decode(t, resp, &company)
+	path := "/auth/companies/" + company.ID
+
+	resp = doRequest(t, ts, http.MethodPatch, path, token,
+		map[string]interface{}{"description": "", "employees_count": 0, "registered": false},
+		map[string]string{"Content-Type": smodels.MergePatchContentType})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// checks that reverting restores the values of the revision
+	t.Run("it should restore the revision", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodPost, path+"/revisions/1/revert", token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var reverted smodels.Company
+		decode(t, resp, &reverted)
+		assert.Equal(t, "description", reverted.Description)
+		assert.Equal(t, uint64(100), reverted.Employees)
+		assert.True(t, reverted.Registered)
+	})
+
+	// checks that false, 0 and empty values are restored as well
+	t.Run("it should restore the zero values", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodPost, path+"/revisions/2/revert", token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var reverted smodels.Company
+		decode(t, resp, &reverted)
+		assert.Empty(t, reverted.Description)
+		assert.Zero(t, reverted.Employees)
+		assert.False(t, reverted.Registered)
+	})
+}
+
+// setParent moves the company under the parent, an empty parent makes it a top level company.
+func setParent(t *testing.T, ts *httptest.Server, token, id, parentID string) *http.Response {
+	var parent *string
+	if parentID != "" {
+		parent = &parentID
+	}
+	return doRequest(t, ts, http.MethodPut, "/auth/companies/"+id+"/parent", token, smodels.CompanyParent{ParentID: parent}, nil)
+}
+
+// createTree creates a chain of companies, each one a subsidiary of the previous one.
+func createTree(t *testing.T, ts *httptest.Server, token string, size int) []smodels.Company {
+	companies := make([]smodels.Company, 0, size)
+	for i := 0; i < size; i++ {
+		company := createCompany(t, ts, token)
+		if i > 0 {
+			resp := setParent(t, ts, token, company.ID, companies[i-1].ID)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			decode(t, resp, &company)
+			require.Equal(t, companies[i-1].ID, company.ParentID)
+		}
+		companies = append(companies, company)
+	}
+	return companies
+}
+
+func companyStatus(t *testing.T, ts *httptest.Server, token, id string) int {
+	resp := doRequest(t, ts, http.MethodGet, "/auth/companies/"+id, token, nil, nil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestCompanyHierarchyIntegration(t *testing.T) {
+	ts, _ := startServer(t, nil)
+	token := signIn(t, ts, randomEmail(t))
+	tree := createTree(t, ts, token, 3)
+
+	// checks that the subtree lists every level with its depth
+	t.Run("it should return the subtree", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodGet, "/auth/companies/"+tree[0].ID+"/subtree", token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var subtree smodels.CompanySubtree
+		decode(t, resp, &subtree)
+
+		depths := make(map[string]int, len(subtree.Companies))
+		for _, node := range subtree.Companies {
+			depths[node.ID] = node.Depth
+		}
+		assert.Equal(t, map[string]int{tree[0].ID: 0, tree[1].ID: 1, tree[2].ID: 2}, depths)
+		assert.False(t, subtree.Truncated)
+	})
+
+	// checks that depth limits the levels and cannot exceed the configured maximum
+	t.Run("it should limit the depth", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.Hierarchy.MaxDepth = 2
+		ts, _ := startConfiguredServer(t, cfg, nil)
+
+		resp := doRequest(t, ts, http.MethodGet, "/auth/companies/"+tree[0].ID+"/subtree?depth=1", token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var subtree smodels.CompanySubtree
+		decode(t, resp, &subtree)
+		ids := make([]string, 0, len(subtree.Companies))
+		for _, node := range subtree.Companies {
+			ids = append(ids, node.ID)
+		}
+		assert.Equal(t, []string{tree[0].ID, tree[1].ID}, ids)
+
+		resp = doRequest(t, ts, http.MethodGet, "/auth/companies/"+tree[0].ID+"/subtree?depth=3", token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// checks that a company cannot become a subsidiary of itself or of its subsidiaries
+	t.Run("it should reject cycles", func(t *testing.T) {
+		for _, parent := range []string{tree[0].ID, tree[1].ID, tree[2].ID} {
+			resp := setParent(t, ts, token, tree[0].ID, parent)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode, parent)
+		}
+	})
+
+	// checks that companies with subsidiaries are kept by the default policy
+	t.Run("it should restrict deletes", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodDelete, "/auth/companies/"+tree[1].ID, token, nil, nil)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		var body map[string]interface{}
+		decode(t, resp, &body)
+		assert.Equal(t, local.ReasonHasSubsidiaries, body["reason"])
+		assert.Equal(t, http.StatusOK, companyStatus(t, ts, token, tree[1].ID))
+	})
+}
+
+func TestCompanyDeletePoliciesIntegration(t *testing.T) {
+	// checks that cascades trash the subtree and restores bring back what they trashed only
+	t.Run("it should cascade deletes and restores", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.Hierarchy.DeletePolicy = dmodels.DeletePolicyCascade
+		ts, _ := startConfiguredServer(t, cfg, nil)
+		token := signIn(t, ts, randomEmail(t))
+		tree := createTree(t, ts, token, 3)
+
+		deleted := createCompany(t, ts, token)
+		resp := setParent(t, ts, token, deleted.ID, tree[1].ID)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+		resp = doRequest(t, ts, http.MethodDelete, "/auth/companies/"+deleted.ID, token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+
+		resp = doRequest(t, ts, http.MethodDelete, "/auth/companies/"+tree[0].ID, token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+		for _, company := range tree {
+			assert.Equal(t, http.StatusNotFound, companyStatus(t, ts, token, company.ID))
+		}
+
+		resp = doRequest(t, ts, http.MethodPost, "/auth/companies/"+tree[0].ID+"/restore", token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+		for _, company := range tree {
+			assert.Equal(t, http.StatusOK, companyStatus(t, ts, token, company.ID))
+		}
+		assert.Equal(t, http.StatusNotFound, companyStatus(t, ts, token, deleted.ID))
+	})
+
+	// checks that cascades are refused when a subsidiary belongs to somebody else
+	t.Run("it should check the owners of the subsidiaries", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.Hierarchy.DeletePolicy = dmodels.DeletePolicyCascade
+		ts, _ := startConfiguredServer(t, cfg, nil)
+		ownerToken := signIn(t, ts, randomEmail(t))
+		otherToken := signIn(t, ts, randomEmail(t))
+		parent := createCompany(t, ts, ownerToken)
+		child := createCompany(t, ts, otherToken)
+		resp := setParent(t, ts, otherToken, child.ID, parent.ID)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+
+		resp = doRequest(t, ts, http.MethodDelete, "/auth/companies/"+parent.ID, ownerToken, nil, nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		var body map[string]interface{}
+		decode(t, resp, &body)
+		assert.Equal(t, local.ReasonNotOwner, body["reason"])
+		assert.Equal(t, http.StatusOK, companyStatus(t, ts, ownerToken, child.ID))
+	})
+
+	// checks that re-parenting moves the subsidiaries to the parent of the deleted company
+	t.Run("it should reparent subsidiaries", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.Hierarchy.DeletePolicy = dmodels.DeletePolicyReparent
+		ts, _ := startConfiguredServer(t, cfg, nil)
+		token := signIn(t, ts, randomEmail(t))
+		tree := createTree(t, ts, token, 3)
+
+		resp := doRequest(t, ts, http.MethodDelete, "/auth/companies/"+tree[1].ID, token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+
+		resp = doRequest(t, ts, http.MethodGet, "/auth/companies/"+tree[2].ID, token, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var company smodels.Company
+		decode(t, resp, &company)
+		assert.Equal(t, tree[0].ID, company.ParentID)
+	})
+}
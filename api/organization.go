@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+// ListOrganizations returns the organizations of the user, the one of the token is marked active.
+func (api *API) ListOrganizations(c *gin.Context) {
+	user := currentUser(c)
+	orgs, err := api.services.ListOrganizations(user)
+	if err != nil {
+		log.Error("[api] ListOrganizations: ListOrganizations", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := make([]smodels.Organization, 0, len(orgs))
+	for _, org := range orgs {
+		item := organizationResponse(org)
+		item.Active = org.ID == user.OrganizationID
+		resp = append(resp, item)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateOrganization creates an organization with the user as its admin,
+// the user has to switch to it to act within it.
+func (api *API) CreateOrganization(c *gin.Context) {
+	var org smodels.Organization
+	if err := c.ShouldBindJSON(&org); err != nil {
+		log.Error("[api] CreateOrganization: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := org.Validate(); err != nil {
+		log.Error("[api] CreateOrganization: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := api.services.CreateOrganization(org, currentUser(c))
+	if err != nil {
+		log.Error("[api] CreateOrganization: CreateOrganization", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, organizationResponse(created))
+}
+
+// SwitchOrganization mints tokens for another organization of the user.
+func (api *API) SwitchOrganization(c *gin.Context) {
+	orgID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		log.Error("[api] SwitchOrganization: FromString", zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": local.NotFound})
+		return
+	}
+
+	user := currentUser(c)
+	td, err := api.services.CreateToken(user.Email, orgID)
+	if err != nil {
+		log.Error("[api] SwitchOrganization: CreateToken", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	err = api.services.CreateAuth(user.Email, td)
+	if err != nil {
+		log.Error("[api] SwitchOrganization: CreateAuth", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": local.ServiceError})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse(td))
+}
+
+func organizationResponse(org dmodels.Organization) smodels.Organization {
+	return smodels.Organization{
+		ID:   org.ID.String(),
+		Name: org.Name,
+		Role: org.Role,
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+// ListUsers returns the members of the organization of the admin.
+func (api *API) ListUsers(c *gin.Context) {
+	users, err := api.services.ListMembers(currentUser(c).OrganizationID)
+	if err != nil {
+		log.Error("[api] ListUsers: ListMembers", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := make([]smodels.UserRole, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, userRoleResponse(user))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// SetUserRole assigns the role within the organization of the admin, the user joins it if needed.
+// The user has to refresh the tokens to use the role.
+func (api *API) SetUserRole(c *gin.Context) {
+	var role smodels.UserRole
+	if err := c.ShouldBindJSON(&role); err != nil {
+		log.Error("[api] SetUserRole: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := role.Validate(); err != nil {
+		log.Error("[api] SetUserRole: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := api.services.SetMemberRole(c.Param("email"), role.Role, currentUser(c))
+	if err != nil {
+		log.Error("[api] SetUserRole: SetMemberRole", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, userRoleResponse(user))
+}
+
+// RemoveUser removes the user from the organization of the admin.
+func (api *API) RemoveUser(c *gin.Context) {
+	if err := api.services.RemoveMember(c.Param("email"), currentUser(c)); err != nil {
+		log.Error("[api] RemoveUser: RemoveMember", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func userRoleResponse(user dmodels.User) smodels.UserRole {
+	return smodels.UserRole{
+		ID:    user.ID.String(),
+		Email: user.Email,
+		Role:  user.Role,
+	}
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/log"
+	"xm-task/smodels"
+)
+
+func (api *API) ListWebhooks(c *gin.Context) {
+	webhooks, err := api.services.ListWebhooks(organizationOf(c))
+	if err != nil {
+		log.Error("[api] ListWebhooks: ListWebhooks", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := make([]smodels.Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		resp = append(resp, webhookResponse(webhook))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *API) GetWebhook(c *gin.Context) {
+	webhook, err := api.services.GetWebhook(c.Param("id"), organizationOf(c))
+	if err != nil {
+		log.Error("[api] GetWebhook: GetWebhook", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, webhookResponse(webhook))
+}
+
+// CreateWebhook registers the endpoint, the response is the only one holding the secret of the webhook.
+func (api *API) CreateWebhook(c *gin.Context) {
+	var webhook smodels.Webhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		log.Error("[api] CreateWebhook: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := webhook.Validate(); err != nil {
+		log.Error("[api] CreateWebhook: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := api.services.CreateWebhook(webhook, currentUser(c))
+	if err != nil {
+		log.Error("[api] CreateWebhook: CreateWebhook", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := webhookResponse(created)
+	resp.Secret = created.Secret
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *API) UpdateWebhook(c *gin.Context) {
+	var update smodels.WebhookUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		log.Error("[api] UpdateWebhook: ShouldBindJSON", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := update.Validate(); err != nil {
+		log.Error("[api] UpdateWebhook: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := api.services.UpdateWebhook(c.Param("id"), update, currentUser(c))
+	if err != nil {
+		log.Error("[api] UpdateWebhook: UpdateWebhook", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, webhookResponse(webhook))
+}
+
+func (api *API) DeleteWebhook(c *gin.Context) {
+	if err := api.services.DeleteWebhook(c.Param("id"), currentUser(c)); err != nil {
+		log.Error("[api] DeleteWebhook: DeleteWebhook", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+func (api *API) ListWebhookDeliveries(c *gin.Context) {
+	var params smodels.WebhookDeliveryListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error("[api] ListWebhookDeliveries: ShouldBindQuery", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": local.BadRequest})
+		return
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Error("[api] ListWebhookDeliveries: Validate", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deliveries, total, err := api.services.ListWebhookDeliveries(c.Param("id"), params, organizationOf(c))
+	if err != nil {
+		log.Error("[api] ListWebhookDeliveries: ListWebhookDeliveries", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	list := smodels.WebhookDeliveryList{
+		Deliveries: make([]smodels.WebhookDelivery, 0, len(deliveries)),
+		Total:      total,
+		Limit:      params.Limit,
+		Offset:     params.Offset,
+	}
+	for _, delivery := range deliveries {
+		list.Deliveries = append(list.Deliveries, webhookDelivery(delivery))
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (api *API) GetWebhookDelivery(c *gin.Context) {
+	delivery, err := api.services.GetWebhookDelivery(c.Param("id"), c.Param("delivery"), organizationOf(c))
+	if err != nil {
+		log.Error("[api] GetWebhookDelivery: GetWebhookDelivery", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	resp := webhookDelivery(delivery)
+	resp.Payload = delivery.Payload
+	c.JSON(http.StatusOK, resp)
+}
+
+// RedeliverWebhookDelivery queues the event of the delivery again and returns the new delivery.
+func (api *API) RedeliverWebhookDelivery(c *gin.Context) {
+	delivery, err := api.services.RedeliverWebhookDelivery(c.Param("id"), c.Param("delivery"), currentUser(c))
+	if err != nil {
+		log.Error("[api] RedeliverWebhookDelivery: RedeliverWebhookDelivery", zap.Error(err))
+		c.JSON(serviceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, webhookDelivery(delivery))
+}
+
+// webhookResponse leaves the secret out, it is only returned on creation.
+func webhookResponse(webhook dmodels.Webhook) smodels.Webhook {
+	resp := smodels.Webhook{
+		ID:         webhook.ID.String(),
+		URL:        webhook.URL,
+		Events:     webhook.Events,
+		Enabled:    webhook.Enabled,
+		Failures:   webhook.Failures,
+		DisabledAt: webhook.DisabledAt,
+		CreatedAt:  webhook.CreatedAt,
+		UpdatedAt:  webhook.UpdatedAt,
+	}
+	if webhook.AuthorID.Valid {
+		resp.AuthorID = webhook.AuthorID.UUID.String()
+	}
+	return resp
+}
+
+func webhookDelivery(delivery dmodels.WebhookDelivery) smodels.WebhookDelivery {
+	resp := smodels.WebhookDelivery{
+		ID:             delivery.ID.String(),
+		Event:          delivery.Event,
+		EventID:        delivery.EventID.String(),
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		ResponseBody:   delivery.ResponseBody,
+		Error:          delivery.Error,
+		AttemptedAt:    delivery.AttemptedAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == dmodels.DeliveryStatusPending {
+		next := delivery.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	return resp
+}
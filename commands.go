@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+	"xm-task/conf"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	"xm-task/log"
+	"xm-task/services"
+	"xm-task/smodels"
+)
+
+// commands are run instead of the server when the first argument matches their name.
+var commands = map[string]func(args []string){
+	"import": runImport,
+	"export": runExport,
+	"role":   runRole,
+}
+
+// newCommandService builds the services a command needs, commands never run migrations.
+func newCommandService(configPath string) *services.ServiceFacade {
+	config, err := conf.GetNewConfig(configPath)
+	if err != nil {
+		log.Fatal("cannot read config from file", zap.Error(err))
+	}
+
+	d, err := dao.New(config, false)
+	if err != nil {
+		log.Fatal("dao.New", zap.Error(err))
+	}
+
+	s, err := services.NewService(config, d)
+	if err != nil {
+		log.Fatal("services.NewService", zap.Error(err))
+	}
+	return s
+}
+
+// mappingFlag collects repeated "-map field=Column" flags.
+type mappingFlag map[string]string
+
+func (m mappingFlag) String() string {
+	pairs := make([]string, 0, len(m))
+	for field, column := range m {
+		pairs = append(pairs, field+"="+column)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m mappingFlag) Set(value string) error {
+	field, column, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("mapping should look like field=Column")
+	}
+	m[strings.TrimSpace(field)] = column
+	return nil
+}
+
+// organizationFlag holds the organization a command acts within.
+type organizationFlag uuid.UUID
+
+func (o organizationFlag) String() string {
+	return uuid.UUID(o).String()
+}
+
+func (o *organizationFlag) Set(value string) error {
+	id, err := uuid.FromString(value)
+	if err != nil {
+		return err
+	}
+	*o = organizationFlag(id)
+	return nil
+}
+
+// runImport imports companies from a CSV or XLSX file and prints the report as JSON lines.
+func runImport(args []string) {
+	var configPath, file, format, sheet, email string
+	var dryRun bool
+	mapping := mappingFlag{}
+	org := organizationFlag(dmodels.DefaultOrganizationID)
+
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", "./config.json", "Path to the config file")
+	fs.StringVar(&file, "file", "", "Path to the CSV or XLSX file")
+	fs.StringVar(&format, "format", "", "File format, csv or xlsx, guessed by the extension by default")
+	fs.StringVar(&sheet, "sheet", "", "XLSX sheet to read, the first one by default")
+	fs.StringVar(&email, "user", "", "Email of the user recorded as the author of the companies")
+	fs.BoolVar(&dryRun, "dry-run", false, "Validate the file without creating companies")
+	fs.Var(mapping, "map", "Column of a company field as field=Column, can be repeated")
+	fs.Var(&org, "org", "Id of the organization to import into, the default one by default")
+	fs.Parse(args)
+
+	if file == "" {
+		log.Info("Usage: program_name import -file <path> [-dry-run] [-map field=Column]")
+		fs.PrintDefaults()
+		return
+	}
+
+	params := smodels.CompanyImportParams{Format: format, Sheet: sheet, DryRun: dryRun, Mapping: mapping}
+	if params.Format == "" {
+		params.Format = smodels.ImportFormat(file)
+	}
+	if err := params.Validate(); err != nil {
+		log.Fatal("invalid import params", zap.Error(err))
+	}
+
+	s := newCommandService(configPath)
+	defer s.Close()
+
+	user := dmodels.User{OrganizationID: uuid.UUID(org)}
+	if email != "" {
+		var err error
+		if user, err = s.GetMember(email, uuid.UUID(org)); err != nil {
+			log.Fatal("services.GetMember", zap.Error(err))
+		}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatal("cannot open import file", zap.Error(err))
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(os.Stdout)
+	summary, err := s.ImportCompanies(f, params, user, func(row smodels.CompanyImportRow) error {
+		return encoder.Encode(row)
+	})
+	if err != nil {
+		log.Error("services.ImportCompanies", zap.Error(err))
+	}
+
+	log.Info("Import finished",
+		zap.Bool("dry_run", summary.DryRun),
+		zap.Int("total", summary.Total),
+		zap.Int("valid", summary.Valid),
+		zap.Int("invalid", summary.Invalid),
+		zap.Int("created", summary.Created),
+		zap.Int("failed", summary.Failed))
+
+	if err != nil {
+		s.Close()
+		os.Exit(1)
+	}
+}
+
+// runExport writes companies to a file. An interrupted export is continued with -after
+// set to the last id it logged.
+func runExport(args []string) {
+	var configPath, out string
+	var params smodels.CompanyExportParams
+	org := organizationFlag(dmodels.DefaultOrganizationID)
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", "./config.json", "Path to the config file")
+	fs.StringVar(&out, "out", "", "Path to the output file")
+	fs.StringVar(&params.Format, "format", smodels.ExportFormatCSV, "Output format, csv, ndjson or parquet")
+	fs.StringVar(&params.After, "after", "", "Id of the last exported company to resume from")
+	fs.Int64Var(&params.Limit, "limit", 0, "Maximum number of companies, 0 exports everything")
+	fs.StringVar(&params.Type, "type", "", "Export companies of this type only")
+	fs.StringVar(&params.NamePrefix, "name-prefix", "", "Export companies with names starting with this prefix only")
+	fs.Var(&org, "org", "Id of the organization to export, the default one by default")
+	fs.Parse(args)
+	params.OrganizationID = org.String()
+
+	if out == "" {
+		log.Info("Usage: program_name export -out <path> [-format csv|ndjson|parquet] [-after <id>]")
+		fs.PrintDefaults()
+		return
+	}
+
+	if err := params.Validate(); err != nil {
+		log.Fatal("invalid export params", zap.Error(err))
+	}
+
+	s := newCommandService(configPath)
+	defer s.Close()
+
+	f, err := os.Create(out)
+	if err != nil {
+		log.Fatal("cannot create export file", zap.Error(err))
+	}
+	defer f.Close()
+
+	summary, err := s.ExportCompanies(f, params)
+	if err != nil {
+		log.Error("services.ExportCompanies", zap.Error(err),
+			zap.Int64("exported", summary.Exported), zap.String("last", summary.Last))
+		s.Close()
+		os.Exit(1)
+	}
+
+	log.Info("Export finished", zap.Int64("exported", summary.Exported), zap.String("last", summary.Last))
+}
+
+// runRole assigns a role within the organization to the user, the user has to sign in once before.
+func runRole(args []string) {
+	var configPath, email, role string
+	org := organizationFlag(dmodels.DefaultOrganizationID)
+
+	fs := flag.NewFlagSet("role", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", "./config.json", "Path to the config file")
+	fs.StringVar(&email, "email", "", "Email of the user")
+	fs.StringVar(&role, "role", "", "Role to assign: admin, editor or viewer")
+	fs.Var(&org, "org", "Id of the organization, the default one by default")
+	fs.Parse(args)
+
+	if email == "" || role == "" {
+		log.Info("Usage: program_name role -email <email> -role <admin|editor|viewer> [-org <id>]")
+		fs.PrintDefaults()
+		return
+	}
+
+	s := newCommandService(configPath)
+	defer s.Close()
+
+	if _, err := s.SetMemberRole(email, role, dmodels.User{OrganizationID: uuid.UUID(org)}); err != nil {
+		log.Error("services.SetMemberRole", zap.Error(err))
+		s.Close()
+		os.Exit(1)
+	}
+
+	log.Info("User updated", zap.String("email", email), zap.String("role", role), zap.Stringer("organization", org))
+}
@@ -1,18 +1,34 @@
 package conf
 
 import (
+	"time"
+
 	"github.com/spf13/viper"
 )
 
 type (
 	Config struct {
-		API      API
-		LogLevel string
-		Postgres Postgres
+		API         API
+		LogLevel    string
+		Postgres    Postgres
+		Search      Search
+		Trash       Trash
+		Import      Import
+		Hierarchy   Hierarchy
+		Stats       Stats
+		Idempotency Idempotency
+		Duplicates  Duplicates
+		Schedules   Schedules
+		Attachments Attachments
+		Logos       Logos
+		Webhooks    Webhooks
+		Feed        Feed
 	}
 	API struct {
 		ListenOnPort       uint64
 		CORSAllowedOrigins []string
+		// RequireIfMatch rejects company updates and deletes sent without an If-Match header.
+		RequireIfMatch bool
 	}
 	Postgres struct {
 		Host     string
@@ -22,9 +38,195 @@ type (
 		Database string
 		SSLMode  string
 	}
+	Search struct {
+		// Language is a Postgres text search configuration used for new companies and queries.
+		Language string
+	}
+	Trash struct {
+		// Retention is how long deleted companies can be restored before they are purged.
+		Retention     time.Duration
+		PurgeInterval time.Duration
+	}
+	Import struct {
+		// ChunkSize is how many rows are validated and written at once.
+		ChunkSize int
+		// MaxFileSize limits uploaded files, in bytes.
+		MaxFileSize int64
+	}
+	Hierarchy struct {
+		// DeletePolicy is applied to the subsidiaries of deleted companies: "restrict", "cascade" or "reparent".
+		DeletePolicy string
+		// MaxDepth limits how many levels tree queries go up or down.
+		MaxDepth int
+		// MaxSubtreeSize limits how many companies a subtree query returns.
+		MaxSubtreeSize int
+	}
+	Stats struct {
+		// CacheTTL is how long computed statistics are served before they are computed again.
+		CacheTTL time.Duration
+	}
+	Idempotency struct {
+		// Window is how long responses are replayed for retries with the same Idempotency-Key.
+		Window time.Duration
+		// LockTimeout is how long a request may hold its key, retries take over keys held longer.
+		LockTimeout time.Duration
+		// MaxResponseSize limits the stored responses, in bytes. Larger responses are not replayed.
+		MaxResponseSize int64
+		PurgeInterval   time.Duration
+	}
+	Duplicates struct {
+		// Threshold is the trigram similarity of names, between 0 and 1, from which companies are suspected duplicates.
+		Threshold float64
+		// MaxResults limits the duplicates reported when creating a company.
+		MaxResults int
+		// MaxReportPairs limits the similar pairs the duplicates report is built from.
+		MaxReportPairs int
+	}
+	Schedules struct {
+		// PollInterval is how often due scheduled changes are looked for.
+		PollInterval time.Duration
+		// BatchSize limits the changes applied per poll.
+		BatchSize int
+		// ClaimTimeout is how long a change may be applied, changes claimed longer are applied again.
+		ClaimTimeout time.Duration
+	}
+	Attachments struct {
+		// Storage keeps the files: "local" or "s3".
+		Storage string
+		// MaxFileSize limits uploaded files, in bytes.
+		MaxFileSize int64
+		// AllowedTypes are the media types, sniffed from the content, which can be uploaded.
+		AllowedTypes []string
+		Local        LocalStorage
+		S3           S3Storage
+	}
+	Logos struct {
+		// MaxFileSize limits uploaded logos, in bytes.
+		MaxFileSize int64
+		// MaxDimension limits the width and the height of uploaded logos, in pixels.
+		MaxDimension int
+		// Sizes are the longest sides of the generated thumbnails, in pixels.
+		Sizes []int
+	}
+	Webhooks struct {
+		// PollInterval is how often due deliveries are looked for.
+		PollInterval time.Duration
+		// BatchSize limits the deliveries sent per poll, Concurrency of them are sent at once.
+		BatchSize   int
+		Concurrency int
+		// ClaimTimeout is how long a delivery may be sent, deliveries claimed longer are sent again.
+		ClaimTimeout time.Duration
+		// Timeout limits a single attempt.
+		Timeout time.Duration
+		// MaxAttempts is how many times a delivery is attempted before it is marked as failed.
+		MaxAttempts int
+		// RetryBackoff is the delay after the first failed attempt, it doubles with every attempt up to MaxRetryBackoff.
+		RetryBackoff    time.Duration
+		MaxRetryBackoff time.Duration
+		// DisableAfter is how many consecutive failed attempts disable the webhook.
+		DisableAfter int
+		// Retention is how long finished deliveries are kept in the log.
+		Retention time.Duration
+		// AllowInsecure lets webhooks use plain HTTP and private networks, it is meant for development.
+		AllowInsecure bool
+	}
+	Feed struct {
+		// ReplaySize is how many of the latest events are kept for clients resuming the feed.
+		ReplaySize int
+		// TrimInterval is how often older events are removed.
+		TrimInterval time.Duration
+		// Heartbeat is how often idle streams are pinged, so that proxies keep them open.
+		Heartbeat time.Duration
+		// MaxSubscribers limits the streams open on an instance.
+		MaxSubscribers int
+		// TokenTTL is how long stream tokens, which are passed with the URL, can be used to open a stream.
+		TokenTTL time.Duration
+	}
+	LocalStorage struct {
+		// Root is the directory the files are kept in.
+		Root string
+	}
+	S3Storage struct {
+		// Endpoint is the URL of the storage, e.g. "http://minio:9000".
+		Endpoint  string
+		Region    string
+		Bucket    string
+		AccessKey string
+		SecretKey string
+	}
+)
+
+const (
+	Service = "xm-task"
+
+	DefaultSearchLanguage = "english"
+
+	DefaultTrashRetention     = time.Hour * 24 * 30
+	DefaultTrashPurgeInterval = time.Hour
+
+	DefaultImportChunkSize   = 500
+	DefaultImportMaxFileSize = 32 << 20
+
+	DefaultHierarchyDeletePolicy   = "restrict"
+	DefaultHierarchyMaxDepth       = 10
+	DefaultHierarchyMaxSubtreeSize = 1000
+
+	DefaultStatsCacheTTL = time.Minute
+
+	DefaultIdempotencyWindow          = time.Hour * 24
+	DefaultIdempotencyLockTimeout     = time.Minute * 5
+	DefaultIdempotencyMaxResponseSize = 1 << 20
+	DefaultIdempotencyPurgeInterval   = time.Hour
+
+	DefaultDuplicatesThreshold      = 0.6
+	DefaultDuplicatesMaxResults     = 5
+	DefaultDuplicatesMaxReportPairs = 10000
+
+	DefaultSchedulesPollInterval = time.Minute
+	DefaultSchedulesBatchSize    = 100
+	DefaultSchedulesClaimTimeout = time.Minute * 5
+
+	StorageLocal = "local"
+	StorageS3    = "s3"
+
+	DefaultAttachmentsMaxFileSize = 10 << 20
+	DefaultAttachmentsRoot        = "./attachments"
+	DefaultS3Region               = "us-east-1"
+
+	DefaultLogosMaxFileSize  = 2 << 20
+	DefaultLogosMaxDimension = 4096
+
+	DefaultWebhooksPollInterval    = time.Second * 5
+	DefaultWebhooksBatchSize       = 100
+	DefaultWebhooksConcurrency     = 8
+	DefaultWebhooksClaimTimeout    = time.Minute * 5
+	DefaultWebhooksTimeout         = time.Second * 10
+	DefaultWebhooksMaxAttempts     = 10
+	DefaultWebhooksRetryBackoff    = time.Second * 30
+	DefaultWebhooksMaxRetryBackoff = time.Hour * 6
+	DefaultWebhooksDisableAfter    = 50
+	DefaultWebhooksRetention       = time.Hour * 24 * 30
+
+	DefaultFeedReplaySize     = 10000
+	DefaultFeedTrimInterval   = time.Minute
+	DefaultFeedHeartbeat      = time.Second * 15
+	DefaultFeedMaxSubscribers = 1000
+	DefaultFeedTokenTTL       = time.Minute
 )
 
-const Service = "xm-task"
+// DefaultAttachmentsAllowedTypes are documents and images, office documents are sniffed as zip archives.
+var DefaultAttachmentsAllowedTypes = []string{
+	"application/pdf",
+	"application/zip",
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"text/plain",
+}
+
+// DefaultLogosSizes are the thumbnails generated for every logo.
+var DefaultLogosSizes = []int{64, 128, 256}
 
 func GetNewConfig(path string) (Config, error) {
 	// I wasn't sure about "config file" requirement, so I made both .json and .env files
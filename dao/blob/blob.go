@@ -0,0 +1,36 @@
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"xm-task/conf"
+)
+
+// ErrNotFound is returned for keys which are not stored.
+var ErrNotFound = errors.New("blob not found")
+
+// Storage keeps files by their keys, keys are slash separated paths, e.g. "org/company/attachment".
+type Storage interface {
+	// PutBlob stores size bytes of r under the key. Checksum is the hex encoded SHA-256 of the content,
+	// the blob is not stored if the content does not match it.
+	PutBlob(key string, r io.Reader, size int64, contentType, checksum string) error
+	GetBlob(key string) (io.ReadCloser, error)
+	// DeleteBlob removes the blob, missing blobs are not an error.
+	DeleteBlob(key string) error
+}
+
+func New(cfg conf.Attachments) (Storage, error) {
+	switch cfg.Storage {
+	case "", conf.StorageLocal:
+		root := cfg.Local.Root
+		if root == "" {
+			root = conf.DefaultAttachmentsRoot
+		}
+		return NewLocal(root)
+	case conf.StorageS3:
+		return NewS3(cfg.S3)
+	}
+	return nil, fmt.Errorf("unknown storage %q", cfg.Storage)
+}
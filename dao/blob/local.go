@@ -0,0 +1,96 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local keeps blobs as files under the root directory.
+type Local struct {
+	root string
+}
+
+func NewLocal(root string) (*Local, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("os.MkdirAll: %v", err)
+	}
+	return &Local{root: root}, nil
+}
+
+// PutBlob writes the content to a temporary file next to the target and renames it once
+// the checksum matches, so that readers never see partial blobs.
+func (l *Local) PutBlob(key string, r io.Reader, size int64, contentType, checksum string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("os.MkdirAll: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("os.CreateTemp: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, size+1))
+	if err != nil {
+		return fmt.Errorf("io.Copy: %v", err)
+	}
+	if written != size {
+		return fmt.Errorf("size %d instead of %d", written, size)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != checksum {
+		return fmt.Errorf("checksum %s instead of %s", sum, checksum)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("tmp.Close: %v", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (l *Local) GetBlob(key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (l *Local) DeleteBlob(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path maps the key to a file under the root, keys cannot leave the root.
+func (l *Local) path(key string) (string, error) {
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." || strings.ContainsRune(part, '\\') {
+			return "", fmt.Errorf("invalid key %q", key)
+		}
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
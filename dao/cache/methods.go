@@ -1,6 +1,11 @@
 package cache
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"xm-task/dmodels"
+)
 
 func (c *Cache) AddAuthToken(key string, item interface{}, expiration time.Duration) (err error) {
 	c.cache.Set(key, item, expiration)
@@ -20,3 +25,49 @@ func (c *Cache) RemoveAuthToken(key string) error {
 	c.cache.Delete(key)
 	return nil
 }
+
+const companyTypePrefix = "company_type:"
+
+// AddCompanyType caches the type by its name for the default expiration time.
+func (c *Cache) AddCompanyType(ct dmodels.CompanyType) error {
+	c.cache.SetDefault(companyTypePrefix+ct.Name, ct)
+	return nil
+}
+
+func (c *Cache) GetCompanyType(name string) (dmodels.CompanyType, bool, error) {
+	item, ok := c.cache.Get(companyTypePrefix + name)
+	if !ok {
+		return dmodels.CompanyType{}, false, nil
+	}
+
+	ct, ok := item.(dmodels.CompanyType)
+	return ct, ok, nil
+}
+
+// RemoveCompanyTypes drops every cached type, it is called after types are changed.
+func (c *Cache) RemoveCompanyTypes() error {
+	for key := range c.cache.Items() {
+		if strings.HasPrefix(key, companyTypePrefix) {
+			c.cache.Delete(key)
+		}
+	}
+	return nil
+}
+
+const companyStatsPrefix = "company_stats:"
+
+// AddCompanyStats caches statistics by the key of the query which computed them.
+func (c *Cache) AddCompanyStats(key string, stats dmodels.CompanyStats, expiration time.Duration) error {
+	c.cache.Set(companyStatsPrefix+key, stats, expiration)
+	return nil
+}
+
+func (c *Cache) GetCompanyStats(key string) (dmodels.CompanyStats, bool, error) {
+	item, ok := c.cache.Get(companyStatsPrefix + key)
+	if !ok {
+		return dmodels.CompanyStats{}, false, nil
+	}
+
+	stats, ok := item.(dmodels.CompanyStats)
+	return stats, ok, nil
+}
@@ -1,10 +1,13 @@
 package dao
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	uuid "github.com/satori/go.uuid"
 	"xm-task/conf"
+	"xm-task/dao/blob"
 	"xm-task/dao/cache"
 	"xm-task/dao/postgres"
 	"xm-task/dmodels"
@@ -14,6 +17,11 @@ type (
 	DAO interface {
 		Postgres
 		Cache
+		blob.Storage
+
+		// Transaction runs fn with a DAO whose database methods are run in a single transaction,
+		// it commits unless fn returns an error.
+		Transaction(fn func(tx DAO) error) error
 	}
 
 	Postgres interface {
@@ -21,24 +29,126 @@ type (
 
 		CreateUser(user dmodels.User) (dmodels.User, error)
 		GetUserByEmail(email string) (dmodels.User, error)
+		GetMember(email string, orgID uuid.UUID) (dmodels.User, error)
+		ListMembers(orgID uuid.UUID) ([]dmodels.User, error)
+		SetMemberRole(email string, orgID uuid.UUID, role string) error
+		RemoveMember(email string, orgID uuid.UUID) error
+
+		CreateOrganization(org dmodels.Organization, userID uuid.UUID) (dmodels.Organization, error)
+		ListUserOrganizations(userID uuid.UUID) ([]dmodels.Organization, error)
+
+		CreateCompany(company dmodels.Company, authorID uuid.UUID) (dmodels.Company, error)
+		UpdateCompany(company dmodels.Company, authorID uuid.UUID) (dmodels.Company, error)
+		RevertCompany(company dmodels.Company, authorID uuid.UUID, revision uint64) (dmodels.Company, error)
+		GetCompanyByID(id string, orgID uuid.UUID) (dmodels.CompanyShow, error)
+		GetCompanyBySlug(slug string, orgID uuid.UUID) (dmodels.CompanyShow, error)
+		GetTrashedCompanyByID(id string, orgID uuid.UUID) (dmodels.CompanyShow, error)
+		ListCompanies(query dmodels.CompanyListQuery) ([]dmodels.CompanyShow, error)
+		CountCompanies(filter dmodels.CompanyFilter) (int64, error)
+		CompanyStats(query dmodels.CompanyStatsQuery) (dmodels.CompanyStats, error)
+		SearchCompanies(query dmodels.CompanySearchQuery) ([]dmodels.CompanySearchResult, error)
+		CountSearchCompanies(query dmodels.CompanySearchQuery) (int64, error)
+		ExistingCompanyNames(names []string, orgID uuid.UUID) ([]string, error)
+		SimilarCompanies(name string, orgID uuid.UUID, threshold float64, limit int) ([]dmodels.SimilarCompany, error)
+		CompanyDuplicatePairs(orgID uuid.UUID, threshold float64, limit int) ([]dmodels.CompanyDuplicatePair, error)
+		DeleteCompanyByID(id string, orgID uuid.UUID, version uint64, policy string, authorID uuid.UUID) ([]dmodels.CompanyShow, error)
+		RestoreCompanyByID(id string, orgID uuid.UUID, authorID uuid.UUID) ([]dmodels.CompanyShow, error)
+		TransferCompany(id string, orgID uuid.UUID, ownerID uuid.UUID, version uint64, authorID uuid.UUID) error
+		PurgeCompanies(deletedBefore time.Time) (int64, error)
+		ApplyCompanyBatch(ops []dmodels.CompanyBatchOperation, atomic bool, orgID uuid.UUID, authorID uuid.UUID) ([]error, error)
+
+		CompanyAncestors(id string, orgID uuid.UUID, depth int) ([]dmodels.CompanyNode, error)
+		CompanySubtree(id string, orgID uuid.UUID, depth int, limit int) ([]dmodels.CompanyNode, error)
+		CompanyGroup(id string, orgID uuid.UUID) (dmodels.CompanyGroup, error)
+		SetCompanyParent(id string, orgID uuid.UUID, parentID *uuid.UUID, version uint64, authorID uuid.UUID) error
+		LockCompanyHierarchy(orgID uuid.UUID) error
+
+		ListCompanyContacts(companyID string, orgID uuid.UUID) ([]dmodels.CompanyContact, error)
+		GetCompanyContact(companyID, id string, orgID uuid.UUID) (dmodels.CompanyContact, error)
+		PrimaryCompanyContact(companyID string, orgID uuid.UUID) (dmodels.CompanyContact, error)
+		CreateCompanyContact(contact dmodels.CompanyContact, orgID uuid.UUID) (dmodels.CompanyContact, error)
+		UpdateCompanyContact(contact dmodels.CompanyContact, orgID uuid.UUID) (dmodels.CompanyContact, error)
+		DeleteCompanyContact(companyID, id string, orgID uuid.UUID) error
+
+		ListCompanyAddresses(companyID string, orgID uuid.UUID) ([]dmodels.CompanyAddress, error)
+		GetCompanyAddress(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAddress, error)
+		PrimaryCompanyAddress(companyID string, orgID uuid.UUID) (dmodels.CompanyAddress, error)
+		CreateCompanyAddress(address dmodels.CompanyAddress, orgID uuid.UUID) (dmodels.CompanyAddress, error)
+		UpdateCompanyAddress(address dmodels.CompanyAddress, orgID uuid.UUID) (dmodels.CompanyAddress, error)
+		DeleteCompanyAddress(companyID, id string, orgID uuid.UUID) error
+
+		ListCompanySchedules(companyID string, status string, orgID uuid.UUID) ([]dmodels.CompanySchedule, error)
+		GetCompanySchedule(companyID, id string, orgID uuid.UUID) (dmodels.CompanySchedule, error)
+		CreateCompanySchedule(schedule dmodels.CompanySchedule) (dmodels.CompanySchedule, []dmodels.CompanySchedule, error)
+		CancelCompanySchedule(companyID, id string, orgID uuid.UUID) (dmodels.CompanySchedule, error)
+		ClaimDueCompanySchedules(limit int, claimTimeout time.Duration) ([]dmodels.CompanySchedule, error)
+		FinishCompanySchedule(id uuid.UUID, status string, reason string) error
+
+		ListCompanyAttachments(companyID string, orgID uuid.UUID) ([]dmodels.CompanyAttachment, error)
+		GetCompanyAttachment(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAttachment, error)
+		CreateCompanyAttachment(attachment dmodels.CompanyAttachment, orgID uuid.UUID) (dmodels.CompanyAttachment, error)
+		DeleteCompanyAttachment(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAttachment, error)
+		ListBlobDeletions(limit int) ([]string, error)
+		RemoveBlobDeletions(keys []string) error
+
+		GetCompanyLogo(companyID string, orgID uuid.UUID) (dmodels.CompanyLogo, error)
+		FindCompanyLogo(companyID string) (dmodels.CompanyLogo, error)
+		SetCompanyLogo(logo dmodels.CompanyLogo, orgID uuid.UUID) (dmodels.CompanyLogo, error)
+		DeleteCompanyLogo(companyID string, orgID uuid.UUID) error
+
+		ListWebhooks(orgID uuid.UUID) ([]dmodels.Webhook, error)
+		GetWebhook(id string, orgID uuid.UUID) (dmodels.Webhook, error)
+		CreateWebhook(webhook dmodels.Webhook) (dmodels.Webhook, error)
+		UpdateWebhook(webhook dmodels.Webhook) (dmodels.Webhook, error)
+		DeleteWebhook(id string, orgID uuid.UUID) error
+		ListWebhookDeliveries(webhookID string, status string, limit, offset int, orgID uuid.UUID) ([]dmodels.WebhookDelivery, int64, error)
+		GetWebhookDelivery(webhookID, id string, orgID uuid.UUID) (dmodels.WebhookDelivery, error)
+		RedeliverWebhookDelivery(webhookID, id string, orgID uuid.UUID) (dmodels.WebhookDelivery, error)
+		QueueWebhookDeliveries(orgID uuid.UUID, event string, eventID uuid.UUID, payload dmodels.JSONObject) (int64, error)
+		ClaimDueWebhookDeliveries(limit int, claimTimeout time.Duration) ([]dmodels.WebhookDelivery, error)
+		RecordWebhookAttempt(attempt dmodels.WebhookAttempt, disableAfter int) error
+		PurgeWebhookDeliveries(before time.Time) (int64, error)
 
-		CreateCompany(company dmodels.Company) (dmodels.Company, error)
-		UpdateCompany(company dmodels.Company) (dmodels.Company, error)
-		GetCompanyByID(id string) (dmodels.CompanyShow, error)
-		DeleteCompanyByID(id string) error
+		AddCompanyEvent(event dmodels.CompanyEvent) error
+		GetCompanyEvent(id int64) (dmodels.CompanyEvent, error)
+		ListCompanyEvents(afterID int64, limit int, orgID uuid.UUID) ([]dmodels.CompanyEvent, error)
+		FirstCompanyEventID() (int64, error)
+		TrimCompanyEvents(keep int) (int64, error)
+		ListenCompanyEvents(ctx context.Context, listening func(), notify func(id int64) error, typesChanged func()) error
+
+		ListCompanyRevisions(companyID string, orgID uuid.UUID) ([]dmodels.CompanyRevision, error)
+		GetCompanyRevision(companyID string, orgID uuid.UUID, revision uint64) (dmodels.CompanyRevision, error)
+
+		AcquireIdempotencyKey(key dmodels.IdempotencyKey, window, lockTimeout time.Duration) (dmodels.IdempotencyKey, bool, error)
+		SaveIdempotencyResponse(key dmodels.IdempotencyKey) error
+		ReleaseIdempotencyKey(userID uuid.UUID, key, fingerprint string) error
+		PurgeIdempotencyKeys() (int64, error)
 
 		GetCompanyTypeByName(name string) (dmodels.CompanyType, error)
+		GetCompanyTypeByID(id uint64) (dmodels.CompanyType, error)
+		ListCompanyTypes(withDeprecated bool) ([]dmodels.CompanyType, error)
+		CreateCompanyType(ct dmodels.CompanyType) (dmodels.CompanyType, error)
+		UpdateCompanyType(ct dmodels.CompanyType) (dmodels.CompanyType, error)
+		MergeCompanyTypes(sourceID, targetID uint64, authorID uuid.UUID) ([]dmodels.Company, error)
 	}
 
 	Cache interface {
 		AddAuthToken(key string, item interface{}, expiration time.Duration) error
 		GetAuthToken(token string) (interface{}, bool, error)
 		RemoveAuthToken(key string) error
+
+		AddCompanyType(ct dmodels.CompanyType) error
+		GetCompanyType(name string) (dmodels.CompanyType, bool, error)
+		RemoveCompanyTypes() error
+
+		AddCompanyStats(key string, stats dmodels.CompanyStats, expiration time.Duration) error
+		GetCompanyStats(key string) (dmodels.CompanyStats, bool, error)
 	}
 
 	daoImpl struct {
 		*postgres.Postgres
 		*cache.Cache
+		blob.Storage
 	}
 )
 
@@ -48,8 +158,20 @@ func New(cfg conf.Config, migrate bool) (DAO, error) {
 		return nil, fmt.Errorf("postgres.NewPostgres: %s", err.Error())
 	}
 
+	storage, err := blob.New(cfg.Attachments)
+	if err != nil {
+		return nil, fmt.Errorf("blob.New: %s", err.Error())
+	}
+
 	return daoImpl{
 		Postgres: pg,
 		Cache:    cache.NewCache(),
+		Storage:  storage,
 	}, nil
 }
+
+func (d daoImpl) Transaction(fn func(tx DAO) error) error {
+	return d.Postgres.Transaction(func(tx *postgres.Postgres) error {
+		return fn(daoImpl{Postgres: tx, Cache: d.Cache, Storage: d.Storage})
+	})
+}
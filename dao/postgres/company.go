@@ -2,34 +2,440 @@ package postgres
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
 	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
 )
 
-func (db *Postgres) CreateCompany(company dmodels.Company) (dmodels.Company, error) {
-	err := db.db.Table(dmodels.CompaniesTable).Create(&company).Error
+const companyShowFields = "c.id, c.name, c.description, c.employees, c.registered, ct.name as type, c.created_at, c.deleted_at, c.version, c.owner_id, c.organization_id, c.parent_id, c.attributes, c.slug, " +
+	"coalesce((select l.hash from company_logos l where l.company_id = c.id), '') as logo_hash"
+
+const (
+	headlineNameOptions    = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+	headlineSnippetOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=25, MinWords=10"
+)
+
+// escapeHTML is the SQL expression escaping the column for HTML. Headlines are built from the escaped text,
+// so that the <mark> tags are the only markup of the highlights; the parser keeps entities as they are.
+func escapeHTML(column string) string {
+	return "replace(replace(replace(replace(replace(" + column +
+		`, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+}
+
+var companySortColumns = map[string]string{
+	dmodels.CompanySortID:         "c.id",
+	dmodels.CompanySortName:       "c.name",
+	dmodels.CompanySortEmployees:  "c.employees",
+	dmodels.CompanySortRegistered: "c.registered",
+	dmodels.CompanySortType:       "ct.name",
+	dmodels.CompanySortCreatedAt:  "c.created_at",
+}
+
+func (db *Postgres) CreateCompany(company dmodels.Company, authorID uuid.UUID) (dmodels.Company, error) {
+	err := db.scoped(company.OrganizationID, func(tx *gorm.DB) error {
+		if err := tx.Table(dmodels.CompaniesTable).Create(&company).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(dmodels.CompaniesTable).Select("slug").Where("id = ?", company.ID).Scan(&company.Slug).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, company.ID.String(), dmodels.RevisionActionCreate, authorID, nil)
+	})
+	return company, err
+}
+
+func (db *Postgres) UpdateCompany(company dmodels.Company, authorID uuid.UUID) (dmodels.Company, error) {
+	err := db.scoped(company.OrganizationID, func(tx *gorm.DB) error {
+		if err := updateCompany(tx, &company); err != nil {
+			return err
+		}
+		return recordRevision(tx, company.ID.String(), dmodels.RevisionActionUpdate, authorID, nil)
+	})
 	return company, err
 }
 
-func (db *Postgres) UpdateCompany(company dmodels.Company) (dmodels.Company, error) {
-	err := db.db.Table(dmodels.CompaniesTable).
-		Where("id = ?", company.ID.String()).
-		Updates(&company).Error
+// RevertCompany updates the company with the values of a previous revision.
+func (db *Postgres) RevertCompany(company dmodels.Company, authorID uuid.UUID, revision uint64) (dmodels.Company, error) {
+	err := db.scoped(company.OrganizationID, func(tx *gorm.DB) error {
+		if err := updateCompany(tx, &company); err != nil {
+			return err
+		}
+		return recordRevision(tx, company.ID.String(), dmodels.RevisionActionRevert, authorID, &revision)
+	})
 	return company, err
 }
 
-func (db *Postgres) GetCompanyByID(id string) (dmodels.CompanyShow, error) {
+// updateCompany writes the company and bumps its version. The version is checked
+// when set, so the update fails if somebody has changed the company in between.
+// The columns are passed as a map, so that reverts restore false, 0 and empty values too.
+func updateCompany(tx *gorm.DB, company *dmodels.Company) error {
+	q := tx.Table(dmodels.CompaniesTable).
+		Where("id = ? and organization_id = ? and deleted_at is null", company.ID.String(), company.OrganizationID)
+	if company.Version != 0 {
+		q = q.Where("version = ?", company.Version)
+	}
+
+	result := q.Updates(map[string]interface{}{
+		"name":        company.Name,
+		"description": company.Description,
+		"employees":   company.Employees,
+		"registered":  company.Registered,
+		"type_id":     company.TypeID,
+		"attributes":  company.Attributes,
+		"updated_at":  company.UpdatedAt,
+		"version":     gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return versionMismatch(tx, company.ID.String(), company.OrganizationID)
+	}
+
+	return tx.Table(dmodels.CompaniesTable).
+		Select("version, slug").
+		Where("id = ? and organization_id = ?", company.ID.String(), company.OrganizationID).
+		Row().Scan(&company.Version, &company.Slug)
+}
+
+// versionMismatch tells apart a missing company from a stale version after an update matched nothing.
+func versionMismatch(tx *gorm.DB, id string, orgID uuid.UUID) error {
+	var live int64
+	err := tx.Table(dmodels.CompaniesTable).
+		Where("id = ? and organization_id = ? and deleted_at is null", id, orgID).
+		Count(&live).Error
+	if err != nil {
+		return err
+	}
+	if live == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return local.ErrPreconditionFailed
+}
+
+func (db *Postgres) GetCompanyByID(id string, orgID uuid.UUID) (dmodels.CompanyShow, error) {
 	var company dmodels.CompanyShow
-	err := db.db.Table(fmt.Sprintf("%s c", dmodels.CompaniesTable)).
-		Select("c.id, c.name, c.description, c.employees, c.registered, ct.name as type").
-		Where("c.id = ?", id).
-		Joins("inner join company_types ct on ct.id = c.type_id").
-		Scan(&company).Error
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return scopedCompanies(tx, orgID).
+			Select(companyShowFields).
+			Where("c.id = ? and c.deleted_at is null", id).
+			Take(&company).Error
+	})
 	return company, err
 }
 
-func (db *Postgres) DeleteCompanyByID(id string) error {
-	return db.db.Table(dmodels.CompaniesTable).
-		Where("id = ?", id).
-		Delete(&dmodels.Company{}).Error
+// GetCompanyBySlug looks the live company up by its current or any of its former slugs,
+// the returned company has the current one.
+func (db *Postgres) GetCompanyBySlug(slug string, orgID uuid.UUID) (dmodels.CompanyShow, error) {
+	var company dmodels.CompanyShow
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return scopedCompanies(tx, orgID).
+			Select(companyShowFields).
+			Joins("inner join company_slugs s on s.company_id = c.id").
+			Where("s.organization_id = ? and s.slug = ? and c.deleted_at is null", orgID, slug).
+			Take(&company).Error
+	})
+	return company, err
+}
+
+// GetTrashedCompanyByID returns a soft deleted company, see RestoreCompanyByID.
+func (db *Postgres) GetTrashedCompanyByID(id string, orgID uuid.UUID) (dmodels.CompanyShow, error) {
+	var company dmodels.CompanyShow
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return scopedCompanies(tx, orgID).
+			Select(companyShowFields).
+			Where("c.id = ? and c.deleted_at is not null", id).
+			Take(&company).Error
+	})
+	return company, err
+}
+
+func (db *Postgres) ListCompanies(query dmodels.CompanyListQuery) ([]dmodels.CompanyShow, error) {
+	companies := make([]dmodels.CompanyShow, 0)
+	err := db.scoped(query.Filter.OrganizationID, func(tx *gorm.DB) error {
+		return listCompanies(tx, query, &companies)
+	})
+	return companies, err
+}
+
+func listCompanies(tx *gorm.DB, query dmodels.CompanyListQuery, companies *[]dmodels.CompanyShow) error {
+	q := filterCompanies(tx, query.Filter).Select(companyShowFields)
+
+	sorts := append(append([]dmodels.CompanySort{}, query.Sort...), dmodels.CompanySort{Field: dmodels.CompanySortID})
+	backward := false
+	if query.Cursor != nil {
+		cond, args, err := keysetCondition(sorts, *query.Cursor)
+		if err != nil {
+			return err
+		}
+		q = q.Where(cond, args...)
+		backward = query.Cursor.Backward
+	}
+
+	for _, s := range sorts {
+		direction := "asc"
+		if s.Desc != backward {
+			direction = "desc"
+		}
+		q = q.Order(fmt.Sprintf("%s %s", companySortColumns[s.Field], direction))
+	}
+
+	return q.Limit(query.Limit).Offset(query.Offset).Scan(companies).Error
+}
+
+func (db *Postgres) CountCompanies(filter dmodels.CompanyFilter) (int64, error) {
+	var total int64
+	err := db.scoped(filter.OrganizationID, func(tx *gorm.DB) error {
+		return filterCompanies(tx, filter).Count(&total).Error
+	})
+	return total, err
+}
+
+// SearchCompanies returns a page of the companies matching the text, the highest ranked first.
+func (db *Postgres) SearchCompanies(query dmodels.CompanySearchQuery) ([]dmodels.CompanySearchResult, error) {
+	results := make([]dmodels.CompanySearchResult, 0)
+	err := db.scoped(query.Filter.OrganizationID, func(tx *gorm.DB) error {
+		return searchCompanies(tx, query, &results)
+	})
+	return results, err
+}
+
+// CountSearchCompanies returns how many companies match the text, regardless of the page.
+func (db *Postgres) CountSearchCompanies(query dmodels.CompanySearchQuery) (int64, error) {
+	var total int64
+	err := db.scoped(query.Filter.OrganizationID, func(tx *gorm.DB) error {
+		return matchSearch(tx, query).Count(&total).Error
+	})
+	return total, err
+}
+
+// searchCompanies pages the matches first, so that the headlines are only built for the returned companies.
+func searchCompanies(tx *gorm.DB, query dmodels.CompanySearchQuery, results *[]dmodels.CompanySearchResult) error {
+	page := matchSearch(tx, query).
+		Select(companyShowFields + ", ts_rank_cd(c.search_vector, q.query) as rank").
+		Order("rank desc, c.id").
+		Limit(query.Limit).
+		Offset(query.Offset)
+
+	return tx.Table("(?) as p", page).
+		Select(`p.*,
+			ts_headline(?::regconfig, `+escapeHTML("p.name")+`, q.query, ?) as name_highlight,
+			ts_headline(?::regconfig, `+escapeHTML("p.description")+`, q.query, ?) as snippet`,
+			query.Language, headlineNameOptions, query.Language, headlineSnippetOptions).
+		Joins("cross join websearch_to_tsquery(?::regconfig, ?) as q(query)", query.Language, query.Text).
+		Order("p.rank desc, p.id").
+		Scan(results).Error
+}
+
+func matchSearch(tx *gorm.DB, query dmodels.CompanySearchQuery) *gorm.DB {
+	return filterCompanies(tx, query.Filter).
+		Joins("cross join websearch_to_tsquery(?::regconfig, ?) as q(query)", query.Language, query.Text).
+		Where("c.search_vector @@ q.query")
+}
+
+// ExistingCompanyNames returns which of the given names are taken by live companies of the organization,
+// names are compared regardless of the case and returned in lower case.
+func (db *Postgres) ExistingCompanyNames(names []string, orgID uuid.UUID) ([]string, error) {
+	existing := make([]string, 0)
+	if len(names) == 0 {
+		return existing, nil
+	}
+	lowered := make([]string, 0, len(names))
+	for _, name := range names {
+		lowered = append(lowered, strings.ToLower(name))
+	}
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return tx.Table(dmodels.CompaniesTable).
+			Where("lower(name) in ? and organization_id = ? and deleted_at is null", lowered, orgID).
+			Pluck("lower(name)", &existing).Error
+	})
+	return existing, err
+}
+
+// DeleteCompanyByID moves the company to the trash, see PurgeCompanies. A non-zero version has to match
+// the current one. Subsidiaries are handled according to the delete policy, the changed ones are returned.
+func (db *Postgres) DeleteCompanyByID(id string, orgID uuid.UUID, version uint64, policy string, authorID uuid.UUID) ([]dmodels.CompanyShow, error) {
+	var affected []dmodels.CompanyShow
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		var err error
+		affected, err = deleteCompany(tx, id, orgID, version, policy, authorID)
+		return err
+	})
+	return affected, err
+}
+
+func deleteCompany(tx *gorm.DB, id string, orgID uuid.UUID, version uint64, policy string, authorID uuid.UUID) ([]dmodels.CompanyShow, error) {
+	if err := lockHierarchy(tx, orgID); err != nil {
+		return nil, err
+	}
+
+	q := tx.Table(dmodels.CompaniesTable).
+		Where("id = ? and organization_id = ? and deleted_at is null", id, orgID)
+	if version != 0 {
+		q = q.Where("version = ?", version)
+	}
+
+	result := q.Updates(map[string]interface{}{
+		"deleted_at": gorm.Expr("now()"),
+		"version":    gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, versionMismatch(tx, id, orgID)
+	}
+	if err := recordRevision(tx, id, dmodels.RevisionActionDelete, authorID, nil); err != nil {
+		return nil, err
+	}
+	return deleteSubsidiaries(tx, id, orgID, policy, authorID)
+}
+
+// TransferCompany changes the owner of the company. A non-zero version has to match the current one.
+func (db *Postgres) TransferCompany(id string, orgID uuid.UUID, ownerID uuid.UUID, version uint64, authorID uuid.UUID) error {
+	return db.scoped(orgID, func(tx *gorm.DB) error {
+		q := tx.Table(dmodels.CompaniesTable).
+			Where("id = ? and organization_id = ? and deleted_at is null", id, orgID)
+		if version != 0 {
+			q = q.Where("version = ?", version)
+		}
+
+		result := q.Updates(map[string]interface{}{
+			"owner_id":   ownerID,
+			"updated_at": gorm.Expr("now()"),
+			"version":    gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return versionMismatch(tx, id, orgID)
+		}
+		return recordRevision(tx, id, dmodels.RevisionActionTransfer, authorID, nil)
+	})
+}
+
+// RestoreCompanyByID takes the company out of the trash along with the subsidiaries trashed by its cascade
+// delete and returns the restored subsidiaries.
+func (db *Postgres) RestoreCompanyByID(id string, orgID uuid.UUID, authorID uuid.UUID) ([]dmodels.CompanyShow, error) {
+	var restored []dmodels.CompanyShow
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := lockHierarchy(tx, orgID); err != nil {
+			return err
+		}
+
+		// the subsidiaries go first, they are found by the time the company was trashed
+		var err error
+		restored, err = restoreSubsidiaries(tx, id, orgID, authorID)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Table(dmodels.CompaniesTable).
+			Where("id = ? and organization_id = ? and deleted_at is not null", id, orgID).
+			Updates(map[string]interface{}{
+				"deleted_at": nil,
+				"updated_at": gorm.Expr("now()"),
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recordRevision(tx, id, dmodels.RevisionActionRestore, authorID, nil)
+	})
+	return restored, err
+}
+
+// PurgeCompanies permanently removes companies of every organization trashed before the given time.
+func (db *Postgres) PurgeCompanies(deletedBefore time.Time) (int64, error) {
+	result := db.db.Table(dmodels.CompaniesTable).
+		Where("deleted_at < ?", deletedBefore).
+		Delete(&dmodels.Company{})
+	return result.RowsAffected, result.Error
+}
+
+// scopedCompanies selects the companies of the organization along with their types.
+func scopedCompanies(tx *gorm.DB, orgID uuid.UUID) *gorm.DB {
+	return tx.Table(fmt.Sprintf("%s c", dmodels.CompaniesTable)).
+		Joins("inner join company_types ct on ct.id = c.type_id").
+		Where("c.organization_id = ?", orgID)
+}
+
+func filterCompanies(tx *gorm.DB, filter dmodels.CompanyFilter) *gorm.DB {
+	q := scopedCompanies(tx, filter.OrganizationID)
+	if filter.Trashed {
+		q = q.Where("c.deleted_at is not null")
+	} else {
+		q = q.Where("c.deleted_at is null")
+	}
+	return matchCompanies(q, filter)
+}
+
+// matchCompanies applies the filter to live and trashed companies alike, Trashed is left to the caller.
+func matchCompanies(q *gorm.DB, filter dmodels.CompanyFilter) *gorm.DB {
+	if filter.Type != "" {
+		q = q.Where("ct.name = ?", filter.Type)
+	}
+	if filter.Registered != nil {
+		q = q.Where("c.registered = ?", *filter.Registered)
+	}
+	if filter.EmployeesMin != nil {
+		q = q.Where("c.employees >= ?", *filter.EmployeesMin)
+	}
+	if filter.EmployeesMax != nil {
+		q = q.Where("c.employees <= ?", *filter.EmployeesMax)
+	}
+	if filter.OwnerID != nil {
+		q = q.Where("c.owner_id = ?", *filter.OwnerID)
+	}
+	if filter.ParentID != nil {
+		q = q.Where("c.parent_id = ?", *filter.ParentID)
+	}
+	if len(filter.Attributes) > 0 {
+		q = q.Where("c.attributes @> cast(? as jsonb)", filter.Attributes)
+	}
+	if filter.NamePrefix != "" {
+		q = q.Where(`c.name ilike ? escape '\'`, escapeLike(filter.NamePrefix)+"%")
+	}
+	return q
+}
+
+// keysetCondition builds "(a > ?) or (a = ? and b > ?) or ..." for the given
+// sort fields, flipping comparison for descending fields and backward cursors.
+func keysetCondition(sorts []dmodels.CompanySort, cursor dmodels.CompanyCursor) (string, []interface{}, error) {
+	if len(cursor.Values) != len(sorts)-1 {
+		return "", nil, fmt.Errorf("cursor has %d values, expected %d", len(cursor.Values), len(sorts)-1)
+	}
+	values := append(append([]interface{}{}, cursor.Values...), cursor.ID)
+
+	var (
+		ors  []string
+		args []interface{}
+	)
+	for i := range sorts {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = ?", companySortColumns[sorts[j].Field]))
+			args = append(args, values[j])
+		}
+		op := ">"
+		if sorts[i].Desc != cursor.Backward {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s ?", companySortColumns[sorts[i].Field], op))
+		args = append(args, values[i])
+		ors = append(ors, fmt.Sprintf("(%s)", strings.Join(ands, " and ")))
+	}
+	return fmt.Sprintf("(%s)", strings.Join(ors, " or ")), args, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }
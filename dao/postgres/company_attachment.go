@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+)
+
+// Attachments are only reachable through live companies. Deleting an attachment, or purging its company,
+// queues the blob for removal from the storage, see ListBlobDeletions.
+
+func (db *Postgres) ListCompanyAttachments(companyID string, orgID uuid.UUID) ([]dmodels.CompanyAttachment, error) {
+	attachments := make([]dmodels.CompanyAttachment, 0)
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companyAttachments(tx, companyID, orgID).
+			Order("x.created_at, x.id").
+			Find(&attachments).Error
+	})
+	return attachments, err
+}
+
+func (db *Postgres) GetCompanyAttachment(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAttachment, error) {
+	var attachment dmodels.CompanyAttachment
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companyAttachments(tx, companyID, orgID).
+			Where("x.id = ?", id).
+			Take(&attachment).Error
+	})
+	return attachment, err
+}
+
+// CreateCompanyAttachment stores the attachment of a live company.
+func (db *Postgres) CreateCompanyAttachment(attachment dmodels.CompanyAttachment, orgID uuid.UUID) (dmodels.CompanyAttachment, error) {
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := lockCompany(tx, attachment.CompanyID, orgID); err != nil {
+			return err
+		}
+		return tx.Table(dmodels.CompanyAttachmentsTable).Create(&attachment).Error
+	})
+	return attachment, err
+}
+
+// DeleteCompanyAttachment removes the attachment and returns it, its blob is queued for removal.
+func (db *Postgres) DeleteCompanyAttachment(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAttachment, error) {
+	var attachment dmodels.CompanyAttachment
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := companyAttachments(tx, companyID, orgID).
+			Where("x.id = ?", id).
+			Take(&attachment).Error; err != nil {
+			return err
+		}
+		return tx.Table(dmodels.CompanyAttachmentsTable).
+			Where("id = ?", attachment.ID).
+			Delete(&dmodels.CompanyAttachment{}).Error
+	})
+	return attachment, err
+}
+
+// ListBlobDeletions returns up to limit storage keys of blobs which are no longer referenced, the oldest first.
+func (db *Postgres) ListBlobDeletions(limit int) ([]string, error) {
+	keys := make([]string, 0)
+	err := db.db.Table(dmodels.BlobDeletionsTable).
+		Order("created_at, storage_key").
+		Limit(limit).
+		Pluck("storage_key", &keys).Error
+	return keys, err
+}
+
+// RemoveBlobDeletions forgets the blobs which were removed from the storage.
+func (db *Postgres) RemoveBlobDeletions(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return db.db.Exec(fmt.Sprintf("delete from %s where storage_key in ?", dmodels.BlobDeletionsTable), keys).Error
+}
+
+// companyAttachments selects the attachments of the live company of the organization along with their authors.
+func companyAttachments(tx *gorm.DB, companyID string, orgID uuid.UUID) *gorm.DB {
+	return companyChildren(tx, dmodels.CompanyAttachmentsTable, companyID, orgID).
+		Select("x.*, u.email as author_email").
+		Joins(fmt.Sprintf("left join %s u on u.id = x.author_id", dmodels.UsersTable))
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"errors"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+)
+
+const createBatchSize = 100
+
+var errBatchFailed = errors.New("batch failed")
+
+// ApplyCompanyBatch applies the operations within one transaction and returns an error per operation.
+// Deletes and updates go first, so that created companies may reuse names freed by them,
+// creates are written with batched inserts. Every operation runs in its own savepoint:
+// in atomic mode any failure rolls back the whole batch, otherwise only the failed operations.
+// Operations are updated in place with the stored versions, all of them belong to the organization.
+func (db *Postgres) ApplyCompanyBatch(ops []dmodels.CompanyBatchOperation, atomic bool, orgID uuid.UUID, authorID uuid.UUID) ([]error, error) {
+	errs := make([]error, len(ops))
+	failed := false
+
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		creates := make([]int, 0, len(ops))
+		for i := range ops {
+			op := &ops[i]
+			switch op.Op {
+			case dmodels.BatchOpCreate:
+				creates = append(creates, i)
+				continue
+			case dmodels.BatchOpUpdate:
+				errs[i] = tx.Transaction(func(tx *gorm.DB) error {
+					if err := updateCompany(tx, &op.Company); err != nil {
+						return err
+					}
+					return recordRevision(tx, op.Company.ID.String(), dmodels.RevisionActionUpdate, authorID, nil)
+				})
+			case dmodels.BatchOpDelete:
+				errs[i] = tx.Transaction(func(tx *gorm.DB) error {
+					var err error
+					op.Affected, err = deleteCompany(tx, op.Company.ID.String(), orgID, op.Company.Version, op.DeletePolicy, authorID)
+					return err
+				})
+			}
+			failed = failed || errs[i] != nil
+		}
+
+		if len(creates) > 0 {
+			companies := make([]dmodels.Company, 0, len(creates))
+			for _, i := range creates {
+				companies = append(companies, ops[i].Company)
+			}
+
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				return createCompanies(tx, companies, authorID)
+			})
+			if err == nil {
+				for j, i := range creates {
+					ops[i].Company = companies[j]
+				}
+			} else {
+				// the batch insert does not tell which row is wrong, so find it out one by one
+				for _, i := range creates {
+					op := &ops[i]
+					errs[i] = tx.Transaction(func(tx *gorm.DB) error {
+						single := []dmodels.Company{op.Company}
+						if err := createCompanies(tx, single, authorID); err != nil {
+							return err
+						}
+						op.Company = single[0]
+						return nil
+					})
+					failed = failed || errs[i] != nil
+				}
+			}
+		}
+
+		if atomic && failed {
+			return errBatchFailed
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchFailed) {
+		return errs, nil
+	}
+
+	return errs, err
+}
+
+func createCompanies(tx *gorm.DB, companies []dmodels.Company, authorID uuid.UUID) error {
+	if err := tx.Table(dmodels.CompaniesTable).CreateInBatches(&companies, createBatchSize).Error; err != nil {
+		return err
+	}
+
+	ids := make([]uuid.UUID, 0, len(companies))
+	for _, company := range companies {
+		ids = append(ids, company.ID)
+	}
+
+	// slugs are given by the database
+	var slugs []struct {
+		ID   uuid.UUID `gorm:"column:id"`
+		Slug string    `gorm:"column:slug"`
+	}
+	if err := tx.Table(dmodels.CompaniesTable).Select("id, slug").Where("id in ?", ids).Scan(&slugs).Error; err != nil {
+		return err
+	}
+	bySlug := make(map[uuid.UUID]string, len(slugs))
+	for _, row := range slugs {
+		bySlug[row.ID] = row.Slug
+	}
+	for i := range companies {
+		companies[i].Slug = bySlug[companies[i].ID]
+	}
+
+	return recordCreateRevisions(tx, ids, authorID)
+}
@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+)
+
+// Contacts and addresses are only reachable through live companies, they are hidden while the company
+// is in the trash and removed along with it by the foreign key once the company is purged.
+
+func (db *Postgres) ListCompanyContacts(companyID string, orgID uuid.UUID) ([]dmodels.CompanyContact, error) {
+	contacts := make([]dmodels.CompanyContact, 0)
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companyChildren(tx, dmodels.CompanyContactsTable, companyID, orgID).
+			Order("x.is_primary desc, x.name, x.id").
+			Find(&contacts).Error
+	})
+	return contacts, err
+}
+
+func (db *Postgres) GetCompanyContact(companyID, id string, orgID uuid.UUID) (dmodels.CompanyContact, error) {
+	var contact dmodels.CompanyContact
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companyChildren(tx, dmodels.CompanyContactsTable, companyID, orgID).
+			Where("x.id = ?", id).
+			Take(&contact).Error
+	})
+	return contact, err
+}
+
+// PrimaryCompanyContact returns the primary contact of the company, gorm.ErrRecordNotFound if there is none.
+func (db *Postgres) PrimaryCompanyContact(companyID string, orgID uuid.UUID) (dmodels.CompanyContact, error) {
+	var contact dmodels.CompanyContact
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companyChildren(tx, dmodels.CompanyContactsTable, companyID, orgID).
+			Where("x.is_primary").
+			Take(&contact).Error
+	})
+	return contact, err
+}
+
+// CreateCompanyContact adds the contact to a live company, a primary contact takes the flag over from the current one.
+func (db *Postgres) CreateCompanyContact(contact dmodels.CompanyContact, orgID uuid.UUID) (dmodels.CompanyContact, error) {
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := prepareCompanyChild(tx, dmodels.CompanyContactsTable, contact.CompanyID, orgID, contact.Primary); err != nil {
+			return err
+		}
+		return tx.Table(dmodels.CompanyContactsTable).Create(&contact).Error
+	})
+	return contact, err
+}
+
+func (db *Postgres) UpdateCompanyContact(contact dmodels.CompanyContact, orgID uuid.UUID) (dmodels.CompanyContact, error) {
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := prepareCompanyChild(tx, dmodels.CompanyContactsTable, contact.CompanyID, orgID, contact.Primary); err != nil {
+			return err
+		}
+		err := updateCompanyChild(tx, dmodels.CompanyContactsTable, contact.ID, contact.CompanyID, map[string]interface{}{
+			"name":       contact.Name,
+			"email":      contact.Email,
+			"phone":      contact.Phone,
+			"position":   contact.Position,
+			"is_primary": contact.Primary,
+			"updated_at": gorm.Expr("now()"),
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Table(dmodels.CompanyContactsTable).Where("id = ?", contact.ID).Take(&contact).Error
+	})
+	return contact, err
+}
+
+func (db *Postgres) DeleteCompanyContact(companyID, id string, orgID uuid.UUID) error {
+	return db.scoped(orgID, func(tx *gorm.DB) error {
+		return deleteCompanyChild(tx, dmodels.CompanyContactsTable, companyID, id, orgID, &dmodels.CompanyContact{})
+	})
+}
+
+func (db *Postgres) ListCompanyAddresses(companyID string, orgID uuid.UUID) ([]dmodels.CompanyAddress, error) {
+	addresses := make([]dmodels.CompanyAddress, 0)
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companyChildren(tx, dmodels.CompanyAddressesTable, companyID, orgID).
+			Order("x.is_primary desc, x.created_at, x.id").
+			Find(&addresses).Error
+	})
+	return addresses, err
+}
+
+func (db *Postgres) GetCompanyAddress(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAddress, error) {
+	var address dmodels.CompanyAddress
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companyChildren(tx, dmodels.CompanyAddressesTable, companyID, orgID).
+			Where("x.id = ?", id).
+			Take(&address).Error
+	})
+	return address, err
+}
+
+// PrimaryCompanyAddress returns the primary address of the company, gorm.ErrRecordNotFound if there is none.
+func (db *Postgres) PrimaryCompanyAddress(companyID string, orgID uuid.UUID) (dmodels.CompanyAddress, error) {
+	var address dmodels.CompanyAddress
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companyChildren(tx, dmodels.CompanyAddressesTable, companyID, orgID).
+			Where("x.is_primary").
+			Take(&address).Error
+	})
+	return address, err
+}
+
+// CreateCompanyAddress adds the address to a live company, a primary address takes the flag over from the current one.
+func (db *Postgres) CreateCompanyAddress(address dmodels.CompanyAddress, orgID uuid.UUID) (dmodels.CompanyAddress, error) {
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := prepareCompanyChild(tx, dmodels.CompanyAddressesTable, address.CompanyID, orgID, address.Primary); err != nil {
+			return err
+		}
+		return tx.Table(dmodels.CompanyAddressesTable).Create(&address).Error
+	})
+	return address, err
+}
+
+func (db *Postgres) UpdateCompanyAddress(address dmodels.CompanyAddress, orgID uuid.UUID) (dmodels.CompanyAddress, error) {
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := prepareCompanyChild(tx, dmodels.CompanyAddressesTable, address.CompanyID, orgID, address.Primary); err != nil {
+			return err
+		}
+		err := updateCompanyChild(tx, dmodels.CompanyAddressesTable, address.ID, address.CompanyID, map[string]interface{}{
+			"line1":       address.Line1,
+			"line2":       address.Line2,
+			"city":        address.City,
+			"region":      address.Region,
+			"postal_code": address.PostalCode,
+			"country":     address.Country,
+			"is_primary":  address.Primary,
+			"updated_at":  gorm.Expr("now()"),
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Table(dmodels.CompanyAddressesTable).Where("id = ?", address.ID).Take(&address).Error
+	})
+	return address, err
+}
+
+func (db *Postgres) DeleteCompanyAddress(companyID, id string, orgID uuid.UUID) error {
+	return db.scoped(orgID, func(tx *gorm.DB) error {
+		return deleteCompanyChild(tx, dmodels.CompanyAddressesTable, companyID, id, orgID, &dmodels.CompanyAddress{})
+	})
+}
+
+// companyChildren selects the rows of the table which belong to the live company of the organization.
+func companyChildren(tx *gorm.DB, table, companyID string, orgID uuid.UUID) *gorm.DB {
+	return tx.Table(fmt.Sprintf("%s x", table)).
+		Select("x.*").
+		Joins("inner join companies c on c.id = x.company_id").
+		Where("x.company_id = ? and c.organization_id = ? and c.deleted_at is null", companyID, orgID)
+}
+
+// prepareCompanyChild locks the live company for the write, so that concurrent writes cannot flag
+// two primary rows, and drops the primary flag of its other rows if the written one is primary.
+func prepareCompanyChild(tx *gorm.DB, table string, companyID uuid.UUID, orgID uuid.UUID, primary bool) error {
+	if err := lockCompany(tx, companyID, orgID); err != nil {
+		return err
+	}
+	if !primary {
+		return nil
+	}
+
+	return tx.Table(table).
+		Where("company_id = ? and is_primary", companyID).
+		Updates(map[string]interface{}{"is_primary": false, "updated_at": gorm.Expr("now()")}).Error
+}
+
+// lockCompany locks the live company of the organization until the end of the transaction.
+func lockCompany(tx *gorm.DB, companyID uuid.UUID, orgID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.Raw("select id from companies where id = ? and organization_id = ? and deleted_at is null for update",
+		companyID, orgID).Scan(&id).Error
+	if err != nil {
+		return err
+	}
+	if id == uuid.Nil {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func updateCompanyChild(tx *gorm.DB, table string, id, companyID uuid.UUID, values map[string]interface{}) error {
+	result := tx.Table(table).
+		Where("id = ? and company_id = ?", id, companyID).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func deleteCompanyChild(tx *gorm.DB, table, companyID, id string, orgID uuid.UUID, model interface{}) error {
+	result := tx.Table(table).
+		Where("id = ? and company_id = ?", id, companyID).
+		Where("exists(select 1 from companies c where c.id = company_id and c.organization_id = ? and c.deleted_at is null)", orgID).
+		Delete(model)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
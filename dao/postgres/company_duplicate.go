@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"strconv"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+)
+
+// SimilarCompanies returns the live companies of the organization whose names resemble the name
+// at least as much as the threshold, the most similar first.
+func (db *Postgres) SimilarCompanies(name string, orgID uuid.UUID, threshold float64, limit int) ([]dmodels.SimilarCompany, error) {
+	companies := make([]dmodels.SimilarCompany, 0)
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := similarityThreshold(tx, threshold); err != nil {
+			return err
+		}
+		return tx.Raw(`select c.id, c.name, c.slug, similarity(lower(c.name), lower(@name)) as similarity
+			from companies c
+			where c.organization_id = @org and c.deleted_at is null and lower(c.name) % lower(@name)
+			order by similarity desc, c.name
+			limit @limit`,
+			map[string]interface{}{"name": name, "org": orgID, "limit": limit}).
+			Scan(&companies).Error
+	})
+	return companies, err
+}
+
+// CompanyDuplicatePairs returns the pairs of live companies of the organization with similar names,
+// the most similar first. The limit caps the number of pairs.
+func (db *Postgres) CompanyDuplicatePairs(orgID uuid.UUID, threshold float64, limit int) ([]dmodels.CompanyDuplicatePair, error) {
+	pairs := make([]dmodels.CompanyDuplicatePair, 0)
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := similarityThreshold(tx, threshold); err != nil {
+			return err
+		}
+		return tx.Raw(`select a.id as first_id, a.name as first_name, a.slug as first_slug,
+				b.id as second_id, b.name as second_name, b.slug as second_slug,
+				similarity(lower(a.name), lower(b.name)) as similarity
+			from companies a
+			join companies b on b.organization_id = a.organization_id and b.deleted_at is null
+				and lower(b.name) % lower(a.name)
+				and (b.created_at, b.id) > (a.created_at, a.id)
+			where a.organization_id = @org and a.deleted_at is null
+			order by similarity desc, a.created_at, a.id, b.created_at, b.id
+			limit @limit`,
+			map[string]interface{}{"org": orgID, "limit": limit}).
+			Scan(&pairs).Error
+	})
+	return pairs, err
+}
+
+// similarityThreshold sets the threshold of the % operator until the transaction ends.
+func similarityThreshold(tx *gorm.DB, threshold float64) error {
+	return tx.Exec("select set_config('pg_trgm.similarity_threshold', ?, true)",
+		strconv.FormatFloat(threshold, 'f', -1, 64)).Error
+}
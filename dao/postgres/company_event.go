@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+)
+
+// companyEventsChannel is notified with the id of every added company event.
+const companyEventsChannel = "company_events"
+
+const companyEventsQuery = `select e.*, coalesce(t.name, '') as type
+	from company_events e
+	left join company_types t on t.id = e.type_id`
+
+// AddCompanyEvent records the event along with the current type of the company and notifies the listeners of every
+// instance once the transaction commits. It is meant for Transaction, so that the event is recorded along with the
+// change. Events are ordered by the id sequence, concurrent transactions may commit them out of that order.
+func (db *Postgres) AddCompanyEvent(event dmodels.CompanyEvent) error {
+	return db.scoped(event.OrganizationID, func(tx *gorm.DB) error {
+		return tx.Exec(`with event as (
+				insert into company_events (organization_id, company_id, type_id, event, version)
+				values (@org, @company, (select type_id from companies where id = @company), @event, @version)
+				returning id)
+			select pg_notify(@channel, id::text) from event`,
+			map[string]interface{}{
+				"org":     event.OrganizationID,
+				"company": event.CompanyID,
+				"event":   event.Event,
+				"version": event.Version,
+				"channel": companyEventsChannel,
+			}).Error
+	})
+}
+
+func (db *Postgres) GetCompanyEvent(id int64) (dmodels.CompanyEvent, error) {
+	var event dmodels.CompanyEvent
+	err := db.db.Raw(companyEventsQuery+" where e.id = ?", id).Take(&event).Error
+	return event, err
+}
+
+// ListCompanyEvents returns up to limit events of the organization following the event afterID, the oldest first.
+func (db *Postgres) ListCompanyEvents(afterID int64, limit int, orgID uuid.UUID) ([]dmodels.CompanyEvent, error) {
+	events := make([]dmodels.CompanyEvent, 0)
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return tx.Raw(companyEventsQuery+" where e.organization_id = ? and e.id > ? order by e.id limit ?",
+			orgID, afterID, limit).Scan(&events).Error
+	})
+	return events, err
+}
+
+// FirstCompanyEventID returns the id of the oldest kept event, zero if there are none.
+func (db *Postgres) FirstCompanyEventID() (int64, error) {
+	var id int64
+	err := db.db.Raw("select coalesce(min(id), 0) from company_events").Scan(&id).Error
+	return id, err
+}
+
+// TrimCompanyEvents removes all but the latest keep events.
+func (db *Postgres) TrimCompanyEvents(keep int) (int64, error) {
+	result := db.db.Exec(`delete from company_events
+		where id <= (select id from company_events order by id desc offset ? limit 1)`, keep)
+	return result.RowsAffected, result.Error
+}
+
+// ListenCompanyEvents passes the ids of the added events to notify until the context is done, the connection
+// is lost or notify fails. typesChanged is called whenever company types are changed. It holds a connection of its
+// own, listening is called once the notifications are received.
+func (db *Postgres) ListenCompanyEvents(ctx context.Context, listening func(), notify func(id int64) error,
+	typesChanged func()) error {
+	conn, err := pgx.Connect(ctx, dsn(db.cfg))
+	if err != nil {
+		return fmt.Errorf("pgx.Connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	for _, channel := range []string{companyEventsChannel, companyTypesChannel} {
+		if _, err := conn.Exec(ctx, "listen "+channel); err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+	}
+	listening()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("conn.WaitForNotification: %w", err)
+		}
+		if notification.Channel == companyTypesChannel {
+			typesChanged()
+			continue
+		}
+		id, err := strconv.ParseInt(notification.Payload, 10, 64)
+		if err != nil {
+			continue
+		}
+		if err := notify(id); err != nil {
+			return err
+		}
+	}
+}
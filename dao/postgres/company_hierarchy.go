@@ -0,0 +1,291 @@
+package postgres
+
+import (
+	"fmt"
+	"math"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+// unlimitedDepth lets tree queries walk the whole tree, the path checks keep them finite anyway.
+const unlimitedDepth = math.MaxInt32
+
+// subtreeCTE walks down from @id through the live subsidiaries, at most @depth levels deep.
+const subtreeCTE = `with recursive subtree as (
+		select c.id, 0 as depth, array[c.id] as path
+		from companies c
+		where c.id = @id and c.organization_id = @org
+	union all
+		select c.id, s.depth + 1, s.path || c.id
+		from subtree s
+		join companies c on c.parent_id = s.id
+		where c.organization_id = @org and c.deleted_at is null
+		  and s.depth < @depth and not c.id = any(s.path)
+)`
+
+// ancestorsCTE walks up from @id through the live parents, at most @depth levels high.
+const ancestorsCTE = `with recursive ancestors as (
+		select c.parent_id as id, 1 as depth, array[c.id, c.parent_id] as path
+		from companies c
+		where c.id = @id and c.organization_id = @org and c.parent_id is not null
+	union all
+		select c.parent_id, a.depth + 1, a.path || c.parent_id
+		from ancestors a
+		join companies c on c.id = a.id
+		where c.organization_id = @org and c.deleted_at is null and c.parent_id is not null
+		  and a.depth < @depth and not c.parent_id = any(a.path)
+)`
+
+// CompanyAncestors returns the live parents of the company, the nearest one first.
+func (db *Postgres) CompanyAncestors(id string, orgID uuid.UUID, depth int) ([]dmodels.CompanyNode, error) {
+	nodes := make([]dmodels.CompanyNode, 0)
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return tx.Raw(ancestorsCTE+`
+			select `+companyShowFields+`, a.depth
+			from ancestors a
+			join companies c on c.id = a.id
+			join company_types ct on ct.id = c.type_id
+			where c.organization_id = @org and c.deleted_at is null
+			order by a.depth`, treeArgs(id, orgID, depth)).
+			Scan(&nodes).Error
+	})
+	return nodes, err
+}
+
+// CompanySubtree returns the company and its live subsidiaries down to the given depth in depth first order.
+// A non-positive depth walks the whole tree, a positive limit caps the number of returned companies.
+func (db *Postgres) CompanySubtree(id string, orgID uuid.UUID, depth int, limit int) ([]dmodels.CompanyNode, error) {
+	if depth <= 0 {
+		depth = unlimitedDepth
+	}
+	query := subtreeCTE + `
+		select ` + companyShowFields + `, s.depth
+		from subtree s
+		join companies c on c.id = s.id
+		join company_types ct on ct.id = c.type_id
+		order by s.path`
+	args := treeArgs(id, orgID, depth)
+	if limit > 0 {
+		query += " limit @limit"
+		args["limit"] = limit
+	}
+
+	nodes := make([]dmodels.CompanyNode, 0)
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return tx.Raw(query, args).Scan(&nodes).Error
+	})
+	return nodes, err
+}
+
+// CompanyGroup sums up the company and all of its live subsidiaries.
+func (db *Postgres) CompanyGroup(id string, orgID uuid.UUID) (dmodels.CompanyGroup, error) {
+	var group dmodels.CompanyGroup
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return tx.Raw(subtreeCTE+`
+			select count(*) as companies,
+				coalesce(sum(c.employees), 0)::bigint as employees,
+				coalesce(max(s.depth), 0) as depth
+			from subtree s
+			join companies c on c.id = s.id`, treeArgs(id, orgID, unlimitedDepth)).
+			Scan(&group).Error
+	})
+	return group, err
+}
+
+// SetCompanyParent moves the company under another live company of the organization, a nil parent
+// makes it a top level company. A non-zero version has to match the current one.
+func (db *Postgres) SetCompanyParent(id string, orgID uuid.UUID, parentID *uuid.UUID, version uint64, authorID uuid.UUID) error {
+	return db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := lockHierarchy(tx, orgID); err != nil {
+			return err
+		}
+
+		var parent interface{}
+		if parentID != nil {
+			if err := checkParent(tx, id, orgID, *parentID); err != nil {
+				return err
+			}
+			parent = *parentID
+		}
+
+		q := tx.Table(dmodels.CompaniesTable).
+			Where("id = ? and organization_id = ? and deleted_at is null", id, orgID)
+		if version != 0 {
+			q = q.Where("version = ?", version)
+		}
+
+		result := q.Updates(map[string]interface{}{
+			"parent_id":  parent,
+			"updated_at": gorm.Expr("now()"),
+			"version":    gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return versionMismatch(tx, id, orgID)
+		}
+		return recordRevision(tx, id, dmodels.RevisionActionReparent, authorID, nil)
+	})
+}
+
+// LockCompanyHierarchy takes the lock of lockHierarchy, it is meant for Transaction, where the lock
+// is held until the end of the transaction.
+func (db *Postgres) LockCompanyHierarchy(orgID uuid.UUID) error {
+	return db.scoped(orgID, func(tx *gorm.DB) error {
+		return lockHierarchy(tx, orgID)
+	})
+}
+
+// lockHierarchy serializes changes of the company tree of the organization until the transaction ends,
+// so that concurrent moves cannot close a cycle or leave subsidiaries under a deleted company.
+func lockHierarchy(tx *gorm.DB, orgID uuid.UUID) error {
+	err := tx.Exec("select pg_advisory_xact_lock(hashtext(?))", "company_hierarchy:"+orgID.String()).Error
+	if err != nil {
+		return fmt.Errorf("lock hierarchy: %w", err)
+	}
+	return nil
+}
+
+// checkParent makes sure the parent is a live company of the organization which is neither the company
+// itself nor one of its subsidiaries. Trashed companies are walked through as well, they may be restored.
+func checkParent(tx *gorm.DB, id string, orgID uuid.UUID, parentID uuid.UUID) error {
+	var live int64
+	err := tx.Table(dmodels.CompaniesTable).
+		Where("id = ? and organization_id = ? and deleted_at is null", parentID, orgID).
+		Count(&live).Error
+	if err != nil {
+		return err
+	}
+	if live == 0 {
+		return fmt.Errorf("%w: parent company not found", local.ErrUnprocessable)
+	}
+
+	var cycle bool
+	err = tx.Raw(`with recursive lineage as (
+			select c.id, c.parent_id, array[c.id] as path
+			from companies c
+			where c.id = @parent and c.organization_id = @org
+		union all
+			select c.id, c.parent_id, l.path || c.id
+			from lineage l
+			join companies c on c.id = l.parent_id
+			where c.organization_id = @org and not c.id = any(l.path)
+		)
+		select exists(select 1 from lineage where id = @id)`,
+		map[string]interface{}{"id": id, "org": orgID, "parent": parentID}).
+		Scan(&cycle).Error
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return fmt.Errorf("%w: company cannot be a subsidiary of itself", local.ErrUnprocessable)
+	}
+	return nil
+}
+
+// deleteSubsidiaries applies the delete policy to the subsidiaries of the just deleted company
+// and returns the changed ones.
+func deleteSubsidiaries(tx *gorm.DB, id string, orgID uuid.UUID, policy string, authorID uuid.UUID) ([]dmodels.CompanyShow, error) {
+	switch policy {
+	case dmodels.DeletePolicyCascade:
+		ids := make([]uuid.UUID, 0)
+		err := tx.Raw(subtreeCTE+" select id from subtree where depth > 0", treeArgs(id, orgID, unlimitedDepth)).
+			Scan(&ids).Error
+		if err != nil {
+			return nil, err
+		}
+		return changeSubsidiaries(tx, ids, orgID, dmodels.RevisionActionDelete, authorID, map[string]interface{}{
+			"deleted_at": gorm.Expr("now()"),
+		})
+	case dmodels.DeletePolicyReparent:
+		ids, err := children(tx, id, orgID)
+		if err != nil {
+			return nil, err
+		}
+		return changeSubsidiaries(tx, ids, orgID, dmodels.RevisionActionReparent, authorID, map[string]interface{}{
+			"parent_id":  gorm.Expr("(select p.parent_id from companies p where p.id = ?)", id),
+			"updated_at": gorm.Expr("now()"),
+		})
+	}
+
+	ids, err := children(tx, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		return nil, local.ErrHasSubsidiaries
+	}
+	return nil, nil
+}
+
+// restoreSubsidiaries restores the subsidiaries trashed along with the company. A cascade delete trashes
+// the whole subtree in one transaction, so they are the ones trashed at the time of the transaction of the company.
+func restoreSubsidiaries(tx *gorm.DB, id string, orgID uuid.UUID, authorID uuid.UUID) ([]dmodels.CompanyShow, error) {
+	ids := make([]uuid.UUID, 0)
+	err := tx.Raw(`with recursive trashed as (
+			select deleted_at from companies where id = @id and organization_id = @org and deleted_at is not null
+		), cascaded as (
+			select c.id, array[c.id] as path
+			from companies c, trashed t
+			where c.parent_id = @id and c.organization_id = @org and c.deleted_at = t.deleted_at
+		union all
+			select c.id, s.path || c.id
+			from cascaded s
+			join companies c on c.parent_id = s.id
+			cross join trashed t
+			where c.organization_id = @org and c.deleted_at = t.deleted_at and not c.id = any(s.path)
+		)
+		select id from cascaded`, treeArgs(id, orgID, unlimitedDepth)).
+		Scan(&ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return changeSubsidiaries(tx, ids, orgID, dmodels.RevisionActionRestore, authorID, map[string]interface{}{
+		"deleted_at": nil,
+		"updated_at": gorm.Expr("now()"),
+	})
+}
+
+func children(tx *gorm.DB, id string, orgID uuid.UUID) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0)
+	err := tx.Table(dmodels.CompaniesTable).
+		Where("parent_id = ? and organization_id = ? and deleted_at is null", id, orgID).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func changeSubsidiaries(tx *gorm.DB, ids []uuid.UUID, orgID uuid.UUID, action string, authorID uuid.UUID,
+	values map[string]interface{}) ([]dmodels.CompanyShow, error) {
+	affected := make([]dmodels.CompanyShow, 0, len(ids))
+	if len(ids) == 0 {
+		return affected, nil
+	}
+
+	values["version"] = gorm.Expr("version + 1")
+	err := tx.Table(dmodels.CompaniesTable).
+		Where("id in ? and organization_id = ?", ids, orgID).
+		Updates(values).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if err := recordRevision(tx, id.String(), action, authorID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	err = scopedCompanies(tx, orgID).
+		Select(companyShowFields).
+		Where("c.id in ?", ids).
+		Scan(&affected).Error
+	return affected, err
+}
+
+func treeArgs(id string, orgID uuid.UUID, depth int) map[string]interface{} {
+	return map[string]interface{}{"id": id, "org": orgID, "depth": depth}
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"xm-task/dmodels"
+)
+
+func (db *Postgres) GetCompanyLogo(companyID string, orgID uuid.UUID) (dmodels.CompanyLogo, error) {
+	var logo dmodels.CompanyLogo
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companyChildren(tx, dmodels.CompanyLogosTable, companyID, orgID).
+			Take(&logo).Error
+	})
+	return logo, err
+}
+
+// FindCompanyLogo returns the logo of a live company of any organization, it serves the public logo URLs.
+func (db *Postgres) FindCompanyLogo(companyID string) (dmodels.CompanyLogo, error) {
+	var logo dmodels.CompanyLogo
+	err := db.db.Table(fmt.Sprintf("%s l", dmodels.CompanyLogosTable)).
+		Select("l.*").
+		Joins(fmt.Sprintf("inner join %s c on c.id = l.company_id", dmodels.CompaniesTable)).
+		Where("l.company_id = ? and c.deleted_at is null", companyID).
+		Take(&logo).Error
+	return logo, err
+}
+
+// SetCompanyLogo stores the logo of a live company in place of the current one. The version of the company is bumped,
+// since its representation changes, the blobs of the previous logo are queued for removal by a trigger.
+func (db *Postgres) SetCompanyLogo(logo dmodels.CompanyLogo, orgID uuid.UUID) (dmodels.CompanyLogo, error) {
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := lockCompany(tx, logo.CompanyID, orgID); err != nil {
+			return err
+		}
+
+		err := tx.Table(dmodels.CompanyLogosTable).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "company_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"hash", "content_type", "width", "height", "size", "sizes", "storage_keys", "author_id", "created_at"}),
+			}).
+			Create(&logo).Error
+		if err != nil {
+			return err
+		}
+		return bumpCompanyVersion(tx, logo.CompanyID)
+	})
+	return logo, err
+}
+
+// DeleteCompanyLogo removes the logo and bumps the version of the company like SetCompanyLogo.
+func (db *Postgres) DeleteCompanyLogo(companyID string, orgID uuid.UUID) error {
+	return db.scoped(orgID, func(tx *gorm.DB) error {
+		id, err := uuid.FromString(companyID)
+		if err != nil {
+			return gorm.ErrRecordNotFound
+		}
+		if err := lockCompany(tx, id, orgID); err != nil {
+			return err
+		}
+
+		result := tx.Table(dmodels.CompanyLogosTable).
+			Where("company_id = ?", id).
+			Delete(&dmodels.CompanyLogo{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return bumpCompanyVersion(tx, id)
+	})
+}
+
+func bumpCompanyVersion(tx *gorm.DB, id uuid.UUID) error {
+	return tx.Table(dmodels.CompaniesTable).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"updated_at": gorm.Expr("now()"),
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+}
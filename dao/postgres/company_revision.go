@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+)
+
+// companySnapshot has the fields of dmodels.CompanySnapshot without its
+// sql.Scanner implementation, so that gorm scans it column by column.
+type companySnapshot dmodels.CompanySnapshot
+
+const snapshotFields = "c.name, c.description, c.employees, c.registered, c.type_id, ct.name as type, c.updated_at, c.deleted_at, c.version, c.owner_id, c.parent_id, c.attributes"
+
+func (db *Postgres) ListCompanyRevisions(companyID string, orgID uuid.UUID) ([]dmodels.CompanyRevision, error) {
+	revisions := make([]dmodels.CompanyRevision, 0)
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companyRevisions(tx, orgID).
+			Where("r.company_id = ?", companyID).
+			Order("r.revision").
+			Find(&revisions).Error
+	})
+	return revisions, err
+}
+
+func (db *Postgres) GetCompanyRevision(companyID string, orgID uuid.UUID, revision uint64) (dmodels.CompanyRevision, error) {
+	var rev dmodels.CompanyRevision
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companyRevisions(tx, orgID).
+			Where("r.company_id = ? and r.revision = ?", companyID, revision).
+			Take(&rev).Error
+	})
+	return rev, err
+}
+
+// companyRevisions selects revisions of the companies of the organization along with their authors.
+func companyRevisions(tx *gorm.DB, orgID uuid.UUID) *gorm.DB {
+	return tx.Table(fmt.Sprintf("%s r", dmodels.CompanyRevisionsTable)).
+		Select("r.*, u.email as author_email").
+		Joins(fmt.Sprintf("inner join %s c on c.id = r.company_id and c.organization_id = ?", dmodels.CompaniesTable), orgID).
+		Joins(fmt.Sprintf("left join %s u on u.id = r.author_id", dmodels.UsersTable))
+}
+
+// recordRevision stores the current state of the company as its next revision.
+// It has to be called within the transaction which changed the company.
+func recordRevision(tx *gorm.DB, companyID string, action string, authorID uuid.UUID, revertedFrom *uint64) error {
+	var snapshot companySnapshot
+	err := snapshots(tx).
+		Where("c.id = ?", companyID).
+		Take(&snapshot).Error
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	var next uint64
+	err = tx.Table(dmodels.CompanyRevisionsTable).
+		Select("coalesce(max(revision), 0) + 1").
+		Where("company_id = ?", companyID).
+		Scan(&next).Error
+	if err != nil {
+		return fmt.Errorf("next revision: %w", err)
+	}
+
+	id, err := uuid.FromString(companyID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Table(dmodels.CompanyRevisionsTable).Create(&dmodels.CompanyRevision{
+		CompanyID:    id,
+		Revision:     next,
+		Action:       action,
+		AuthorID:     uuid.NullUUID{UUID: authorID, Valid: authorID != uuid.Nil},
+		RevertedFrom: revertedFrom,
+		Snapshot:     dmodels.CompanySnapshot(snapshot),
+	}).Error
+}
+
+// recordCreateRevisions stores the first revision of just inserted companies with a single batch insert.
+func recordCreateRevisions(tx *gorm.DB, ids []uuid.UUID, authorID uuid.UUID) error {
+	var rows []struct {
+		ID       uuid.UUID       `gorm:"column:id"`
+		Snapshot companySnapshot `gorm:"embedded"`
+	}
+	err := snapshots(tx).
+		Select("c.id, "+snapshotFields).
+		Where("c.id in ?", ids).
+		Find(&rows).Error
+	if err != nil {
+		return fmt.Errorf("snapshots: %w", err)
+	}
+
+	revisions := make([]dmodels.CompanyRevision, 0, len(rows))
+	for _, row := range rows {
+		revisions = append(revisions, dmodels.CompanyRevision{
+			CompanyID: row.ID,
+			Revision:  1,
+			Action:    dmodels.RevisionActionCreate,
+			AuthorID:  uuid.NullUUID{UUID: authorID, Valid: authorID != uuid.Nil},
+			Snapshot:  dmodels.CompanySnapshot(row.Snapshot),
+		})
+	}
+
+	return tx.Table(dmodels.CompanyRevisionsTable).CreateInBatches(&revisions, createBatchSize).Error
+}
+
+func snapshots(tx *gorm.DB) *gorm.DB {
+	return tx.Table(fmt.Sprintf("%s c", dmodels.CompaniesTable)).
+		Select(snapshotFields).
+		Joins("inner join company_types ct on ct.id = c.type_id")
+}
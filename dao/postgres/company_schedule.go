@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+// ListCompanySchedules returns the scheduled changes of the live company, the earliest first.
+// An empty status lists changes in any status.
+func (db *Postgres) ListCompanySchedules(companyID string, status string, orgID uuid.UUID) ([]dmodels.CompanySchedule, error) {
+	schedules := make([]dmodels.CompanySchedule, 0)
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		q := companySchedules(tx, orgID).Where("s.company_id = ?", companyID)
+		if status != "" {
+			q = q.Where("s.status = ?", status)
+		}
+		return q.Order("s.effective_at, s.created_at, s.id").Find(&schedules).Error
+	})
+	return schedules, err
+}
+
+func (db *Postgres) GetCompanySchedule(companyID, id string, orgID uuid.UUID) (dmodels.CompanySchedule, error) {
+	var schedule dmodels.CompanySchedule
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return companySchedules(tx, orgID).
+			Where("s.company_id = ? and s.id = ?", companyID, id).
+			Take(&schedule).Error
+	})
+	return schedule, err
+}
+
+// CreateCompanySchedule stores the change of a live company unless pending changes of the company touch the same
+// fields, those are returned instead. A field conflicts with its nested fields.
+func (db *Postgres) CreateCompanySchedule(schedule dmodels.CompanySchedule) (dmodels.CompanySchedule, []dmodels.CompanySchedule, error) {
+	var conflicts []dmodels.CompanySchedule
+	err := db.scoped(schedule.OrganizationID, func(tx *gorm.DB) error {
+		// the lock serializes schedules of the company, so that conflicting ones cannot be stored concurrently
+		if err := lockCompany(tx, schedule.CompanyID, schedule.OrganizationID); err != nil {
+			return err
+		}
+
+		err := companySchedules(tx, schedule.OrganizationID).
+			Where("s.company_id = ? and s.status = ?", schedule.CompanyID, dmodels.ScheduleStatusPending).
+			Where(`exists(select 1 from unnest(s.fields) f, unnest(?::text[]) g
+				where f = g or left(f, length(g) + 1) = g || '.' or left(g, length(f) + 1) = f || '.')`, schedule.Fields).
+			Order("s.created_at, s.id").
+			Find(&conflicts).Error
+		if err != nil || len(conflicts) > 0 {
+			return err
+		}
+
+		return tx.Table(dmodels.CompanySchedulesTable).Create(&schedule).Error
+	})
+	return schedule, conflicts, err
+}
+
+// CancelCompanySchedule cancels a pending change which is not being applied, local.ErrConflict is returned otherwise.
+func (db *Postgres) CancelCompanySchedule(companyID, id string, orgID uuid.UUID) (dmodels.CompanySchedule, error) {
+	var schedule dmodels.CompanySchedule
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		if err := companySchedules(tx, orgID).
+			Where("s.company_id = ? and s.id = ?", companyID, id).
+			Take(&schedule).Error; err != nil {
+			return err
+		}
+
+		result := tx.Table(dmodels.CompanySchedulesTable).
+			Where("id = ? and status = ? and claimed_at is null", id, dmodels.ScheduleStatusPending).
+			Updates(map[string]interface{}{
+				"status":      dmodels.ScheduleStatusCancelled,
+				"finished_at": gorm.Expr("now()"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return local.ErrConflict
+		}
+
+		return companySchedules(tx, orgID).Where("s.id = ?", id).Take(&schedule).Error
+	})
+	return schedule, err
+}
+
+// ClaimDueCompanySchedules marks up to limit pending changes of all organizations which took effect as being applied,
+// the earliest first, along with the emails of their authors. Changes claimed longer than the claim timeout ago are
+// claimed again, their worker is assumed gone.
+func (db *Postgres) ClaimDueCompanySchedules(limit int, claimTimeout time.Duration) ([]dmodels.CompanySchedule, error) {
+	schedules := make([]dmodels.CompanySchedule, 0)
+	err := db.db.Raw(`with claimed as (
+			update company_schedules set claimed_at = now()
+			where id in (
+				select id from company_schedules
+				where status = @pending and effective_at <= now()
+				  and (claimed_at is null or claimed_at < now() - make_interval(secs => @timeout))
+				order by effective_at, created_at
+				limit @limit
+				for update skip locked)
+			returning *)
+		select s.*, u.email as author_email from claimed s
+		left join users u on u.id = s.author_id`,
+		map[string]interface{}{
+			"pending": dmodels.ScheduleStatusPending,
+			"timeout": claimTimeout.Seconds(),
+			"limit":   limit,
+		}).Scan(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(schedules, func(i, j int) bool {
+		if !schedules[i].EffectiveAt.Equal(schedules[j].EffectiveAt) {
+			return schedules[i].EffectiveAt.Before(schedules[j].EffectiveAt)
+		}
+		return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+	})
+	return schedules, nil
+}
+
+// FinishCompanySchedule records the outcome of a claimed change.
+func (db *Postgres) FinishCompanySchedule(id uuid.UUID, status string, reason string) error {
+	return db.db.Table(dmodels.CompanySchedulesTable).
+		Where("id = ? and status = ?", id, dmodels.ScheduleStatusPending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"error":       reason,
+			"claimed_at":  nil,
+			"finished_at": gorm.Expr("now()"),
+		}).Error
+}
+
+// companySchedules selects scheduled changes of the live companies of the organization along with their authors.
+func companySchedules(tx *gorm.DB, orgID uuid.UUID) *gorm.DB {
+	return tx.Table(fmt.Sprintf("%s s", dmodels.CompanySchedulesTable)).
+		Select("s.*, u.email as author_email").
+		Joins(fmt.Sprintf("inner join %s c on c.id = s.company_id and c.organization_id = ? and c.deleted_at is null",
+			dmodels.CompaniesTable), orgID).
+		Joins(fmt.Sprintf("left join %s u on u.id = s.author_id", dmodels.UsersTable))
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+)
+
+// CompanyStats counts the live companies matched by the filter by type, registration and size,
+// and the matched companies created and deleted within the windows of the query.
+func (db *Postgres) CompanyStats(query dmodels.CompanyStatsQuery) (dmodels.CompanyStats, error) {
+	stats := dmodels.CompanyStats{
+		ByType:      make([]dmodels.CompanyTypeCount, 0),
+		ByEmployees: make([]dmodels.CompanyBucketCount, 0, len(dmodels.EmployeeBuckets)),
+		Windows:     make([]dmodels.CompanyWindowCount, 0, len(query.Windows)),
+	}
+
+	filter := query.Filter
+	filter.Trashed = false
+	err := db.scoped(filter.OrganizationID, func(tx *gorm.DB) error {
+		var registered []struct {
+			Registered bool  `gorm:"column:registered"`
+			Count      int64 `gorm:"column:count"`
+		}
+		err := filterCompanies(tx, filter).
+			Select("c.registered, count(*) as count").
+			Group("c.registered").
+			Scan(&registered).Error
+		if err != nil {
+			return fmt.Errorf("by registered: %w", err)
+		}
+		for _, row := range registered {
+			if row.Registered {
+				stats.Registered = row.Count
+			} else {
+				stats.Unregistered = row.Count
+			}
+			stats.Total += row.Count
+		}
+
+		err = filterCompanies(tx, filter).
+			Select("ct.name as type, count(*) as count").
+			Group("ct.name").
+			Order("count desc, ct.name").
+			Scan(&stats.ByType).Error
+		if err != nil {
+			return fmt.Errorf("by type: %w", err)
+		}
+
+		buckets := make([]dmodels.CompanyBucketCount, 0, len(dmodels.EmployeeBuckets))
+		err = filterCompanies(tx, filter).
+			Select(employeeBucketCase() + " as bucket, count(*) as count").
+			Group("bucket").
+			Scan(&buckets).Error
+		if err != nil {
+			return fmt.Errorf("by employees: %w", err)
+		}
+		counts := make(map[string]int64, len(buckets))
+		for _, bucket := range buckets {
+			counts[bucket.Bucket] = bucket.Count
+		}
+		for _, bucket := range dmodels.EmployeeBuckets {
+			stats.ByEmployees = append(stats.ByEmployees, dmodels.CompanyBucketCount{
+				Bucket: bucket.Name,
+				Count:  counts[bucket.Name],
+			})
+		}
+
+		if len(query.Windows) == 0 {
+			return nil
+		}
+		return windowCounts(tx, query, &stats)
+	})
+	return stats, err
+}
+
+// windowCounts counts the created and deleted companies of every window in a single pass.
+func windowCounts(tx *gorm.DB, query dmodels.CompanyStatsQuery, stats *dmodels.CompanyStats) error {
+	fields := make([]string, 0, 2*len(query.Windows))
+	args := make([]interface{}, 0, 2*len(query.Windows))
+	for i, since := range query.Windows {
+		fields = append(fields,
+			fmt.Sprintf("count(*) filter (where c.created_at >= ?) as created_%d", i),
+			fmt.Sprintf("count(*) filter (where c.deleted_at >= ?) as deleted_%d", i))
+		args = append(args, since, since)
+	}
+
+	row := make(map[string]interface{})
+	err := matchCompanies(scopedCompanies(tx, query.Filter.OrganizationID), query.Filter).
+		Select(strings.Join(fields, ", "), args...).
+		Take(&row).Error
+	if err != nil {
+		return fmt.Errorf("windows: %w", err)
+	}
+
+	for i, since := range query.Windows {
+		created, _ := row[fmt.Sprintf("created_%d", i)].(int64)
+		deleted, _ := row[fmt.Sprintf("deleted_%d", i)].(int64)
+		stats.Windows = append(stats.Windows, dmodels.CompanyWindowCount{
+			Since:   since,
+			Created: created,
+			Deleted: deleted,
+		})
+	}
+	return nil
+}
+
+// employeeBucketCase names the bucket of c.employees, the buckets are constants so they are inlined.
+func employeeBucketCase() string {
+	var b strings.Builder
+	b.WriteString("case")
+	for _, bucket := range dmodels.EmployeeBuckets {
+		if bucket.Max == nil {
+			fmt.Fprintf(&b, " when c.employees >= %d then '%s'", bucket.Min, bucket.Name)
+		} else {
+			fmt.Fprintf(&b, " when c.employees between %d and %d then '%s'", bucket.Min, *bucket.Max, bucket.Name)
+		}
+	}
+	b.WriteString(" end")
+	return b.String()
+}
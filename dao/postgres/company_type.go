@@ -1,6 +1,13 @@
 package postgres
 
-import "xm-task/dmodels"
+import (
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"xm-task/dmodels"
+)
 
 func (db *Postgres) GetCompanyTypeByName(name string) (dmodels.CompanyType, error) {
 	var ct dmodels.CompanyType
@@ -10,3 +17,101 @@ func (db *Postgres) GetCompanyTypeByName(name string) (dmodels.CompanyType, erro
 		First(&ct).Error
 	return ct, err
 }
+
+func (db *Postgres) GetCompanyTypeByID(id uint64) (dmodels.CompanyType, error) {
+	var ct dmodels.CompanyType
+	err := db.db.Table(dmodels.CompanyTypesTable).
+		Select("*").
+		Where("id = ?", id).
+		Take(&ct).Error
+	return ct, err
+}
+
+// ListCompanyTypes returns types ordered by name with the number of their live companies.
+func (db *Postgres) ListCompanyTypes(withDeprecated bool) ([]dmodels.CompanyType, error) {
+	q := db.db.Table(fmt.Sprintf("%s ct", dmodels.CompanyTypesTable)).
+		Select(fmt.Sprintf("ct.*, (select count(*) from %s c where c.type_id = ct.id and c.deleted_at is null) as companies",
+			dmodels.CompaniesTable))
+	if !withDeprecated {
+		q = q.Where("ct.deprecated_at is null")
+	}
+
+	types := make([]dmodels.CompanyType, 0)
+	err := q.Order("ct.name").Find(&types).Error
+	return types, err
+}
+
+func (db *Postgres) CreateCompanyType(ct dmodels.CompanyType) (dmodels.CompanyType, error) {
+	err := db.db.Table(dmodels.CompanyTypesTable).
+		Omit("id", "companies").
+		Create(&ct).Error
+	return ct, err
+}
+
+// companyTypesChannel is notified when types are changed, so that every instance drops the types it cached.
+const companyTypesChannel = "company_types"
+
+// UpdateCompanyType saves the name, the deprecation time and the attributes schema of the type.
+func (db *Postgres) UpdateCompanyType(ct dmodels.CompanyType) (dmodels.CompanyType, error) {
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(dmodels.CompanyTypesTable).
+			Where("id = ?", ct.ID).
+			Updates(map[string]interface{}{
+				"name":              ct.Name,
+				"deprecated_at":     ct.DeprecatedAt,
+				"attributes_schema": ct.AttributesSchema,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return notifyCompanyTypes(tx)
+	})
+	return ct, err
+}
+
+// MergeCompanyTypes moves every company, trashed ones included, from the source type to the
+// target one and removes the source type. Moved companies get a new version and revision.
+func (db *Postgres) MergeCompanyTypes(sourceID, targetID uint64, authorID uuid.UUID) ([]dmodels.Company, error) {
+	companies := make([]dmodels.Company, 0)
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Table(dmodels.CompaniesTable).
+			Model(&companies).
+			Clauses(clause.Returning{}).
+			Where("type_id = ?", sourceID).
+			Updates(map[string]interface{}{
+				"type_id":    targetID,
+				"updated_at": gorm.Expr("now()"),
+				"version":    gorm.Expr("version + 1"),
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, company := range companies {
+			err := recordRevision(tx, company.ID.String(), dmodels.RevisionActionUpdate, authorID, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		result := tx.Table(dmodels.CompanyTypesTable).
+			Where("id = ?", sourceID).
+			Delete(&dmodels.CompanyType{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return notifyCompanyTypes(tx)
+	})
+	return companies, err
+}
+
+// notifyCompanyTypes notifies the listeners of every instance once the transaction commits.
+func notifyCompanyTypes(tx *gorm.DB) error {
+	return tx.Exec("select pg_notify(?, '')", companyTypesChannel).Error
+}
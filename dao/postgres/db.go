@@ -9,6 +9,7 @@ import (
 	pg "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -18,9 +19,14 @@ import (
 
 const migrationsPath = "./dao/postgres/migrations"
 
+// tenantRole is subject to the row level security policies, see scoped.
+const tenantRole = "xm_tenant"
+
 type Postgres struct {
 	cfg conf.Postgres
 	db  *gorm.DB
+	// inTransaction is set for the copies of Transaction, their db is the transaction.
+	inTransaction bool
 }
 
 func NewPostgres(cfg conf.Postgres, migrate bool) (*Postgres, error) {
@@ -53,13 +59,17 @@ func NewPostgres(cfg conf.Postgres, migrate bool) (*Postgres, error) {
 }
 
 func makeConn(cfg conf.Postgres) (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(dsn(cfg)), &gorm.Config{
+		DisableForeignKeyConstraintWhenMigrating: true,
+		TranslateError:                           true,
+	})
+}
+
+func dsn(cfg conf.Postgres) string {
 	hostPort := net.JoinHostPort(cfg.Host, cfg.Port)
 
-	s := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
 		cfg.User, cfg.Password, hostPort, cfg.Database, cfg.SSLMode)
-	return gorm.Open(postgres.Open(s), &gorm.Config{
-		DisableForeignKeyConstraintWhenMigrating: true,
-	})
 }
 
 func (db *Postgres) makeMigration(conn *gorm.DB, migrationDir string) error {
@@ -104,3 +114,33 @@ func (db *Postgres) CheckDBStatus() bool {
 
 	return true
 }
+
+// scoped runs fn in a transaction bound to the organization. Besides the organization filters
+// of the queries, the transaction switches to the tenant role, so that row level security
+// hides the companies of other organizations even if a filter is missing.
+func (db *Postgres) scoped(orgID uuid.UUID, fn func(tx *gorm.DB) error) error {
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("set local role " + tenantRole).Error; err != nil {
+			return fmt.Errorf("set role: %w", err)
+		}
+		if err := tx.Exec("select set_config('app.organization_id', ?, true)", orgID.String()).Error; err != nil {
+			return fmt.Errorf("set organization: %w", err)
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		// the role would outlive the savepoint of an enclosing transaction
+		if db.inTransaction {
+			return tx.Exec("reset role").Error
+		}
+		return nil
+	})
+}
+
+// Transaction runs fn with a copy of the database whose methods are run in a single transaction,
+// it commits unless fn returns an error.
+func (db *Postgres) Transaction(fn func(tx *Postgres) error) error {
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Postgres{cfg: db.cfg, db: tx, inTransaction: true})
+	})
+}
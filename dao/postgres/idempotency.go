@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+)
+
+// AcquireIdempotencyKey stores the key as in progress for the window. Keys which expired, or which
+// were left in progress longer than the lock timeout by a request with the same fingerprint, are taken over.
+// Otherwise the stored key is returned and acquired is false.
+func (db *Postgres) AcquireIdempotencyKey(key dmodels.IdempotencyKey, window, lockTimeout time.Duration) (dmodels.IdempotencyKey, bool, error) {
+	// the stored key may expire and be purged between the insert and the select, so it is tried twice
+	for attempt := 0; attempt < 2; attempt++ {
+		result := db.db.Exec(`insert into idempotency_keys (user_id, key, fingerprint, locked_at, expires_at)
+			values (@user, @key, @fingerprint, now(), now() + make_interval(secs => @window))
+			on conflict (user_id, key) do update
+			set fingerprint = excluded.fingerprint, status_code = null, headers = '{}', body = null,
+				locked_at = excluded.locked_at, expires_at = excluded.expires_at, created_at = now()
+			where idempotency_keys.expires_at <= now()
+			   or (idempotency_keys.status_code is null
+				   and idempotency_keys.fingerprint = excluded.fingerprint
+				   and idempotency_keys.locked_at < now() - make_interval(secs => @lock))`,
+			map[string]interface{}{
+				"user":        key.UserID,
+				"key":         key.Key,
+				"fingerprint": key.Fingerprint,
+				"window":      window.Seconds(),
+				"lock":        lockTimeout.Seconds(),
+			})
+		if result.Error != nil {
+			return dmodels.IdempotencyKey{}, false, result.Error
+		}
+		if result.RowsAffected > 0 {
+			return key, true, nil
+		}
+
+		var stored dmodels.IdempotencyKey
+		err := db.db.Table(dmodels.IdempotencyKeysTable).
+			Where("user_id = ? and key = ?", key.UserID, key.Key).
+			Take(&stored).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		return stored, false, err
+	}
+	return dmodels.IdempotencyKey{}, false, gorm.ErrRecordNotFound
+}
+
+// SaveIdempotencyResponse stores the response of the request which holds the key. A key taken over
+// by a retry and completed meanwhile, or expired and purged, results in gorm.ErrRecordNotFound.
+func (db *Postgres) SaveIdempotencyResponse(key dmodels.IdempotencyKey) error {
+	result := db.db.Table(dmodels.IdempotencyKeysTable).
+		Where("user_id = ? and key = ? and fingerprint = ? and status_code is null", key.UserID, key.Key, key.Fingerprint).
+		Updates(map[string]interface{}{
+			"status_code": key.StatusCode,
+			"headers":     key.Headers,
+			"body":        key.Body,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey drops a key in progress, so that the request can be retried.
+func (db *Postgres) ReleaseIdempotencyKey(userID uuid.UUID, key, fingerprint string) error {
+	return db.db.Table(dmodels.IdempotencyKeysTable).
+		Where("user_id = ? and key = ? and fingerprint = ? and status_code is null", userID, key, fingerprint).
+		Delete(&dmodels.IdempotencyKey{}).Error
+}
+
+// PurgeIdempotencyKeys removes the expired keys.
+func (db *Postgres) PurgeIdempotencyKeys() (int64, error) {
+	result := db.db.Table(dmodels.IdempotencyKeysTable).
+		Where("expires_at <= now()").
+		Delete(&dmodels.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,5 @@
+drop index if exists companies_parent_id_idx;
+
+alter table companies
+    drop constraint if exists companies_parent_not_self,
+    drop column if exists parent_id;
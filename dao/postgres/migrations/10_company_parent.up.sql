@@ -0,0 +1,7 @@
+alter table companies
+    add column if not exists parent_id uuid references companies (id) on delete set null;
+
+alter table companies
+    add constraint companies_parent_not_self check (parent_id <> id);
+
+create index if not exists companies_parent_id_idx on companies (parent_id) where parent_id is not null;
@@ -0,0 +1,8 @@
+drop index if exists companies_attributes_idx;
+
+alter table companies
+    drop constraint if exists companies_attributes_object,
+    drop column if exists attributes;
+
+alter table company_types
+    drop column if exists attributes_schema;
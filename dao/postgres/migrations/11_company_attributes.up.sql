@@ -0,0 +1,11 @@
+alter table company_types
+    add column if not exists attributes_schema jsonb;
+
+alter table companies
+    add column if not exists attributes jsonb default '{}' not null;
+
+alter table companies
+    add constraint companies_attributes_object check (jsonb_typeof(attributes) = 'object');
+
+-- jsonb_path_ops serves the containment queries of attribute filters
+create index if not exists companies_attributes_idx on companies using gin (attributes jsonb_path_ops);
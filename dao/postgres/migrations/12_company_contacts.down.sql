@@ -0,0 +1,2 @@
+drop table if exists company_addresses;
+drop table if exists company_contacts;
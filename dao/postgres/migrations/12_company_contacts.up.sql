@@ -0,0 +1,43 @@
+create table if not exists company_contacts
+(
+    id         uuid         default uuid_generate_v4() not null constraint company_contacts_pk primary key,
+    company_id uuid         references companies (id) on delete cascade not null,
+    name       varchar(100)                            not null,
+    email      varchar(100) default ''                 not null,
+    phone      varchar(30)  default ''                 not null,
+    position   varchar(100) default ''                 not null,
+    is_primary boolean      default false              not null,
+    created_at timestamp    default now()              not null,
+    updated_at timestamp    default now()              not null
+);
+
+create index if not exists company_contacts_company_id_idx on company_contacts (company_id);
+create unique index if not exists company_contacts_primary_key on company_contacts (company_id) where is_primary;
+
+create table if not exists company_addresses
+(
+    id          uuid         default uuid_generate_v4() not null constraint company_addresses_pk primary key,
+    company_id  uuid         references companies (id) on delete cascade not null,
+    line1       varchar(200)                            not null,
+    line2       varchar(200) default ''                 not null,
+    city        varchar(100)                            not null,
+    region      varchar(100) default ''                 not null,
+    postal_code varchar(20)  default ''                 not null,
+    country     char(2)                                 not null,
+    is_primary  boolean      default false              not null,
+    created_at  timestamp    default now()              not null,
+    updated_at  timestamp    default now()              not null
+);
+
+create index if not exists company_addresses_company_id_idx on company_addresses (company_id);
+create unique index if not exists company_addresses_primary_key on company_addresses (company_id) where is_primary;
+
+alter table company_contacts enable row level security;
+
+create policy company_contacts_organization on company_contacts
+    using (exists(select 1 from companies c where c.id = company_id));
+
+alter table company_addresses enable row level security;
+
+create policy company_addresses_organization on company_addresses
+    using (exists(select 1 from companies c where c.id = company_id));
@@ -0,0 +1,15 @@
+create table if not exists idempotency_keys
+(
+    user_id     uuid references users (id) on delete cascade not null,
+    key         varchar(255)                                 not null,
+    fingerprint char(64)                                     not null,
+    status_code integer,
+    headers     jsonb     default '{}'                       not null,
+    body        bytea,
+    locked_at   timestamp default now()                      not null,
+    expires_at  timestamp                                    not null,
+    created_at  timestamp default now()                      not null,
+    constraint idempotency_keys_pk primary key (user_id, key)
+);
+
+create index if not exists idempotency_keys_expires_at_idx on idempotency_keys (expires_at);
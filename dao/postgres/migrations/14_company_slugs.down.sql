@@ -0,0 +1,13 @@
+drop trigger if exists companies_slug on companies;
+drop function if exists companies_set_slug();
+drop function if exists company_slug(uuid, uuid, text);
+drop function if exists company_slug_base(text);
+
+drop index if exists companies_slug_key;
+alter table companies
+    drop column if exists slug;
+
+drop table if exists company_slugs;
+
+drop index if exists companies_name_live_key;
+create unique index if not exists companies_name_live_key on companies (organization_id, name) where deleted_at is null;
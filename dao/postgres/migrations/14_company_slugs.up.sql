@@ -0,0 +1,108 @@
+-- names are unique within an organization regardless of the case
+do
+$$
+    declare
+        duplicates text;
+    begin
+        select string_agg(format('%s (%s)', names, organization_id), ', ')
+        into duplicates
+        from (select organization_id, string_agg(name, ' / ') as names
+              from companies
+              where deleted_at is null
+              group by organization_id, lower(name)
+              having count(*) > 1) d;
+        if duplicates is not null then
+            raise exception 'live companies differ only in the case of their names, rename them first: %', duplicates;
+        end if;
+    end
+$$;
+
+drop index if exists companies_name_live_key;
+create unique index if not exists companies_name_live_key on companies (organization_id, lower(name)) where deleted_at is null;
+
+-- company_slugs keeps every slug a company ever had, so that old links keep working after renames.
+-- Slugs are never handed over to other companies until the company is purged.
+create table if not exists company_slugs
+(
+    organization_id uuid                    not null,
+    slug            varchar(64)             not null,
+    company_id      uuid references companies (id) on delete cascade deferrable initially deferred not null,
+    created_at      timestamp default now() not null,
+    constraint company_slugs_pk primary key (organization_id, slug)
+);
+
+create index if not exists company_slugs_company_id_idx on company_slugs (company_id);
+
+alter table company_slugs enable row level security;
+
+create policy company_slugs_organization on company_slugs
+    using (organization_id = nullif(current_setting('app.organization_id', true), '')::uuid);
+
+alter table companies
+    add column if not exists slug varchar(64);
+
+-- company_slug_base turns the name into lower case ASCII words joined by dashes
+create or replace function company_slug_base(name text) returns text as
+$$
+select coalesce(nullif(trim(both '-' from left(regexp_replace(lower(name), '[^a-z0-9]+', '-', 'g'), 50)), ''), 'company')
+$$ language sql immutable;
+
+-- company_slug reserves a slug for the company, numbering it when the base is taken by another company
+create or replace function company_slug(org uuid, company uuid, name text) returns text as
+$$
+declare
+    base      text := company_slug_base(name);
+    candidate text := base;
+    n         int  := 1;
+begin
+    perform pg_advisory_xact_lock(hashtext('company_slugs:' || org::text));
+    loop
+        if not exists(select 1
+                      from company_slugs s
+                      where s.organization_id = org
+                        and s.slug = candidate
+                        and s.company_id <> company) then
+            insert into company_slugs (organization_id, slug, company_id)
+            values (org, candidate, company)
+            on conflict do nothing;
+            return candidate;
+        end if;
+        n := n + 1;
+        candidate := base || '-' || n;
+    end loop;
+end
+$$ language plpgsql;
+
+do
+$$
+    declare
+        c record;
+    begin
+        for c in select id, organization_id, name from companies order by created_at, id
+            loop
+                update companies set slug = company_slug(c.organization_id, c.id, c.name) where id = c.id;
+            end loop;
+    end
+$$;
+
+alter table companies
+    alter column slug set not null;
+
+create unique index if not exists companies_slug_key on companies (organization_id, slug);
+
+-- slugs follow the names, a change of the case or the punctuation keeps the current slug
+create or replace function companies_set_slug() returns trigger as
+$$
+begin
+    if tg_op = 'INSERT' or company_slug_base(new.name) <> company_slug_base(old.name) then
+        new.slug := company_slug(new.organization_id, new.id, new.name);
+    end if;
+    return new;
+end
+$$ language plpgsql;
+
+create trigger companies_slug
+    before insert or update of name
+    on companies
+    for each row
+execute function companies_set_slug();
@@ -0,0 +1 @@
+drop index if exists companies_name_trgm_idx;
@@ -0,0 +1,4 @@
+create extension if not exists pg_trgm;
+
+-- similar names of live companies are looked up before creating companies, see SimilarCompanies
+create index if not exists companies_name_trgm_idx on companies using gin (lower(name) gin_trgm_ops) where deleted_at is null;
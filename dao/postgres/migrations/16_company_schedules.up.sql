@@ -0,0 +1,25 @@
+create table if not exists company_schedules
+(
+    id              uuid        default uuid_generate_v4() not null constraint company_schedules_pk primary key,
+    organization_id uuid references organizations (id) on delete cascade not null,
+    company_id      uuid references companies (id) on delete cascade not null,
+    -- changes is a JSON Merge Patch of the company, fields are the paths it touches
+    changes         jsonb                                  not null,
+    fields          text[]                                 not null,
+    effective_at    timestamp                              not null,
+    status          varchar(20) default 'pending'          not null,
+    error           text        default ''                 not null,
+    author_id       uuid references users (id) on delete set null,
+    -- claimed_at is set while a worker applies the change, stale claims are taken over
+    claimed_at      timestamp,
+    finished_at     timestamp,
+    created_at      timestamp   default now()              not null
+);
+
+create index if not exists company_schedules_company_id_idx on company_schedules (company_id, effective_at);
+create index if not exists company_schedules_due_idx on company_schedules (effective_at) where status = 'pending';
+
+alter table company_schedules enable row level security;
+
+create policy company_schedules_organization on company_schedules
+    using (organization_id = nullif(current_setting('app.organization_id', true), '')::uuid);
@@ -0,0 +1,3 @@
+drop table if exists company_attachments;
+drop function if exists company_attachments_deleted();
+drop table if exists blob_deletions;
@@ -0,0 +1,41 @@
+create table if not exists company_attachments
+(
+    id           uuid         default uuid_generate_v4() not null constraint company_attachments_pk primary key,
+    company_id   uuid         references companies (id) on delete cascade not null,
+    file_name    varchar(255)                            not null,
+    content_type varchar(255)                            not null,
+    size         bigint                                  not null,
+    -- checksum is the hex encoded SHA-256 of the content
+    checksum     char(64)                                not null,
+    storage_key  varchar(512)                            not null constraint company_attachments_storage_key unique,
+    author_id    uuid references users (id) on delete set null,
+    created_at   timestamp    default now()              not null
+);
+
+create index if not exists company_attachments_company_id_idx on company_attachments (company_id, created_at);
+
+alter table company_attachments enable row level security;
+
+create policy company_attachments_organization on company_attachments
+    using (exists(select 1 from companies c where c.id = company_id));
+
+-- Blobs of deleted attachments, including the ones removed along with purged companies,
+-- wait here until they are removed from the storage.
+create table if not exists blob_deletions
+(
+    storage_key varchar(512)            not null constraint blob_deletions_pk primary key,
+    created_at  timestamp default now() not null
+);
+
+create or replace function company_attachments_deleted() returns trigger as
+$$
+begin
+    insert into blob_deletions (storage_key) values (old.storage_key) on conflict do nothing;
+    return old;
+end;
+$$ language plpgsql;
+
+create trigger company_attachments_deleted
+    after delete on company_attachments
+    for each row
+execute function company_attachments_deleted();
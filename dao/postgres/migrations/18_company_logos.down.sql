@@ -0,0 +1,2 @@
+drop table if exists company_logos;
+drop function if exists company_logos_replaced();
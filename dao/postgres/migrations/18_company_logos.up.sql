@@ -0,0 +1,39 @@
+create table if not exists company_logos
+(
+    company_id   uuid references companies (id) on delete cascade not null constraint company_logos_pk primary key,
+    -- hash is a prefix of the SHA-256 of the stored original, it is part of the logo URLs
+    hash         varchar(64)             not null,
+    content_type varchar(100)            not null,
+    width        integer                 not null,
+    height       integer                 not null,
+    size         bigint                  not null,
+    -- sizes are the longest sides of the thumbnails, storage_keys hold the original and the thumbnails,
+    -- every upload has its own keys so that queued blobs are never reused
+    sizes        integer[]               not null,
+    storage_keys text[]                  not null,
+    author_id    uuid references users (id) on delete set null,
+    created_at   timestamp default now() not null
+);
+
+alter table company_logos enable row level security;
+
+create policy company_logos_organization on company_logos
+    using (exists(select 1 from companies c where c.id = company_id));
+
+-- replaced and removed logos leave their blobs behind, see blob_deletions
+create or replace function company_logos_replaced() returns trigger as
+$$
+begin
+    if tg_op = 'DELETE' or old.storage_keys <> new.storage_keys then
+        insert into blob_deletions (storage_key)
+        select unnest(old.storage_keys)
+        on conflict do nothing;
+    end if;
+    return null;
+end;
+$$ language plpgsql;
+
+create trigger company_logos_replaced
+    after update or delete on company_logos
+    for each row
+execute function company_logos_replaced();
@@ -0,0 +1,55 @@
+create table if not exists webhooks
+(
+    id              uuid      default uuid_generate_v4() not null constraint webhooks_pk primary key,
+    organization_id uuid references organizations (id) on delete cascade not null,
+    url             text                                 not null,
+    -- events are the company events delivered to the endpoint, e.g. company.created
+    events          text[]                               not null,
+    -- secret signs the deliveries, it is only shown when the webhook is created
+    secret          varchar(64)                          not null,
+    enabled         boolean   default true               not null,
+    -- failures counts the consecutive failed attempts, the webhook is disabled once they reach the limit
+    failures        integer   default 0                  not null,
+    disabled_at     timestamp,
+    author_id       uuid references users (id) on delete set null,
+    created_at      timestamp default now()              not null,
+    updated_at      timestamp default now()              not null
+);
+
+create index if not exists webhooks_organization_id_idx on webhooks (organization_id);
+
+alter table webhooks enable row level security;
+
+create policy webhooks_organization on webhooks
+    using (organization_id = nullif(current_setting('app.organization_id', true), '')::uuid);
+
+create table if not exists webhook_deliveries
+(
+    id              uuid        default uuid_generate_v4() not null constraint webhook_deliveries_pk primary key,
+    webhook_id      uuid references webhooks (id) on delete cascade not null,
+    organization_id uuid references organizations (id) on delete cascade not null,
+    event           varchar(50)                            not null,
+    -- event_id is shared by the deliveries of the same event, receivers may drop duplicates by it
+    event_id        uuid                                   not null,
+    payload         jsonb                                  not null,
+    status          varchar(20) default 'pending'          not null,
+    attempts        integer     default 0                  not null,
+    next_attempt_at timestamp   default now()              not null,
+    -- claimed_at is set while a worker delivers the event, stale claims are taken over
+    claimed_at      timestamp,
+    -- the outcome of the last attempt
+    response_status integer     default 0                  not null,
+    response_body   text        default ''                 not null,
+    error           text        default ''                 not null,
+    attempted_at    timestamp,
+    created_at      timestamp   default now()              not null
+);
+
+create index if not exists webhook_deliveries_webhook_id_idx on webhook_deliveries (webhook_id, created_at);
+create index if not exists webhook_deliveries_due_idx on webhook_deliveries (next_attempt_at) where status = 'pending';
+create index if not exists webhook_deliveries_created_at_idx on webhook_deliveries (created_at) where status <> 'pending';
+
+alter table webhook_deliveries enable row level security;
+
+create policy webhook_deliveries_organization on webhook_deliveries
+    using (organization_id = nullif(current_setting('app.organization_id', true), '')::uuid);
@@ -0,0 +1,19 @@
+-- company_events is the replay buffer of the live change feed, only the latest events are kept.
+-- The ids only grow, clients resume from the last one they saw.
+create table if not exists company_events
+(
+    id              bigserial                 not null constraint company_events_pk primary key,
+    organization_id uuid references organizations (id) on delete cascade not null,
+    company_id      uuid                      not null,
+    type_id         bigint,
+    event           varchar(50)               not null,
+    version         bigint    default 0       not null,
+    created_at      timestamp default now()   not null
+);
+
+create index if not exists company_events_organization_id_idx on company_events (organization_id, id);
+
+alter table company_events enable row level security;
+
+create policy company_events_organization on company_events
+    using (organization_id = nullif(current_setting('app.organization_id', true), '')::uuid);
@@ -0,0 +1,4 @@
+drop index if exists companies_search_vector_idx;
+
+alter table companies drop column if exists search_vector;
+alter table companies drop column if exists search_language;
@@ -0,0 +1,10 @@
+alter table companies
+    add column if not exists search_language regconfig default 'english' not null;
+
+alter table companies
+    add column if not exists search_vector tsvector generated always as (
+        setweight(to_tsvector(search_language, name), 'A') ||
+        setweight(to_tsvector(search_language, description), 'B')
+    ) stored;
+
+create index if not exists companies_search_vector_idx on companies using gin (search_vector);
@@ -0,0 +1,7 @@
+delete from companies where deleted_at is not null;
+
+drop index if exists companies_deleted_at_idx;
+drop index if exists companies_name_live_key;
+
+alter table companies add constraint companies_name_key unique (name);
+alter table companies drop column if exists deleted_at;
@@ -0,0 +1,8 @@
+alter table companies
+    add column if not exists deleted_at timestamp;
+
+-- names have to be unique among live companies only, trashed ones may be recreated
+alter table companies drop constraint if exists companies_name_key;
+create unique index if not exists companies_name_live_key on companies (name) where deleted_at is null;
+
+create index if not exists companies_deleted_at_idx on companies (deleted_at) where deleted_at is not null;
@@ -0,0 +1 @@
+drop table if exists company_revisions;
@@ -0,0 +1,27 @@
+create table if not exists company_revisions
+(
+    id            bigserial                         not null constraint company_revisions_pk primary key,
+    company_id    uuid           references    companies(id) on delete cascade not null,
+    revision      int8                              not null,
+    action        varchar(20)                       not null,
+    author_id     uuid           references    users(id) on delete set null,
+    reverted_from int8,
+    snapshot      jsonb                             not null,
+    created_at    timestamp      default now()      not null,
+    constraint company_revisions_company_revision_key unique (company_id, revision)
+);
+
+-- existing companies start their history from a synthetic "create" revision
+insert into company_revisions (company_id, revision, action, snapshot, created_at)
+select c.id, 1, 'create', json_build_object(
+           'name', c.name,
+           'description', c.description,
+           'employees', c.employees,
+           'registered', c.registered,
+           'type_id', c.type_id,
+           'type', ct.name,
+           'updated_at', to_char(c.updated_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+           'deleted_at', to_char(c.deleted_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+       ), c.created_at
+from companies c
+         inner join company_types ct on ct.id = c.type_id;
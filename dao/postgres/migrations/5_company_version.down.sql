@@ -0,0 +1 @@
+alter table companies drop column if exists version;
@@ -0,0 +1,2 @@
+alter table companies
+    add column if not exists version int8 default 1 not null;
@@ -0,0 +1,5 @@
+alter table users drop column if exists is_admin;
+
+drop index if exists company_types_name_key;
+
+alter table company_types drop column if exists deprecated_at;
@@ -0,0 +1,10 @@
+alter table company_types
+    add column if not exists deprecated_at timestamp;
+
+create unique index if not exists company_types_name_key on company_types (name);
+
+-- the seed rows were inserted with explicit ids, so the sequence has to catch up
+select setval(pg_get_serial_sequence('company_types', 'id'), (select coalesce(max(id), 1) from company_types));
+
+alter table users
+    add column if not exists is_admin boolean default false not null;
@@ -0,0 +1,5 @@
+drop index if exists companies_owner_id_idx;
+
+alter table companies
+    drop column if exists owner_id,
+    drop column if exists created_by;
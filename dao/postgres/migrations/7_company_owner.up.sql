@@ -0,0 +1,16 @@
+alter table companies
+    add column if not exists created_by uuid references users (id) on delete set null,
+    add column if not exists owner_id   uuid references users (id) on delete set null;
+
+-- companies created before ownership belong to the author of their first revision. Companies without one,
+-- created before revisions were recorded, are left without an owner on purpose: there is no user to hand them to,
+-- so only the roles managing every company can change them until they are transferred.
+update companies c
+set created_by = r.author_id,
+    owner_id   = r.author_id
+from company_revisions r
+where r.company_id = c.id
+  and r.revision = 1
+  and c.created_by is null;
+
+create index if not exists companies_owner_id_idx on companies (owner_id);
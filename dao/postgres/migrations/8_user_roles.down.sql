@@ -0,0 +1,8 @@
+alter table users
+    add column if not exists is_admin boolean default false not null;
+
+update users
+set is_admin = true
+where role = 'admin';
+
+alter table users drop column if exists role;
@@ -0,0 +1,8 @@
+alter table users
+    add column if not exists role varchar(20) default 'editor' not null;
+
+update users
+set role = 'admin'
+where is_admin;
+
+alter table users drop column if exists is_admin;
@@ -0,0 +1,25 @@
+drop policy if exists company_revisions_organization on company_revisions;
+alter table company_revisions disable row level security;
+
+drop policy if exists companies_organization on companies;
+alter table companies disable row level security;
+
+drop owned by xm_tenant;
+drop role if exists xm_tenant;
+
+drop index if exists companies_name_live_key;
+create unique index if not exists companies_name_live_key on companies (name) where deleted_at is null;
+
+alter table companies drop column if exists organization_id;
+
+alter table users
+    add column if not exists role varchar(20) default 'editor' not null;
+
+update users u
+set role = m.role
+from organization_members m
+where m.user_id = u.id
+  and m.organization_id = '00000000-0000-0000-0000-000000000001';
+
+drop table if exists organization_members;
+drop table if exists organizations;
@@ -0,0 +1,71 @@
+create table if not exists organizations
+(
+    id         uuid default uuid_generate_v4() not null constraint organizations_pk primary key,
+    name       varchar(100)                    not null,
+    created_at timestamp default now()         not null
+);
+
+-- the default organization keeps the data created before organizations, see dmodels.DefaultOrganizationID
+insert into organizations (id, name)
+values ('00000000-0000-0000-0000-000000000001', 'Default')
+on conflict do nothing;
+
+create table if not exists organization_members
+(
+    organization_id uuid references organizations (id) on delete cascade not null,
+    user_id         uuid references users (id) on delete cascade         not null,
+    role            varchar(20) default 'editor'                         not null,
+    created_at      timestamp default now()                              not null,
+    constraint organization_members_pk primary key (organization_id, user_id)
+);
+
+create index if not exists organization_members_user_id_idx on organization_members (user_id);
+
+-- roles are per organization from now on
+insert into organization_members (organization_id, user_id, role)
+select '00000000-0000-0000-0000-000000000001', id, role
+from users
+on conflict do nothing;
+
+alter table users drop column if exists role;
+
+alter table companies
+    add column if not exists organization_id uuid references organizations (id) on delete cascade;
+
+update companies
+set organization_id = '00000000-0000-0000-0000-000000000001'
+where organization_id is null;
+
+alter table companies alter column organization_id set not null;
+
+-- names are unique within an organization
+drop index if exists companies_name_live_key;
+create unique index if not exists companies_name_live_key on companies (organization_id, name) where deleted_at is null;
+
+-- Row level security is the second line of defence behind the organization filters of the queries.
+-- Scoped transactions switch to the tenant role and set app.organization_id, the owner of the tables
+-- keeps bypassing the policies for migrations and maintenance jobs.
+do
+$$
+    begin
+        if not exists (select from pg_roles where rolname = 'xm_tenant') then
+            create role xm_tenant nologin;
+        end if;
+    end
+$$;
+
+grant xm_tenant to current_user;
+grant select, insert, update, delete on all tables in schema public to xm_tenant;
+grant usage, select on all sequences in schema public to xm_tenant;
+alter default privileges in schema public grant select, insert, update, delete on tables to xm_tenant;
+alter default privileges in schema public grant usage, select on sequences to xm_tenant;
+
+alter table companies enable row level security;
+
+create policy companies_organization on companies
+    using (organization_id = nullif(current_setting('app.organization_id', true), '')::uuid);
+
+alter table company_revisions enable row level security;
+
+create policy company_revisions_organization on company_revisions
+    using (exists(select 1 from companies c where c.id = company_id));
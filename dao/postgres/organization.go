@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+)
+
+// CreateOrganization stores the organization and makes the user its member with the given role.
+func (db *Postgres) CreateOrganization(org dmodels.Organization, userID uuid.UUID) (dmodels.Organization, error) {
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(dmodels.OrganizationsTable).Create(&org).Error; err != nil {
+			return err
+		}
+		return tx.Table(dmodels.OrganizationMembersTable).Create(&dmodels.OrganizationMember{
+			OrganizationID: org.ID,
+			UserID:         userID,
+			Role:           org.Role,
+		}).Error
+	})
+	return org, err
+}
+
+// ListUserOrganizations returns the organizations of the user in the order of joining,
+// along with the role of the user.
+func (db *Postgres) ListUserOrganizations(userID uuid.UUID) ([]dmodels.Organization, error) {
+	orgs := make([]dmodels.Organization, 0)
+	err := db.db.Table(fmt.Sprintf("%s o", dmodels.OrganizationsTable)).
+		Select("o.*, m.role").
+		Joins(fmt.Sprintf("inner join %s m on m.organization_id = o.id", dmodels.OrganizationMembersTable)).
+		Where("m.user_id = ?", userID).
+		Order("m.created_at, o.id").
+		Find(&orgs).Error
+	return orgs, err
+}
@@ -1,9 +1,26 @@
 package postgres
 
-import "xm-task/dmodels"
+import (
+	"fmt"
 
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"xm-task/dmodels"
+)
+
+// CreateUser stores the user along with the membership described by its OrganizationID and Role.
 func (db *Postgres) CreateUser(user dmodels.User) (dmodels.User, error) {
-	err := db.db.Table(dmodels.UsersTable).Create(&user).Error
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(dmodels.UsersTable).Create(&user).Error; err != nil {
+			return err
+		}
+		return tx.Table(dmodels.OrganizationMembersTable).Create(&dmodels.OrganizationMember{
+			OrganizationID: user.OrganizationID,
+			UserID:         user.ID,
+			Role:           user.Role,
+		}).Error
+	})
 	return user, err
 }
 
@@ -15,3 +32,60 @@ func (db *Postgres) GetUserByEmail(email string) (dmodels.User, error) {
 		First(&user).Error
 	return user, err
 }
+
+// GetMember returns the user along with the role within the organization.
+func (db *Postgres) GetMember(email string, orgID uuid.UUID) (dmodels.User, error) {
+	var user dmodels.User
+	err := db.members(orgID).
+		Where("u.email = ?", email).
+		Take(&user).Error
+	return user, err
+}
+
+// ListMembers returns the members of the organization ordered by email.
+func (db *Postgres) ListMembers(orgID uuid.UUID) ([]dmodels.User, error) {
+	users := make([]dmodels.User, 0)
+	err := db.members(orgID).
+		Order("u.email").
+		Find(&users).Error
+	return users, err
+}
+
+// SetMemberRole assigns the role within the organization, the user joins it if needed.
+func (db *Postgres) SetMemberRole(email string, orgID uuid.UUID, role string) error {
+	user, err := db.GetUserByEmail(email)
+	if err != nil {
+		return err
+	}
+
+	return db.db.Table(dmodels.OrganizationMembersTable).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "organization_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role"}),
+		}).
+		Create(&dmodels.OrganizationMember{
+			OrganizationID: orgID,
+			UserID:         user.ID,
+			Role:           role,
+		}).Error
+}
+
+func (db *Postgres) RemoveMember(email string, orgID uuid.UUID) error {
+	result := db.db.Table(dmodels.OrganizationMembersTable).
+		Where(fmt.Sprintf("organization_id = ? and user_id = (select id from %s where email = ?)", dmodels.UsersTable),
+			orgID, email).
+		Delete(&dmodels.OrganizationMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (db *Postgres) members(orgID uuid.UUID) *gorm.DB {
+	return db.db.Table(fmt.Sprintf("%s u", dmodels.UsersTable)).
+		Select("u.*, m.organization_id, m.role").
+		Joins(fmt.Sprintf("inner join %s m on m.user_id = u.id and m.organization_id = ?", dmodels.OrganizationMembersTable), orgID)
+}
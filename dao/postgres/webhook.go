@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+)
+
+func (db *Postgres) ListWebhooks(orgID uuid.UUID) ([]dmodels.Webhook, error) {
+	webhooks := make([]dmodels.Webhook, 0)
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return tx.Table(dmodels.WebhooksTable).
+			Where("organization_id = ?", orgID).
+			Order("created_at, id").
+			Find(&webhooks).Error
+	})
+	return webhooks, err
+}
+
+func (db *Postgres) GetWebhook(id string, orgID uuid.UUID) (dmodels.Webhook, error) {
+	var webhook dmodels.Webhook
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return tx.Table(dmodels.WebhooksTable).
+			Where("id = ? and organization_id = ?", id, orgID).
+			Take(&webhook).Error
+	})
+	return webhook, err
+}
+
+func (db *Postgres) CreateWebhook(webhook dmodels.Webhook) (dmodels.Webhook, error) {
+	err := db.scoped(webhook.OrganizationID, func(tx *gorm.DB) error {
+		return tx.Table(dmodels.WebhooksTable).Create(&webhook).Error
+	})
+	return webhook, err
+}
+
+// UpdateWebhook saves the URL, the events and the state of the webhook.
+func (db *Postgres) UpdateWebhook(webhook dmodels.Webhook) (dmodels.Webhook, error) {
+	err := db.scoped(webhook.OrganizationID, func(tx *gorm.DB) error {
+		result := tx.Table(dmodels.WebhooksTable).
+			Where("id = ? and organization_id = ?", webhook.ID, webhook.OrganizationID).
+			Updates(map[string]interface{}{
+				"url":         webhook.URL,
+				"events":      webhook.Events,
+				"enabled":     webhook.Enabled,
+				"failures":    webhook.Failures,
+				"disabled_at": webhook.DisabledAt,
+				"updated_at":  gorm.Expr("now()"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Table(dmodels.WebhooksTable).Where("id = ?", webhook.ID).Take(&webhook).Error
+	})
+	return webhook, err
+}
+
+// DeleteWebhook removes the webhook along with its deliveries.
+func (db *Postgres) DeleteWebhook(id string, orgID uuid.UUID) error {
+	return db.scoped(orgID, func(tx *gorm.DB) error {
+		result := tx.Table(dmodels.WebhooksTable).
+			Where("id = ? and organization_id = ?", id, orgID).
+			Delete(&dmodels.Webhook{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// ListWebhookDeliveries returns a page of the deliveries of the webhook, the latest first, along with their total.
+// An empty status lists deliveries in any status.
+func (db *Postgres) ListWebhookDeliveries(webhookID string, status string, limit, offset int, orgID uuid.UUID) ([]dmodels.WebhookDelivery, int64, error) {
+	deliveries := make([]dmodels.WebhookDelivery, 0)
+	var total int64
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		query := func() *gorm.DB {
+			q := tx.Table(dmodels.WebhookDeliveriesTable).
+				Where("webhook_id = ? and organization_id = ?", webhookID, orgID)
+			if status != "" {
+				q = q.Where("status = ?", status)
+			}
+			return q
+		}
+		if err := query().Count(&total).Error; err != nil {
+			return err
+		}
+		return query().Order("created_at desc, id").Limit(limit).Offset(offset).Find(&deliveries).Error
+	})
+	return deliveries, total, err
+}
+
+func (db *Postgres) GetWebhookDelivery(webhookID, id string, orgID uuid.UUID) (dmodels.WebhookDelivery, error) {
+	var delivery dmodels.WebhookDelivery
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		return tx.Table(dmodels.WebhookDeliveriesTable).
+			Where("webhook_id = ? and id = ? and organization_id = ?", webhookID, id, orgID).
+			Take(&delivery).Error
+	})
+	return delivery, err
+}
+
+// RedeliverWebhookDelivery queues the event of the delivery once more as a new delivery,
+// so that the log keeps the attempts of the former one.
+func (db *Postgres) RedeliverWebhookDelivery(webhookID, id string, orgID uuid.UUID) (dmodels.WebhookDelivery, error) {
+	var delivery dmodels.WebhookDelivery
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		err := tx.Table(dmodels.WebhookDeliveriesTable).
+			Where("webhook_id = ? and id = ? and organization_id = ?", webhookID, id, orgID).
+			Take(&delivery).Error
+		if err != nil {
+			return err
+		}
+
+		delivery = dmodels.WebhookDelivery{
+			ID:             uuid.NewV4(),
+			WebhookID:      delivery.WebhookID,
+			OrganizationID: delivery.OrganizationID,
+			Event:          delivery.Event,
+			EventID:        delivery.EventID,
+			Payload:        delivery.Payload,
+			Status:         dmodels.DeliveryStatusPending,
+		}
+		return tx.Table(dmodels.WebhookDeliveriesTable).Create(&delivery).Error
+	})
+	return delivery, err
+}
+
+// QueueWebhookDeliveries queues the event for every enabled webhook of the organization subscribed to it
+// and returns how many deliveries were queued.
+func (db *Postgres) QueueWebhookDeliveries(orgID uuid.UUID, event string, eventID uuid.UUID, payload dmodels.JSONObject) (int64, error) {
+	var queued int64
+	err := db.scoped(orgID, func(tx *gorm.DB) error {
+		result := tx.Exec(`insert into webhook_deliveries (webhook_id, organization_id, event, event_id, payload)
+			select id, organization_id, @event, @event_id, @payload from webhooks
+			where organization_id = @org and enabled and @event = any(events)`,
+			map[string]interface{}{
+				"event":    event,
+				"event_id": eventID,
+				"payload":  payload,
+				"org":      orgID,
+			})
+		queued = result.RowsAffected
+		return result.Error
+	})
+	return queued, err
+}
+
+// ClaimDueWebhookDeliveries marks up to limit pending deliveries of enabled webhooks of all organizations as
+// being sent, the longest waiting first, and loads the URLs and the secrets of their webhooks. Deliveries claimed
+// longer than the claim timeout ago are claimed again, their worker is assumed gone.
+func (db *Postgres) ClaimDueWebhookDeliveries(limit int, claimTimeout time.Duration) ([]dmodels.WebhookDelivery, error) {
+	deliveries := make([]dmodels.WebhookDelivery, 0)
+	err := db.db.Raw(`with claimed as (
+			update webhook_deliveries set claimed_at = now()
+			where id in (
+				select d.id from webhook_deliveries d
+				inner join webhooks w on w.id = d.webhook_id and w.enabled
+				where d.status = @pending and d.next_attempt_at <= now()
+				  and (d.claimed_at is null or d.claimed_at < now() - make_interval(secs => @timeout))
+				order by d.next_attempt_at
+				limit @limit
+				for update of d skip locked)
+			returning *)
+		select c.*, w.url, w.secret
+		from claimed c
+		inner join webhooks w on w.id = c.webhook_id
+		order by c.next_attempt_at`,
+		map[string]interface{}{
+			"pending": dmodels.DeliveryStatusPending,
+			"timeout": claimTimeout.Seconds(),
+			"limit":   limit,
+		}).Scan(&deliveries).Error
+	return deliveries, err
+}
+
+// RecordWebhookAttempt records the outcome of an attempt of a claimed delivery. Failed attempts count towards
+// the consecutive failures of the webhook, which is disabled once they reach disableAfter, successful ones reset
+// them.
+func (db *Postgres) RecordWebhookAttempt(attempt dmodels.WebhookAttempt, disableAfter int) error {
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(dmodels.WebhookDeliveriesTable).
+			Where("id = ? and status = ?", attempt.DeliveryID, dmodels.DeliveryStatusPending).
+			Updates(map[string]interface{}{
+				"status":          attempt.Status,
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": attempt.NextAttemptAt,
+				"claimed_at":      nil,
+				"response_status": attempt.ResponseStatus,
+				"response_body":   attempt.ResponseBody,
+				"error":           attempt.Error,
+				"attempted_at":    gorm.Expr("now()"),
+			})
+		// another worker took the delivery over and recorded it already
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if attempt.Status == dmodels.DeliveryStatusSucceeded {
+			return tx.Table(dmodels.WebhooksTable).
+				Where("id = ? and failures > 0", attempt.WebhookID).
+				Update("failures", 0).Error
+		}
+
+		var failures int
+		err := tx.Raw("update webhooks set failures = failures + 1 where id = ? and enabled returning failures",
+			attempt.WebhookID).Scan(&failures).Error
+		if err != nil || failures < disableAfter {
+			return err
+		}
+
+		return tx.Table(dmodels.WebhooksTable).
+			Where("id = ?", attempt.WebhookID).
+			Updates(map[string]interface{}{
+				"enabled":     false,
+				"disabled_at": gorm.Expr("now()"),
+				"updated_at":  gorm.Expr("now()"),
+			}).Error
+	})
+}
+
+// PurgeWebhookDeliveries removes the finished deliveries created before the time.
+func (db *Postgres) PurgeWebhookDeliveries(before time.Time) (int64, error) {
+	result := db.db.Table(dmodels.WebhookDeliveriesTable).
+		Where("status <> ? and created_at < ?", dmodels.DeliveryStatusPending, before).
+		Delete(&dmodels.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
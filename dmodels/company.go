@@ -8,22 +8,117 @@ import (
 
 const CompaniesTable = "companies"
 
+const (
+	CompanySortID         = "id"
+	CompanySortName       = "name"
+	CompanySortEmployees  = "employees_count"
+	CompanySortRegistered = "registered"
+	CompanySortType       = "type"
+	CompanySortCreatedAt  = "created_at"
+)
+
 type Company struct {
-	ID          uuid.UUID `gorm:"column:id;PRIMARY_KEY"`
-	Name        string    `gorm:"column:name"`
-	Description string    `gorm:"column:description"`
-	Employees   uint64    `gorm:"column:employees"`
-	Registered  bool      `gorm:"column:registered;default:false"`
-	TypeID      uint64    `gorm:"column:type_id"`
-	CreatedAt   time.Time `gorm:"column:created_at;default:now()"`
-	UpdatedAt   time.Time `gorm:"column:updated_at;default:now()"`
+	ID          uuid.UUID  `gorm:"column:id;PRIMARY_KEY"`
+	Name        string     `gorm:"column:name"`
+	Description string     `gorm:"column:description"`
+	Employees   uint64     `gorm:"column:employees"`
+	Registered  bool       `gorm:"column:registered;default:false"`
+	TypeID      uint64     `gorm:"column:type_id"`
+	Language    string     `gorm:"column:search_language;default:english" json:"-"`
+	CreatedAt   time.Time  `gorm:"column:created_at;default:now()"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at;default:now()"`
+	DeletedAt   *time.Time `gorm:"column:deleted_at"`
+	// Version is incremented on every change, a non-zero value is checked on update.
+	Version uint64 `gorm:"column:version;default:1"`
+	// CreatedBy never changes, OwnerID is changed by transfers. Only the owner and admins can modify the company.
+	CreatedBy *uuid.UUID `gorm:"column:created_by"`
+	OwnerID   *uuid.UUID `gorm:"column:owner_id"`
+	// OrganizationID never changes, every company query is scoped by it.
+	OrganizationID uuid.UUID `gorm:"column:organization_id"`
+	// ParentID is only written by SetCompanyParent, which keeps the hierarchy free of cycles.
+	ParentID *uuid.UUID `gorm:"column:parent_id;->"`
+	// Attributes are checked against the schema of the company type, see CompanyType.AttributesSchema.
+	Attributes JSONObject `gorm:"column:attributes"`
+	// Slug is derived from the name by the database, see GetCompanyBySlug.
+	Slug string `gorm:"column:slug;->"`
 }
 
 type CompanyShow struct {
-	ID          uuid.UUID `gorm:"column:id;PRIMARY_KEY"`
-	Name        string    `gorm:"column:name"`
-	Description string    `gorm:"column:description"`
-	Employees   uint64    `gorm:"column:employees"`
-	Registered  bool      `gorm:"column:registered;default:false"`
-	Type        string    `gorm:"column:type"`
+	ID             uuid.UUID  `gorm:"column:id;PRIMARY_KEY"`
+	Name           string     `gorm:"column:name"`
+	Description    string     `gorm:"column:description"`
+	Employees      uint64     `gorm:"column:employees"`
+	Registered     bool       `gorm:"column:registered;default:false"`
+	Type           string     `gorm:"column:type"`
+	CreatedAt      time.Time  `gorm:"column:created_at"`
+	DeletedAt      *time.Time `gorm:"column:deleted_at"`
+	Version        uint64     `gorm:"column:version"`
+	OwnerID        *uuid.UUID `gorm:"column:owner_id"`
+	OrganizationID uuid.UUID  `gorm:"column:organization_id"`
+	ParentID       *uuid.UUID `gorm:"column:parent_id"`
+	Attributes     JSONObject `gorm:"column:attributes"`
+	Slug           string     `gorm:"column:slug"`
+	// LogoHash is empty for companies without a logo, see CompanyLogo.
+	LogoHash string `gorm:"column:logo_hash"`
+}
+
+// CompanyFilter narrows company listings, zero values are ignored except OrganizationID,
+// which is always applied.
+type CompanyFilter struct {
+	OrganizationID uuid.UUID
+	Type           string
+	Registered     *bool
+	EmployeesMin   *uint64
+	EmployeesMax   *uint64
+	NamePrefix     string
+	OwnerID        *uuid.UUID
+	// ParentID lists the direct subsidiaries of the company.
+	ParentID *uuid.UUID
+	// Attributes matches companies whose attributes contain the given ones.
+	Attributes JSONObject
+	// Trashed switches the listing to soft deleted companies.
+	Trashed bool
+}
+
+type CompanySort struct {
+	Field string
+	Desc  bool
+}
+
+// CompanyCursor points at the boundary row of a keyset page.
+// Values are ordered the same way as the sort fields of the query.
+type CompanyCursor struct {
+	Values   []interface{}
+	ID       uuid.UUID
+	Backward bool
+}
+
+type CompanyListQuery struct {
+	Filter CompanyFilter
+	Sort   []CompanySort
+	Cursor *CompanyCursor
+	Limit  int
+	Offset int
+}
+
+type CompanyPage struct {
+	Companies  []CompanyShow
+	Total      int64
+	NextCursor string
+	PrevCursor string
+}
+
+type CompanySearchQuery struct {
+	Text     string
+	Language string
+	Filter   CompanyFilter
+	Limit    int
+	Offset   int
+}
+
+type CompanySearchResult struct {
+	CompanyShow
+	Rank          float64 `gorm:"column:rank"`
+	NameHighlight string  `gorm:"column:name_highlight"`
+	Snippet       string  `gorm:"column:snippet"`
 }
@@ -0,0 +1,27 @@
+package dmodels
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	CompanyAttachmentsTable = "company_attachments"
+	BlobDeletionsTable      = "blob_deletions"
+)
+
+// CompanyAttachment describes a file of the company kept in the blob storage under StorageKey.
+// ContentType is sniffed from the content, Checksum is the hex encoded SHA-256 of it.
+type CompanyAttachment struct {
+	ID          uuid.UUID     `gorm:"column:id;PRIMARY_KEY"`
+	CompanyID   uuid.UUID     `gorm:"column:company_id"`
+	FileName    string        `gorm:"column:file_name"`
+	ContentType string        `gorm:"column:content_type"`
+	Size        int64         `gorm:"column:size"`
+	Checksum    string        `gorm:"column:checksum"`
+	StorageKey  string        `gorm:"column:storage_key"`
+	AuthorID    uuid.NullUUID `gorm:"column:author_id"`
+	AuthorEmail string        `gorm:"column:author_email;->"`
+	CreatedAt   time.Time     `gorm:"column:created_at;default:now()"`
+}
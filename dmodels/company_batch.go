@@ -0,0 +1,28 @@
+package dmodels
+
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// CompanyBatchOperation is a single change of a batch. Company.ID is required for
+// updates and deletes, a non-zero Company.Version has to match the stored one.
+type CompanyBatchOperation struct {
+	Op      string
+	Company Company
+	// DeletePolicy and Affected are used by deletes, Affected holds the subsidiaries
+	// trashed or re-parented along with the company.
+	DeletePolicy string
+	Affected     []CompanyShow
+}
+
+type CompanyBatchResult struct {
+	Op      string
+	Company Company
+	// Deleted is the state of a deleted company, it is sent with the deleted event.
+	Deleted CompanyShow
+	// Affected are the subsidiaries changed by the delete, see CompanyBatchOperation.
+	Affected []CompanyShow
+	Err      error
+}
@@ -0,0 +1,41 @@
+package dmodels
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	CompanyContactsTable  = "company_contacts"
+	CompanyAddressesTable = "company_addresses"
+)
+
+// CompanyContact is a person of the company, at most one contact of a company is primary.
+type CompanyContact struct {
+	ID        uuid.UUID `gorm:"column:id;PRIMARY_KEY"`
+	CompanyID uuid.UUID `gorm:"column:company_id"`
+	Name      string    `gorm:"column:name"`
+	Email     string    `gorm:"column:email"`
+	Phone     string    `gorm:"column:phone"`
+	Position  string    `gorm:"column:position"`
+	Primary   bool      `gorm:"column:is_primary"`
+	CreatedAt time.Time `gorm:"column:created_at;default:now()"`
+	UpdatedAt time.Time `gorm:"column:updated_at;default:now()"`
+}
+
+// CompanyAddress is a postal address of the company, at most one address of a company is primary.
+// Country is an ISO 3166-1 alpha-2 code.
+type CompanyAddress struct {
+	ID         uuid.UUID `gorm:"column:id;PRIMARY_KEY"`
+	CompanyID  uuid.UUID `gorm:"column:company_id"`
+	Line1      string    `gorm:"column:line1"`
+	Line2      string    `gorm:"column:line2"`
+	City       string    `gorm:"column:city"`
+	Region     string    `gorm:"column:region"`
+	PostalCode string    `gorm:"column:postal_code"`
+	Country    string    `gorm:"column:country"`
+	Primary    bool      `gorm:"column:is_primary"`
+	CreatedAt  time.Time `gorm:"column:created_at;default:now()"`
+	UpdatedAt  time.Time `gorm:"column:updated_at;default:now()"`
+}
@@ -0,0 +1,29 @@
+package dmodels
+
+import uuid "github.com/satori/go.uuid"
+
+// SimilarCompany is a live company whose name resembles the given one, Similarity is between 0 and 1.
+type SimilarCompany struct {
+	ID         uuid.UUID `gorm:"column:id"`
+	Name       string    `gorm:"column:name"`
+	Slug       string    `gorm:"column:slug"`
+	Similarity float64   `gorm:"column:similarity"`
+}
+
+// CompanyDuplicatePair is a pair of live companies with similar names, the first one was created earlier.
+type CompanyDuplicatePair struct {
+	FirstID    uuid.UUID `gorm:"column:first_id"`
+	FirstName  string    `gorm:"column:first_name"`
+	FirstSlug  string    `gorm:"column:first_slug"`
+	SecondID   uuid.UUID `gorm:"column:second_id"`
+	SecondName string    `gorm:"column:second_name"`
+	SecondSlug string    `gorm:"column:second_slug"`
+	Similarity float64   `gorm:"column:similarity"`
+}
+
+// CompanyDuplicateCluster groups companies linked by similar names, directly or through each other.
+// Similarity is the highest one between two companies of the cluster.
+type CompanyDuplicateCluster struct {
+	Companies  []SimilarCompany
+	Similarity float64
+}
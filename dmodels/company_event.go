@@ -0,0 +1,22 @@
+package dmodels
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const CompanyEventsTable = "company_events"
+
+// CompanyEvent is a change of a company pushed to the live feed, Event is one of the webhook events.
+// The ids only grow, so clients resume the feed from the last one they saw.
+type CompanyEvent struct {
+	ID             int64     `gorm:"column:id;PRIMARY_KEY"`
+	OrganizationID uuid.UUID `gorm:"column:organization_id"`
+	CompanyID      uuid.UUID `gorm:"column:company_id"`
+	// Type is the name of the company type at the time of the event.
+	Type      string    `gorm:"column:type;->"`
+	Event     string    `gorm:"column:event"`
+	Version   uint64    `gorm:"column:version"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
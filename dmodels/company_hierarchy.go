@@ -0,0 +1,25 @@
+package dmodels
+
+// Delete policies decide what happens to the subsidiaries of a deleted company.
+const (
+	// DeletePolicyRestrict refuses to delete companies with live subsidiaries.
+	DeletePolicyRestrict = "restrict"
+	// DeletePolicyCascade moves the whole subtree to the trash.
+	DeletePolicyCascade = "cascade"
+	// DeletePolicyReparent hands the direct subsidiaries over to the parent of the deleted company.
+	DeletePolicyReparent = "reparent"
+)
+
+// CompanyNode is a company of a tree query, Depth is its distance from the queried company.
+type CompanyNode struct {
+	CompanyShow
+	Depth int `gorm:"column:depth"`
+}
+
+// CompanyGroup sums up a company along with all of its live subsidiaries.
+type CompanyGroup struct {
+	Companies int64  `gorm:"column:companies"`
+	Employees uint64 `gorm:"column:employees"`
+	// Depth is the number of levels below the company.
+	Depth int `gorm:"column:depth"`
+}
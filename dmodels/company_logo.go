@@ -0,0 +1,41 @@
+package dmodels
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
+)
+
+const CompanyLogosTable = "company_logos"
+
+// CompanyLogo is the logo of the company along with its thumbnails. StorageKeys hold the blob of the
+// original first, followed by the thumbnails in the order of Sizes. Hash is part of the logo URLs.
+type CompanyLogo struct {
+	CompanyID   uuid.UUID      `gorm:"column:company_id;PRIMARY_KEY"`
+	Hash        string         `gorm:"column:hash"`
+	ContentType string         `gorm:"column:content_type"`
+	Width       int            `gorm:"column:width"`
+	Height      int            `gorm:"column:height"`
+	Size        int64          `gorm:"column:size"`
+	Sizes       pq.Int64Array  `gorm:"column:sizes;type:integer[]"`
+	StorageKeys pq.StringArray `gorm:"column:storage_keys;type:text[]"`
+	AuthorID    uuid.NullUUID  `gorm:"column:author_id"`
+	CreatedAt   time.Time      `gorm:"column:created_at;default:now()"`
+}
+
+// StorageKey returns the blob of the thumbnail with the size, zero means the original.
+func (l CompanyLogo) StorageKey(size int) (string, bool) {
+	if size == 0 {
+		if len(l.StorageKeys) == 0 {
+			return "", false
+		}
+		return l.StorageKeys[0], true
+	}
+	for i, s := range l.Sizes {
+		if int(s) == size && i+1 < len(l.StorageKeys) {
+			return l.StorageKeys[i+1], true
+		}
+	}
+	return "", false
+}
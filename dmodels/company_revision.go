@@ -0,0 +1,70 @@
+package dmodels
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const CompanyRevisionsTable = "company_revisions"
+
+const (
+	RevisionActionCreate  = "create"
+	RevisionActionUpdate  = "update"
+	RevisionActionDelete  = "delete"
+	RevisionActionRestore = "restore"
+	RevisionActionRevert  = "revert"
+	// RevisionActionTransfer changes the owner only, see Company.OwnerID.
+	RevisionActionTransfer = "transfer"
+	// RevisionActionReparent changes the parent only, see Company.ParentID.
+	RevisionActionReparent = "reparent"
+)
+
+type CompanyRevision struct {
+	ID           uint64          `gorm:"column:id;PRIMARY_KEY"`
+	CompanyID    uuid.UUID       `gorm:"column:company_id"`
+	Revision     uint64          `gorm:"column:revision"`
+	Action       string          `gorm:"column:action"`
+	AuthorID     uuid.NullUUID   `gorm:"column:author_id"`
+	AuthorEmail  string          `gorm:"column:author_email;->"`
+	RevertedFrom *uint64         `gorm:"column:reverted_from"`
+	Snapshot     CompanySnapshot `gorm:"column:snapshot;type:jsonb"`
+	CreatedAt    time.Time       `gorm:"column:created_at;default:now()"`
+}
+
+// CompanySnapshot is a full copy of a company row stored with every revision.
+type CompanySnapshot struct {
+	Name        string     `gorm:"column:name"        json:"name"`
+	Description string     `gorm:"column:description" json:"description"`
+	Employees   uint64     `gorm:"column:employees"   json:"employees"`
+	Registered  bool       `gorm:"column:registered"  json:"registered"`
+	TypeID      uint64     `gorm:"column:type_id"     json:"type_id"`
+	Type        string     `gorm:"column:type"        json:"type"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at"  json:"updated_at"`
+	DeletedAt   *time.Time `gorm:"column:deleted_at"  json:"deleted_at,omitempty"`
+	Version     uint64     `gorm:"column:version"     json:"version"`
+	OwnerID     *uuid.UUID `gorm:"column:owner_id"    json:"owner_id,omitempty"`
+	ParentID    *uuid.UUID `gorm:"column:parent_id"   json:"parent_id,omitempty"`
+	Attributes  JSONObject `gorm:"column:attributes"  json:"attributes,omitempty"`
+}
+
+func (s CompanySnapshot) Value() (driver.Value, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (s *CompanySnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	}
+	return fmt.Errorf("cannot scan %T into CompanySnapshot", value)
+}
@@ -0,0 +1,36 @@
+package dmodels
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
+)
+
+const CompanySchedulesTable = "company_schedules"
+
+const (
+	ScheduleStatusPending   = "pending"
+	ScheduleStatusApplied   = "applied"
+	ScheduleStatusCancelled = "cancelled"
+	// ScheduleStatusFailed is set when the change cannot be applied, e.g. it is no longer valid.
+	ScheduleStatusFailed = "failed"
+)
+
+// CompanySchedule is a change of the company which takes effect at EffectiveAt.
+// Changes is a JSON Merge Patch, Fields are the paths it touches, e.g. "name" or "attributes.size".
+type CompanySchedule struct {
+	ID             uuid.UUID      `gorm:"column:id;PRIMARY_KEY"`
+	OrganizationID uuid.UUID      `gorm:"column:organization_id"`
+	CompanyID      uuid.UUID      `gorm:"column:company_id"`
+	Changes        JSONObject     `gorm:"column:changes"`
+	Fields         pq.StringArray `gorm:"column:fields;type:text[]"`
+	EffectiveAt    time.Time      `gorm:"column:effective_at"`
+	Status         string         `gorm:"column:status;default:pending"`
+	Error          string         `gorm:"column:error"`
+	AuthorID       uuid.NullUUID  `gorm:"column:author_id"`
+	AuthorEmail    string         `gorm:"column:author_email;->"`
+	ClaimedAt      *time.Time     `gorm:"column:claimed_at"`
+	FinishedAt     *time.Time     `gorm:"column:finished_at"`
+	CreatedAt      time.Time      `gorm:"column:created_at;default:now()"`
+}
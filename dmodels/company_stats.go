@@ -0,0 +1,62 @@
+package dmodels
+
+import "time"
+
+// EmployeeBucket is a range of company sizes, a nil Max leaves the range open.
+type EmployeeBucket struct {
+	Name string
+	Min  uint64
+	Max  *uint64
+}
+
+func maxEmployees(n uint64) *uint64 {
+	return &n
+}
+
+// EmployeeBuckets split companies by size, they follow each other without gaps.
+var EmployeeBuckets = []EmployeeBucket{
+	{Name: "0-9", Min: 0, Max: maxEmployees(9)},
+	{Name: "10-49", Min: 10, Max: maxEmployees(49)},
+	{Name: "50-249", Min: 50, Max: maxEmployees(249)},
+	{Name: "250-999", Min: 250, Max: maxEmployees(999)},
+	{Name: "1000+", Min: 1000},
+}
+
+// CompanyStatsQuery aggregates the companies matched by the filter. The windows count companies
+// created and deleted since the given moments.
+type CompanyStatsQuery struct {
+	Filter  CompanyFilter
+	Windows []time.Time
+}
+
+type CompanyStats struct {
+	Total        int64
+	Registered   int64
+	Unregistered int64
+	// ByType is ordered by count, types without companies are left out.
+	ByType []CompanyTypeCount
+	// ByEmployees follows EmployeeBuckets, empty buckets included.
+	ByEmployees []CompanyBucketCount
+	// Windows follow the windows of the query.
+	Windows []CompanyWindowCount
+	// ComputedAt is set by services, the windows end there.
+	ComputedAt time.Time
+}
+
+type CompanyTypeCount struct {
+	Type  string `gorm:"column:type"`
+	Count int64  `gorm:"column:count"`
+}
+
+type CompanyBucketCount struct {
+	Bucket string `gorm:"column:bucket"`
+	Count  int64  `gorm:"column:count"`
+}
+
+// CompanyWindowCount counts the companies created and the ones moved to the trash since the moment.
+// Purged companies are not counted anymore.
+type CompanyWindowCount struct {
+	Since   time.Time
+	Created int64
+	Deleted int64
+}
@@ -1,8 +1,21 @@
 package dmodels
 
+import "time"
+
 const CompanyTypesTable = "company_types"
 
 type CompanyType struct {
-	ID   uint64 `gorm:"column:id;PRIMARY_KEY"`
-	Name string `gorm:"column:name"`
+	ID           uint64     `gorm:"column:id;PRIMARY_KEY"`
+	Name         string     `gorm:"column:name"`
+	DeprecatedAt *time.Time `gorm:"column:deprecated_at"`
+	// AttributesSchema is a JSON Schema for the attributes of companies of the type,
+	// types without one accept no attributes.
+	AttributesSchema JSONObject `gorm:"column:attributes_schema"`
+	// Companies is the number of live companies of the type, it is filled by listings only.
+	Companies int64 `gorm:"column:companies;->"`
+}
+
+// Deprecated types stay on existing companies but cannot be assigned anymore.
+func (ct CompanyType) Deprecated() bool {
+	return ct.DeprecatedAt != nil
 }
@@ -0,0 +1,29 @@
+package dmodels
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const IdempotencyKeysTable = "idempotency_keys"
+
+// IdempotencyKey remembers the response to a mutating request sent with an Idempotency-Key header.
+// Keys are scoped by the user, StatusCode is nil while the first request is still being handled.
+type IdempotencyKey struct {
+	UserID uuid.UUID `gorm:"column:user_id;PRIMARY_KEY"`
+	Key    string    `gorm:"column:key;PRIMARY_KEY"`
+	// Fingerprint is a hash of the request, the key cannot be reused for another request.
+	Fingerprint string     `gorm:"column:fingerprint"`
+	StatusCode  *int       `gorm:"column:status_code"`
+	Headers     JSONObject `gorm:"column:headers"`
+	Body        []byte     `gorm:"column:body"`
+	LockedAt    time.Time  `gorm:"column:locked_at"`
+	ExpiresAt   time.Time  `gorm:"column:expires_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at"`
+}
+
+// Completed tells whether the response is stored and can be replayed.
+func (k IdempotencyKey) Completed() bool {
+	return k.StatusCode != nil
+}
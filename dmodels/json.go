@@ -0,0 +1,34 @@
+package dmodels
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONObject is stored in a jsonb column, a nil object is stored as null.
+type JSONObject map[string]interface{}
+
+func (o JSONObject) Value() (driver.Value, error) {
+	if o == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (o *JSONObject) Scan(value interface{}) error {
+	*o = nil
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	}
+	return fmt.Errorf("cannot scan %T into JSONObject", value)
+}
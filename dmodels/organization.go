@@ -0,0 +1,31 @@
+package dmodels
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	OrganizationsTable       = "organizations"
+	OrganizationMembersTable = "organization_members"
+)
+
+// DefaultOrganizationID keeps the companies created before organizations were introduced.
+// Public routes show its companies and new users join it.
+var DefaultOrganizationID = uuid.FromStringOrNil("00000000-0000-0000-0000-000000000001")
+
+type Organization struct {
+	ID        uuid.UUID `gorm:"column:id;PRIMARY_KEY"`
+	Name      string    `gorm:"column:name"`
+	CreatedAt time.Time `gorm:"column:created_at;default:now()"`
+	// Role is the role of the member the organization was listed for.
+	Role string `gorm:"column:role;->"`
+}
+
+type OrganizationMember struct {
+	OrganizationID uuid.UUID `gorm:"column:organization_id;PRIMARY_KEY"`
+	UserID         uuid.UUID `gorm:"column:user_id;PRIMARY_KEY"`
+	Role           string    `gorm:"column:role"`
+	CreatedAt      time.Time `gorm:"column:created_at;default:now()"`
+}
@@ -8,4 +8,8 @@ type User struct {
 	ID       uuid.UUID `gorm:"column:id;PRIMARY_KEY"`
 	Email    string    `gorm:"column:email"`
 	Password string    `gorm:"column:password"`
+	// OrganizationID and Role describe the membership the user acts within,
+	// they are filled by member lookups only.
+	OrganizationID uuid.UUID `gorm:"column:organization_id;->"`
+	Role           string    `gorm:"column:role;->"`
 }
@@ -0,0 +1,69 @@
+package dmodels
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	WebhooksTable          = "webhooks"
+	WebhookDeliveriesTable = "webhook_deliveries"
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	// DeliveryStatusFailed is set once the attempts run out.
+	DeliveryStatusFailed = "failed"
+)
+
+// Webhook is an endpoint of the organization the company events listed in Events are delivered to.
+// Failures counts the consecutive failed attempts, the webhook is disabled once they reach the limit.
+type Webhook struct {
+	ID             uuid.UUID      `gorm:"column:id;PRIMARY_KEY"`
+	OrganizationID uuid.UUID      `gorm:"column:organization_id"`
+	URL            string         `gorm:"column:url"`
+	Events         pq.StringArray `gorm:"column:events;type:text[]"`
+	Secret         string         `gorm:"column:secret"`
+	Enabled        bool           `gorm:"column:enabled"`
+	Failures       int            `gorm:"column:failures"`
+	DisabledAt     *time.Time     `gorm:"column:disabled_at"`
+	AuthorID       uuid.NullUUID  `gorm:"column:author_id"`
+	CreatedAt      time.Time      `gorm:"column:created_at;default:now()"`
+	UpdatedAt      time.Time      `gorm:"column:updated_at;default:now()"`
+}
+
+// WebhookDelivery is an event queued for a webhook along with the outcome of its last attempt.
+// URL and Secret are the ones of the webhook, they are only loaded for the deliveries being sent.
+type WebhookDelivery struct {
+	ID             uuid.UUID  `gorm:"column:id;PRIMARY_KEY"`
+	WebhookID      uuid.UUID  `gorm:"column:webhook_id"`
+	OrganizationID uuid.UUID  `gorm:"column:organization_id"`
+	Event          string     `gorm:"column:event"`
+	EventID        uuid.UUID  `gorm:"column:event_id"`
+	Payload        JSONObject `gorm:"column:payload"`
+	Status         string     `gorm:"column:status;default:pending"`
+	Attempts       int        `gorm:"column:attempts"`
+	NextAttemptAt  time.Time  `gorm:"column:next_attempt_at;default:now()"`
+	ClaimedAt      *time.Time `gorm:"column:claimed_at"`
+	ResponseStatus int        `gorm:"column:response_status"`
+	ResponseBody   string     `gorm:"column:response_body"`
+	Error          string     `gorm:"column:error"`
+	AttemptedAt    *time.Time `gorm:"column:attempted_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at;default:now()"`
+	URL            string     `gorm:"column:url;->"`
+	Secret         string     `gorm:"column:secret;->"`
+}
+
+// WebhookAttempt is the outcome of a delivery attempt, Status is the one the delivery gets.
+type WebhookAttempt struct {
+	DeliveryID     uuid.UUID
+	WebhookID      uuid.UUID
+	Status         string
+	NextAttemptAt  time.Time
+	ResponseStatus int
+	ResponseBody   string
+	Error          string
+}
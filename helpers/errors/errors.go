@@ -1,8 +1,65 @@
 package errors
 
+import "errors"
+
 const (
 	BadRequest      = "bad_request"
 	ServiceError    = "service_error"
 	UnavailableErr  = "unavailable"
 	UnauthorizedErr = "unauthorized"
+	Forbidden       = "forbidden"
+	NotFound        = "not_found"
+	Conflict        = "conflict"
+
+	PreconditionFailed   = "precondition_failed"
+	PreconditionRequired = "precondition_required"
+	Unprocessable        = "unprocessable"
+	UnsupportedMediaType = "unsupported_media_type"
+	FailedDependency     = "failed_dependency"
+	RequestTooLarge      = "request_too_large"
+	NotAcceptable        = "not_acceptable"
+)
+
+// Reasons accompany Forbidden and Conflict so that clients can tell denials apart.
+const (
+	ReasonMissingPermission = "missing_permission"
+	ReasonNotOwner          = "not_owner"
+	ReasonNotMember         = "not_member"
+	// ReasonOrganizationRequired is given when the route is limited to another organization.
+	ReasonOrganizationRequired = "organization_required"
+	// ReasonHasSubsidiaries is given when a company with subsidiaries cannot be deleted.
+	ReasonHasSubsidiaries = "has_subsidiaries"
+	// ReasonRequestInProgress is given while a request with the same Idempotency-Key is being handled.
+	ReasonRequestInProgress = "request_in_progress"
+	// ReasonIdempotencyKeyReused is given when an Idempotency-Key is sent along with another request.
+	ReasonIdempotencyKeyReused = "idempotency_key_reused"
+	// ReasonPossibleDuplicates is given when companies with similar names exist, see DuplicatesError.
+	ReasonPossibleDuplicates = "possible_duplicates"
+	// ReasonScheduleConflict is given when pending changes of the company touch the same fields.
+	ReasonScheduleConflict = "schedule_conflict"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrNotFound      = errors.New(NotFound)
+	ErrConflict      = errors.New(Conflict)
+	ErrForbidden     = errors.New(Forbidden)
+	ErrNotMember     = errors.New(ReasonNotMember)
+	// ErrHasSubsidiaries is returned by deletes restricted by the delete policy.
+	ErrHasSubsidiaries = errors.New(ReasonHasSubsidiaries)
+	// ErrRequestInProgress and ErrIdempotencyKeyReused reject retries which cannot be replayed.
+	ErrRequestInProgress    = errors.New(ReasonRequestInProgress)
+	ErrIdempotencyKeyReused = errors.New(ReasonIdempotencyKeyReused)
+	ErrPossibleDuplicates   = errors.New(ReasonPossibleDuplicates)
+	ErrScheduleConflict     = errors.New(ReasonScheduleConflict)
+
+	ErrPreconditionFailed   = errors.New(PreconditionFailed)
+	ErrValidation           = errors.New("validation failed")
+	ErrUnprocessable        = errors.New(Unprocessable)
+	ErrUnsupportedMediaType = errors.New(UnsupportedMediaType)
+	ErrRequestTooLarge      = errors.New(RequestTooLarge)
+	// ErrFailedDependency marks operations rolled back because another operation of the batch failed.
+	ErrFailedDependency = errors.New(FailedDependency)
+	// ErrUnavailable is returned while the instance cannot serve the request, clients retry later.
+	ErrUnavailable = errors.New(UnavailableErr)
 )
@@ -0,0 +1,48 @@
+package rbac
+
+// Roles are stored per user and embedded into access tokens.
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+
+	// DefaultRole is given to users on registration.
+	DefaultRole = RoleEditor
+)
+
+type Permission string
+
+const (
+	// CompaniesRead allows the protected listings, exports and revision history.
+	CompaniesRead Permission = "companies:read"
+	// CompaniesWrite allows to create companies and to change the owned ones.
+	CompaniesWrite Permission = "companies:write"
+	// CompaniesManage allows to change companies of other owners.
+	CompaniesManage Permission = "companies:manage"
+
+	CompanyTypesManage Permission = "company_types:manage"
+	UsersManage        Permission = "users:manage"
+	// WebhooksManage allows to manage the webhooks of the organization and to inspect their deliveries.
+	WebhooksManage Permission = "webhooks:manage"
+)
+
+var permissions = map[string][]Permission{
+	RoleViewer: {CompaniesRead},
+	RoleEditor: {CompaniesRead, CompaniesWrite, WebhooksManage},
+	RoleAdmin:  {CompaniesRead, CompaniesWrite, CompaniesManage, CompanyTypesManage, UsersManage, WebhooksManage},
+}
+
+// Can tells whether the role grants the permission, unknown roles grant nothing.
+func Can(role string, permission Permission) bool {
+	for _, p := range permissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidRole(role string) bool {
+	_, ok := permissions[role]
+	return ok
+}
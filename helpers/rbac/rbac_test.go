@@ -0,0 +1,31 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCan(t *testing.T) {
+	all := []Permission{CompaniesRead, CompaniesWrite, CompaniesManage, CompanyTypesManage, UsersManage, WebhooksManage}
+	granted := map[string][]Permission{
+		RoleViewer: {CompaniesRead},
+		RoleEditor: {CompaniesRead, CompaniesWrite, WebhooksManage},
+		RoleAdmin:  all,
+		"owner":    nil,
+		"":         nil,
+	}
+
+	// checks every permission of every role, unknown roles grant nothing
+	t.Run("it should grant the permissions of the role", func(t *testing.T) {
+		for role, permissions := range granted {
+			for _, permission := range all {
+				expected := false
+				for _, p := range permissions {
+					expected = expected || p == permission
+				}
+				assert.Equal(t, expected, Can(role, permission), "%q %s", role, permission)
+			}
+		}
+	})
+}
@@ -14,9 +14,17 @@ import (
 	"xm-task/helpers/modules"
 	"xm-task/log"
 	"xm-task/services"
+	"xm-task/workers"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
 	var configPath string
 	var migrate string
 	flag.StringVar(&configPath, "config", "./config.json", "Path to the config file")
@@ -60,7 +68,14 @@ func main() {
 		log.Fatal("api.NewAPI", zap.Error(err))
 	}
 
-	mds := []modules.Module{a}
+	mds := []modules.Module{
+		a,
+		workers.NewTrashPurger(config, s),
+		workers.NewIdempotencyPurger(config, s),
+		workers.NewScheduleApplier(config, s),
+		workers.NewWebhookDispatcher(config, s),
+		workers.NewFeedListener(config, s),
+	}
 
 	modules.Run(mds)
 
@@ -9,12 +9,32 @@ import (
 	"os"
 	"strings"
 	"time"
+	"xm-task/conf"
+	local "xm-task/helpers/errors"
 	"xm-task/smodels"
 )
 
-func (s *ServiceFacade) CreateToken(email string) (smodels.TokenDetails, error) {
+// streamScope marks the tokens accepted by the live feed streams only.
+const streamScope = "stream"
+
+// CreateToken mints the token pair for the organization, the access token carries it along with
+// the role of the user within it. A nil orgID selects the organization the user joined first.
+// Active tokens are reused unless they were minted for another organization or role.
+func (s *ServiceFacade) CreateToken(email string, orgID uuid.UUID) (smodels.TokenDetails, error) {
 	var td smodels.TokenDetails
 
+	if orgID == uuid.Nil {
+		var err error
+		if orgID, err = s.firstOrganization(email); err != nil {
+			return smodels.TokenDetails{}, err
+		}
+	}
+
+	member, err := s.GetMember(email, orgID)
+	if err != nil {
+		return smodels.TokenDetails{}, err
+	}
+
 	val, _, err := s.dao.GetAuthToken(fmt.Sprintf("%s_td", email))
 	if err != nil {
 		return smodels.TokenDetails{}, err
@@ -22,10 +42,12 @@ func (s *ServiceFacade) CreateToken(email string) (smodels.TokenDetails, error)
 
 	if val != nil {
 		td = val.(smodels.TokenDetails)
-		if td.RtExpires != 0 {
+		if td.RtExpires != 0 && td.OrganizationID == orgID.String() && td.Role == member.Role {
 			return td, nil
 		}
 	}
+	td.OrganizationID = orgID.String()
+	td.Role = member.Role
 
 	td.AtExpires = time.Now().Add(time.Minute * 30).Unix()
 	td.AccessUuid = uuid.NewV4().String()
@@ -37,6 +59,8 @@ func (s *ServiceFacade) CreateToken(email string) (smodels.TokenDetails, error)
 	atClaims["authorized"] = true
 	atClaims["access_uuid"] = td.AccessUuid
 	atClaims["email"] = email
+	atClaims["org"] = td.OrganizationID
+	atClaims["role"] = td.Role
 	atClaims["exp"] = td.AtExpires
 	at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
 	td.AccessToken, err = at.SignedString([]byte(os.Getenv("ACCESS_TOKEN_SECRET")))
@@ -47,6 +71,7 @@ func (s *ServiceFacade) CreateToken(email string) (smodels.TokenDetails, error)
 	rtClaims := jwt.MapClaims{}
 	rtClaims["refresh_uuid"] = td.RefreshUuid
 	rtClaims["email"] = email
+	rtClaims["org"] = td.OrganizationID
 	rtClaims["exp"] = td.RtExpires
 	rt := jwt.NewWithClaims(jwt.SigningMethodHS256, rtClaims)
 	td.RefreshToken, err = rt.SignedString([]byte(os.Getenv("REFRESH_TOKEN_SECRET")))
@@ -57,6 +82,24 @@ func (s *ServiceFacade) CreateToken(email string) (smodels.TokenDetails, error)
 	return td, nil
 }
 
+// firstOrganization returns the organization the user joined first, users without one cannot sign in.
+func (s *ServiceFacade) firstOrganization(email string) (uuid.UUID, error) {
+	user, err := s.dao.GetUserByEmail(email)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("dao.GetUserByEmail: %v", err)
+	}
+
+	orgs, err := s.dao.ListUserOrganizations(user.ID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("dao.ListUserOrganizations: %v", err)
+	}
+	if len(orgs) == 0 {
+		return uuid.Nil, fmt.Errorf("user %s has no organization: %w", email, local.ErrNotMember)
+	}
+
+	return orgs[0].ID, nil
+}
+
 func (s *ServiceFacade) CreateAuth(email string, td smodels.TokenDetails) error {
 	at := time.Unix(td.AtExpires, 0)
 	rt := time.Unix(td.RtExpires, 0)
@@ -90,20 +133,58 @@ func (s *ServiceFacade) ExtractTokenMetadata(c *gin.Context) (smodels.AccessDeta
 	if err != nil {
 		return smodels.AccessDetails{}, err
 	}
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if ok && token.Valid {
-		accessUuid, ok := claims["access_uuid"].(string)
-		if !ok {
-			return smodels.AccessDetails{}, err
-		}
+	ad, scope, err := tokenMetadata(token)
+	if err != nil {
+		return smodels.AccessDetails{}, err
+	}
+	// stream tokens are accepted by the streams only
+	if scope != "" {
+		return smodels.AccessDetails{}, fmt.Errorf("unexpected %s token", scope)
+	}
+	return ad, nil
+}
 
-		email := fmt.Sprintf("%s", claims["email"])
-		return smodels.AccessDetails{
-			AccessUuid: accessUuid,
-			Email:      email,
-		}, nil
+// CreateStreamToken mints a token for the live feed streams on behalf of the access token. EventSource and browser
+// WebSockets cannot send the Authorization header, so it is passed with the URL or a cookie, which is why it expires
+// after Feed.TokenTTL. It is revoked along with the access token.
+func (s *ServiceFacade) CreateStreamToken(ad smodels.AccessDetails) (smodels.StreamToken, error) {
+	ttl := s.cfg.Feed.TokenTTL
+	if ttl <= 0 {
+		ttl = conf.DefaultFeedTokenTTL
+	}
+
+	st := smodels.StreamToken{ExpiresAt: time.Now().Add(ttl).Unix()}
+	claims := jwt.MapClaims{}
+	claims["access_uuid"] = ad.AccessUuid
+	claims["email"] = ad.Email
+	claims["org"] = ad.OrganizationID
+	claims["role"] = ad.Role
+	claims["scope"] = streamScope
+	claims["exp"] = st.ExpiresAt
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	var err error
+	st.Token, err = token.SignedString([]byte(os.Getenv("ACCESS_TOKEN_SECRET")))
+	if err != nil {
+		return smodels.StreamToken{}, err
 	}
-	return smodels.AccessDetails{}, err
+	return st, nil
+}
+
+// ExtractStreamTokenMetadata accepts the tokens minted by CreateStreamToken only.
+func (s *ServiceFacade) ExtractStreamTokenMetadata(tokenString string) (smodels.AccessDetails, error) {
+	token, err := parseToken(tokenString)
+	if err != nil {
+		return smodels.AccessDetails{}, err
+	}
+	ad, scope, err := tokenMetadata(token)
+	if err != nil {
+		return smodels.AccessDetails{}, err
+	}
+	if scope != streamScope {
+		return smodels.AccessDetails{}, fmt.Errorf("not a stream token")
+	}
+	return ad, nil
 }
 
 func (s *ServiceFacade) VerifyToken(r *http.Request) (*jwt.Token, error) {
@@ -111,7 +192,10 @@ func (s *ServiceFacade) VerifyToken(r *http.Request) (*jwt.Token, error) {
 	if tokenString == "" {
 		return nil, fmt.Errorf("no token")
 	}
+	return parseToken(tokenString)
+}
 
+func parseToken(tokenString string) (*jwt.Token, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -124,6 +208,30 @@ func (s *ServiceFacade) VerifyToken(r *http.Request) (*jwt.Token, error) {
 	return token, nil
 }
 
+// tokenMetadata returns the claims of an access or stream token along with its scope, empty for access tokens.
+func tokenMetadata(token *jwt.Token) (smodels.AccessDetails, string, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return smodels.AccessDetails{}, "", fmt.Errorf("invalid token")
+	}
+	accessUuid, ok := claims["access_uuid"].(string)
+	if !ok {
+		return smodels.AccessDetails{}, "", fmt.Errorf("invalid token")
+	}
+
+	email := fmt.Sprintf("%s", claims["email"])
+	// tokens minted before roles and organizations were introduced have no such claims
+	orgID, _ := claims["org"].(string)
+	role, _ := claims["role"].(string)
+	scope, _ := claims["scope"].(string)
+	return smodels.AccessDetails{
+		AccessUuid:     accessUuid,
+		Email:          email,
+		OrganizationID: orgID,
+		Role:           role,
+	}, scope, nil
+}
+
 func (s *ServiceFacade) Refresh(r *http.Request) (smodels.TokenDetails, error) {
 	refreshToken := r.Header.Get("Authorization")
 	parts := strings.Split(refreshToken, " ")
@@ -148,6 +256,7 @@ func (s *ServiceFacade) Refresh(r *http.Request) (smodels.TokenDetails, error) {
 			return smodels.TokenDetails{}, fmt.Errorf("error: %s", "invalid token")
 		}
 		email := fmt.Sprintf("%s", claims["email"])
+		orgID, _ := claims["org"].(string)
 		accessUuid, ok, err := s.dao.GetAuthToken(fmt.Sprintf("%s_access", refreshUuid))
 		if err != nil || !ok {
 			return smodels.TokenDetails{}, fmt.Errorf("error: %s", "cannot get access token: invalid refresh_access token")
@@ -162,7 +271,7 @@ func (s *ServiceFacade) Refresh(r *http.Request) (smodels.TokenDetails, error) {
 			return smodels.TokenDetails{}, fmt.Errorf("error: %s", "invalid token provided")
 		}
 
-		ts, err := s.CreateToken(email)
+		ts, err := s.CreateToken(email, uuid.FromStringOrNil(orgID))
 		if err != nil {
 			return smodels.TokenDetails{}, fmt.Errorf("error: %s", "cannot create token")
 		}
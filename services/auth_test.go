@@ -0,0 +1,62 @@
+package services
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xm-task/smodels"
+)
+
+func TestStreamToken(t *testing.T) {
+	s := &ServiceFacade{}
+	ad := smodels.AccessDetails{
+		AccessUuid:     "c7a3c1d4-3f0e-4d1b-9a53-2b8f4b0d6e10",
+		Email:          "email12@gmail.com",
+		OrganizationID: "00000000-0000-0000-0000-000000000001",
+		Role:           "viewer",
+	}
+
+	// checks that stream tokens carry the access token they were minted for
+	t.Run("it should open the streams", func(t *testing.T) {
+		st, err := s.CreateStreamToken(ad)
+		require.NoError(t, err)
+
+		extracted, err := s.ExtractStreamTokenMetadata(st.Token)
+		require.NoError(t, err)
+		assert.Equal(t, ad, extracted)
+	})
+
+	// checks that stream tokens are not accepted as access tokens
+	t.Run("it should reject stream tokens in the Authorization header", func(t *testing.T) {
+		st, err := s.CreateStreamToken(ad)
+		require.NoError(t, err)
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/auth/companies", nil)
+		c.Request.Header.Set("Authorization", "Bearer "+st.Token)
+		_, err = s.ExtractTokenMetadata(c)
+		assert.Error(t, err)
+	})
+
+	// checks that access tokens are not accepted as stream tokens, since they live longer
+	t.Run("it should reject access tokens", func(t *testing.T) {
+		claims := jwt.MapClaims{
+			"access_uuid": ad.AccessUuid,
+			"email":       ad.Email,
+			"org":         ad.OrganizationID,
+			"role":        ad.Role,
+			"exp":         time.Now().Add(time.Minute).Unix(),
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(os.Getenv("ACCESS_TOKEN_SECRET")))
+		require.NoError(t, err)
+
+		_, err = s.ExtractStreamTokenMetadata(token)
+		assert.Error(t, err)
+	})
+}
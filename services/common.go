@@ -1,5 +1,36 @@
 package services
 
+import (
+	"errors"
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dao/blob"
+	local "xm-task/helpers/errors"
+)
+
 func (s *ServiceFacade) CheckDBStatus() bool {
 	return s.dao.CheckDBStatus()
 }
+
+// daoError wraps a dao error, translating the ones the API reports with a specific status.
+func daoError(method string, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, blob.ErrNotFound):
+		return fmt.Errorf("%s: %w", method, local.ErrNotFound)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%s: %w", method, local.ErrConflict)
+	case errors.Is(err, local.ErrPreconditionFailed), errors.Is(err, local.ErrHasSubsidiaries),
+		errors.Is(err, local.ErrUnprocessable), errors.Is(err, local.ErrConflict):
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return fmt.Errorf("%s: %v", method, err)
+}
+
+func uuidString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
@@ -1,115 +1,327 @@
 package services
 
 import (
-	"encoding/json"
 	"fmt"
-	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	uuid "github.com/satori/go.uuid"
-	"log"
+	"strings"
 	"time"
+	"xm-task/conf"
+	"xm-task/dao"
 	"xm-task/dmodels"
 	"xm-task/smodels"
 )
 
-func (s *ServiceFacade) CreateCompany(company smodels.Company) (dmodels.Company, error) {
-	ct, err := s.dao.GetCompanyTypeByName(company.Type)
+// CreateCompany creates the company unless live companies with similar names exist, which is reported
+// with DuplicatesError. allowDuplicates skips the check once the user has confirmed the creation.
+func (s *ServiceFacade) CreateCompany(company smodels.Company, allowDuplicates bool, user dmodels.User) (dmodels.CompanyShow, error) {
+	ct, err := s.companyType(company.Type, "")
 	if err != nil {
-		return dmodels.Company{}, fmt.Errorf("dao.GetCompanyTypeByName: %v", err)
+		return dmodels.CompanyShow{}, err
 	}
-
-	createdCompany, err := s.dao.CreateCompany(dmodels.Company{
-		ID:          uuid.NewV4(),
-		Name:        company.Name,
-		Description: company.Description,
-		Employees:   company.Employees,
-		Registered:  company.Registered,
-		TypeID:      ct.ID,
-	})
-	if err != nil {
-		return dmodels.Company{}, fmt.Errorf("dao.CreateCompany: %v", err)
+	if err := s.validateAttributes(ct, company.Attributes); err != nil {
+		return dmodels.CompanyShow{}, err
 	}
+	if !allowDuplicates {
+		if err := s.checkDuplicates(company.Name, user.OrganizationID); err != nil {
+			return dmodels.CompanyShow{}, err
+		}
+	}
+
+	var created dmodels.CompanyShow
+	err = s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+		createdCompany, err := tx.CreateCompany(dmodels.Company{
+			ID:             uuid.NewV4(),
+			Name:           company.Name,
+			Description:    company.Description,
+			Employees:      company.Employees,
+			Registered:     company.Registered,
+			TypeID:         ct.ID,
+			Attributes:     company.Attributes,
+			Language:       s.searchLanguage(),
+			CreatedBy:      ownerOf(user),
+			OwnerID:        ownerOf(user),
+			OrganizationID: user.OrganizationID,
+		}, user.ID)
+		if err != nil {
+			return daoError("dao.CreateCompany", err)
+		}
+		events.add(createdCompaniesTopic, createdCompany)
 
-	topic := "created-companies"
-	message, _ := json.Marshal(createdCompany)
-	err = s.kafka.Produce(&kafka.Message{
-		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
-		Value:          message,
-	}, nil)
+		created, err = tx.GetCompanyByID(createdCompany.ID.String(), user.OrganizationID)
+		if err != nil {
+			return daoError("dao.GetCompanyByID", err)
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf("Failed to produce message: %s\n", err)
+		return dmodels.CompanyShow{}, err
 	}
 
 	go s.kafka.Flush(200)
 
-	return createdCompany, nil
+	return created, nil
 }
 
-func (s *ServiceFacade) UpdateCompany(company smodels.Company) (dmodels.Company, error) {
+// UpdateCompany overwrites the company, a non-zero version has to match the stored one.
+func (s *ServiceFacade) UpdateCompany(company smodels.Company, version uint64, user dmodels.User) (dmodels.Company, error) {
 	cUUID, err := uuid.FromString(company.ID)
 	if err != nil {
 		return dmodels.Company{}, fmt.Errorf("uuid.FromString: %v", err)
 	}
 
-	ct, err := s.dao.GetCompanyTypeByName(company.Type)
+	current, err := s.dao.GetCompanyByID(company.ID, user.OrganizationID)
 	if err != nil {
-		return dmodels.Company{}, fmt.Errorf("dao.GetCompanyTypeByName: %v", err)
+		return dmodels.Company{}, daoError("dao.GetCompanyByID", err)
+	}
+	if err := canModify(current.OwnerID, user); err != nil {
+		return dmodels.Company{}, err
 	}
 
-	updatedCompany, err := s.dao.UpdateCompany(dmodels.Company{
-		ID:          cUUID,
-		Name:        company.Name,
-		Description: company.Description,
-		Employees:   company.Employees,
-		Registered:  company.Registered,
-		TypeID:      ct.ID,
-		UpdatedAt:   time.Now(),
-	})
+	ct, err := s.companyType(company.Type, current.Type)
 	if err != nil {
-		return dmodels.Company{}, fmt.Errorf("dao.UpdateCompany: %v", err)
+		return dmodels.Company{}, err
+	}
+	if err := s.validateAttributes(ct, company.Attributes); err != nil {
+		return dmodels.Company{}, err
 	}
 
-	topic := "updated-companies"
-	message, _ := json.Marshal(updatedCompany)
-	err = s.kafka.Produce(&kafka.Message{
-		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
-		Value:          message,
-	}, nil)
+	var updatedCompany dmodels.Company
+	err = s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+		updatedCompany, err = tx.UpdateCompany(dmodels.Company{
+			ID:             cUUID,
+			Name:           company.Name,
+			Description:    company.Description,
+			Employees:      company.Employees,
+			Registered:     company.Registered,
+			TypeID:         ct.ID,
+			Attributes:     company.Attributes,
+			UpdatedAt:      time.Now(),
+			Version:        version,
+			OrganizationID: user.OrganizationID,
+		}, user.ID)
+		if err != nil {
+			return daoError("dao.UpdateCompany", err)
+		}
+		events.add(updatedCompaniesTopic, updatedCompany)
+		return nil
+	})
 	if err != nil {
-		log.Printf("Failed to produce message: %s\n", err)
+		return dmodels.Company{}, err
 	}
 
 	return updatedCompany, nil
 }
 
-func (s *ServiceFacade) GetCompanyByID(id string) (dmodels.CompanyShow, error) {
-	company, err := s.dao.GetCompanyByID(id)
+func (s *ServiceFacade) GetCompanyByID(id string, orgID uuid.UUID) (dmodels.CompanyShow, error) {
+	company, err := s.dao.GetCompanyByID(id, orgID)
 	if err != nil {
-		return dmodels.CompanyShow{}, fmt.Errorf("dao.GetCompanyByID: %v", err)
+		return dmodels.CompanyShow{}, daoError("dao.GetCompanyByID", err)
 	}
 
 	return company, nil
 }
 
-func (s *ServiceFacade) DeleteCompanyByID(id string) error {
-	company, err := s.dao.GetCompanyByID(id)
+// GetCompanyBySlug returns the company with the current or a former slug, the company has the current one.
+func (s *ServiceFacade) GetCompanyBySlug(slug string, orgID uuid.UUID) (dmodels.CompanyShow, error) {
+	company, err := s.dao.GetCompanyBySlug(strings.ToLower(slug), orgID)
+	if err != nil {
+		return dmodels.CompanyShow{}, daoError("dao.GetCompanyBySlug", err)
+	}
+
+	return company, nil
+}
+
+// DeleteCompanyByID moves the company to the trash, its subsidiaries are handled by the configured delete policy.
+func (s *ServiceFacade) DeleteCompanyByID(id string, version uint64, user dmodels.User) error {
+	company, err := s.dao.GetCompanyByID(id, user.OrganizationID)
+	if err != nil {
+		return daoError("dao.GetCompanyByID", err)
+	}
+	if err := canModify(company.OwnerID, user); err != nil {
+		return err
+	}
+
+	policy := s.deletePolicy()
+	return s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+		// the subsidiaries are checked under the lock of the delete, so that none can be moved under the company meanwhile
+		if err := tx.LockCompanyHierarchy(user.OrganizationID); err != nil {
+			return fmt.Errorf("dao.LockCompanyHierarchy: %v", err)
+		}
+		if err := canModifySubsidiaries(tx, id, policy, user); err != nil {
+			return err
+		}
+
+		affected, err := tx.DeleteCompanyByID(id, user.OrganizationID, version, policy, user.ID)
+		if err != nil {
+			return daoError("dao.DeleteCompanyByID", err)
+		}
+		events.add(deletedCompaniesTopic, company)
+		subsidiaryEvents(affected, events)
+		return nil
+	})
+}
+
+func (s *ServiceFacade) RestoreCompanyByID(id string, user dmodels.User) (dmodels.CompanyShow, error) {
+	trashed, err := s.dao.GetTrashedCompanyByID(id, user.OrganizationID)
 	if err != nil {
-		return fmt.Errorf("dao.GetCompanyByID: %v", err)
+		return dmodels.CompanyShow{}, daoError("dao.GetTrashedCompanyByID", err)
 	}
+	if err := canModify(trashed.OwnerID, user); err != nil {
+		return dmodels.CompanyShow{}, err
+	}
+
+	var company dmodels.CompanyShow
+	err = s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+		restored, err := tx.RestoreCompanyByID(id, user.OrganizationID, user.ID)
+		if err != nil {
+			return daoError("dao.RestoreCompanyByID", err)
+		}
 
-	err = s.dao.DeleteCompanyByID(id)
+		company, err = tx.GetCompanyByID(id, user.OrganizationID)
+		if err != nil {
+			return daoError("dao.GetCompanyByID", err)
+		}
+		events.add(restoredCompaniesTopic, company)
+		for _, subsidiary := range restored {
+			events.add(restoredCompaniesTopic, subsidiary)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("dao.DeleteCompanyByID: %v", err)
+		return dmodels.CompanyShow{}, err
 	}
 
-	topic := "deleted-companies"
-	message, _ := json.Marshal(company)
-	err = s.kafka.Produce(&kafka.Message{
-		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
-		Value:          message,
-	}, nil)
+	return company, nil
+}
+
+// PurgeTrash permanently removes companies kept in the trash longer than the retention period.
+func (s *ServiceFacade) PurgeTrash() (int64, error) {
+	retention := s.cfg.Trash.Retention
+	if retention <= 0 {
+		retention = conf.DefaultTrashRetention
+	}
+
+	purged, err := s.dao.PurgeCompanies(time.Now().Add(-retention))
 	if err != nil {
-		log.Printf("Failed to produce message: %s\n", err)
+		return 0, fmt.Errorf("dao.PurgeCompanies: %v", err)
+	}
+
+	// attachments of the purged companies leave their blobs behind
+	if _, err := s.purgeBlobs(); err != nil {
+		return purged, err
+	}
+
+	return purged, nil
+}
+
+func (s *ServiceFacade) ListCompanies(params smodels.CompanyListParams) (dmodels.CompanyPage, error) {
+	sorts := parseCompanySort(params.SortFields())
+	query := dmodels.CompanyListQuery{
+		Filter: companyFilter(params.CompanyFilterParams),
+		Sort:   sorts,
+		Limit:  params.Limit + 1,
 	}
 
-	return nil
+	if params.Offset != nil {
+		query.Offset = *params.Offset
+	} else if params.Cursor != "" {
+		cursor, err := decodeCursor(sorts, params.Cursor)
+		if err != nil {
+			return dmodels.CompanyPage{}, err
+		}
+		query.Cursor = cursor
+	}
+
+	companies, err := s.dao.ListCompanies(query)
+	if err != nil {
+		return dmodels.CompanyPage{}, fmt.Errorf("dao.ListCompanies: %v", err)
+	}
+
+	total, err := s.dao.CountCompanies(query.Filter)
+	if err != nil {
+		return dmodels.CompanyPage{}, fmt.Errorf("dao.CountCompanies: %v", err)
+	}
+
+	hasMore := len(companies) > params.Limit
+	if hasMore {
+		companies = companies[:params.Limit]
+	}
+
+	backward := query.Cursor != nil && query.Cursor.Backward
+	if backward {
+		for i, j := 0, len(companies)-1; i < j; i, j = i+1, j-1 {
+			companies[i], companies[j] = companies[j], companies[i]
+		}
+	}
+
+	page := dmodels.CompanyPage{
+		Companies: companies,
+		Total:     total,
+	}
+	if len(companies) == 0 {
+		return page, nil
+	}
+
+	hasNext, hasPrev := hasMore, query.Cursor != nil
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+	if hasNext {
+		page.NextCursor = encodeCursor(sorts, companies[len(companies)-1], false)
+	}
+	if hasPrev {
+		page.PrevCursor = encodeCursor(sorts, companies[0], true)
+	}
+
+	return page, nil
+}
+
+func (s *ServiceFacade) SearchCompanies(params smodels.CompanySearchParams) ([]dmodels.CompanySearchResult, int64, error) {
+	query := dmodels.CompanySearchQuery{
+		Text:     params.Query,
+		Language: s.searchLanguage(),
+		Filter:   companyFilter(params.CompanyFilterParams),
+		Limit:    params.Limit,
+		Offset:   params.Offset,
+	}
+	results, err := s.dao.SearchCompanies(query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("dao.SearchCompanies: %v", err)
+	}
+
+	total, err := s.dao.CountSearchCompanies(query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("dao.CountSearchCompanies: %v", err)
+	}
+
+	return results, total, nil
+}
+
+func (s *ServiceFacade) searchLanguage() string {
+	if s.cfg.Search.Language == "" {
+		return conf.DefaultSearchLanguage
+	}
+	return s.cfg.Search.Language
+}
+
+func companyFilter(params smodels.CompanyFilterParams) dmodels.CompanyFilter {
+	filter := dmodels.CompanyFilter{
+		OrganizationID: uuid.FromStringOrNil(params.OrganizationID),
+		Type:           params.Type,
+		Registered:     params.Registered,
+		EmployeesMin:   params.EmployeesMin,
+		EmployeesMax:   params.EmployeesMax,
+		NamePrefix:     params.NamePrefix,
+		Trashed:        params.Trashed,
+	}
+	if attributes, err := params.AttributesFilter(); err == nil {
+		filter.Attributes = attributes
+	}
+	if ownerID, err := uuid.FromString(params.OwnerID); err == nil {
+		filter.OwnerID = &ownerID
+	}
+	if parentID, err := uuid.FromString(params.ParentID); err == nil {
+		filter.ParentID = &parentID
+	}
+	return filter
 }
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	uuid "github.com/satori/go.uuid"
+	"xm-task/conf"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+// sniffLength is how much of the content http.DetectContentType looks at.
+const sniffLength = 512
+
+// blobDeletionsBatch limits the queued blobs removed at once, see purgeBlobs.
+const blobDeletionsBatch = 500
+
+func (s *ServiceFacade) ListCompanyAttachments(companyID string, orgID uuid.UUID) ([]dmodels.CompanyAttachment, error) {
+	if _, err := s.dao.GetCompanyByID(companyID, orgID); err != nil {
+		return nil, daoError("dao.GetCompanyByID", err)
+	}
+
+	attachments, err := s.dao.ListCompanyAttachments(companyID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("dao.ListCompanyAttachments: %v", err)
+	}
+
+	return attachments, nil
+}
+
+func (s *ServiceFacade) GetCompanyAttachment(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAttachment, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return dmodels.CompanyAttachment{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	attachment, err := s.dao.GetCompanyAttachment(companyID, id, orgID)
+	if err != nil {
+		return dmodels.CompanyAttachment{}, daoError("dao.GetCompanyAttachment", err)
+	}
+
+	return attachment, nil
+}
+
+// OpenCompanyAttachment returns the attachment along with its content, the caller closes the content.
+func (s *ServiceFacade) OpenCompanyAttachment(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAttachment, io.ReadCloser, error) {
+	attachment, err := s.GetCompanyAttachment(companyID, id, orgID)
+	if err != nil {
+		return dmodels.CompanyAttachment{}, nil, err
+	}
+
+	content, err := s.dao.GetBlob(attachment.StorageKey)
+	if err != nil {
+		return dmodels.CompanyAttachment{}, nil, daoError("dao.GetBlob", err)
+	}
+
+	return attachment, content, nil
+}
+
+// UploadCompanyAttachment stores the file in the blob storage and records it. The content type is sniffed
+// from the content and has to be one of Attachments.AllowedTypes, the declared one is not trusted.
+func (s *ServiceFacade) UploadCompanyAttachment(companyID, fileName string, file io.ReadSeeker, user dmodels.User) (dmodels.CompanyAttachment, error) {
+	company, err := s.modifiableCompany(companyID, user)
+	if err != nil {
+		return dmodels.CompanyAttachment{}, err
+	}
+
+	maxSize := s.cfg.Attachments.MaxFileSize
+	if maxSize <= 0 {
+		maxSize = conf.DefaultAttachmentsMaxFileSize
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return dmodels.CompanyAttachment{}, fmt.Errorf("file.Read: %v", err)
+	}
+	if n == 0 {
+		return dmodels.CompanyAttachment{}, fmt.Errorf("%w: file is empty", local.ErrValidation)
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !s.attachmentTypeAllowed(contentType) {
+		return dmodels.CompanyAttachment{}, fmt.Errorf("%w: %s", local.ErrUnsupportedMediaType, contentType)
+	}
+
+	size, checksum, err := fileChecksum(file, maxSize)
+	if err != nil {
+		return dmodels.CompanyAttachment{}, err
+	}
+
+	id := uuid.NewV4()
+	key := fmt.Sprintf("%s/%s/%s", user.OrganizationID, company.ID, id)
+	if err := s.dao.PutBlob(key, file, size, contentType, checksum); err != nil {
+		return dmodels.CompanyAttachment{}, fmt.Errorf("dao.PutBlob: %v", err)
+	}
+
+	attachment, err := s.dao.CreateCompanyAttachment(dmodels.CompanyAttachment{
+		ID:          id,
+		CompanyID:   company.ID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		Checksum:    checksum,
+		StorageKey:  key,
+		AuthorID:    uuid.NullUUID{UUID: user.ID, Valid: true},
+	}, user.OrganizationID)
+	if err != nil {
+		// nothing refers to the blob yet, a failed removal only leaves an orphan behind
+		_ = s.dao.DeleteBlob(key)
+		return dmodels.CompanyAttachment{}, daoError("dao.CreateCompanyAttachment", err)
+	}
+	attachment.AuthorEmail = user.Email
+
+	return attachment, nil
+}
+
+// DeleteCompanyAttachment removes the attachment. The blob is removed right away, if that fails
+// it stays queued and is removed along with the purged companies, see PurgeTrash.
+func (s *ServiceFacade) DeleteCompanyAttachment(companyID, id string, user dmodels.User) error {
+	if _, err := uuid.FromString(id); err != nil {
+		return fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+	if _, err := s.modifiableCompany(companyID, user); err != nil {
+		return err
+	}
+
+	attachment, err := s.dao.DeleteCompanyAttachment(companyID, id, user.OrganizationID)
+	if err != nil {
+		return daoError("dao.DeleteCompanyAttachment", err)
+	}
+
+	s.dropBlobs([]string{attachment.StorageKey})
+
+	return nil
+}
+
+// dropBlobs removes the queued blobs right away, the ones which fail are left to purgeBlobs.
+func (s *ServiceFacade) dropBlobs(keys []string) {
+	removed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if err := s.dao.DeleteBlob(key); err == nil {
+			removed = append(removed, key)
+		}
+	}
+	if len(removed) > 0 {
+		_ = s.dao.RemoveBlobDeletions(removed)
+	}
+}
+
+// purgeBlobs removes the queued blobs of deleted attachments and replaced logos from the storage and returns how many were removed.
+func (s *ServiceFacade) purgeBlobs() (int, error) {
+	var purged int
+	for {
+		keys, err := s.dao.ListBlobDeletions(blobDeletionsBatch)
+		if err != nil {
+			return purged, fmt.Errorf("dao.ListBlobDeletions: %v", err)
+		}
+
+		removed := make([]string, 0, len(keys))
+		var lastErr error
+		for _, key := range keys {
+			if err := s.dao.DeleteBlob(key); err != nil {
+				lastErr = fmt.Errorf("dao.DeleteBlob %s: %v", key, err)
+				continue
+			}
+			removed = append(removed, key)
+		}
+		if err := s.dao.RemoveBlobDeletions(removed); err != nil {
+			return purged, fmt.Errorf("dao.RemoveBlobDeletions: %v", err)
+		}
+		purged += len(removed)
+
+		// failed blobs would be listed again, they are left for the next purge
+		if lastErr != nil || len(keys) < blobDeletionsBatch {
+			return purged, lastErr
+		}
+	}
+}
+
+func (s *ServiceFacade) attachmentTypeAllowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	allowed := s.cfg.Attachments.AllowedTypes
+	if len(allowed) == 0 {
+		allowed = conf.DefaultAttachmentsAllowedTypes
+	}
+	for _, t := range allowed {
+		if t == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// fileChecksum reads the whole file, up to maxSize bytes, and rewinds it.
+// It returns the size and the hex encoded SHA-256 of the content.
+func fileChecksum(file io.ReadSeeker, maxSize int64) (int64, string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, "", fmt.Errorf("file.Seek: %v", err)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return 0, "", fmt.Errorf("file.Read: %v", err)
+	}
+	if size > maxSize {
+		return 0, "", fmt.Errorf("%w: file is larger than %d bytes", local.ErrRequestTooLarge, maxSize)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, "", fmt.Errorf("file.Seek: %v", err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xm-task/dao/blob"
+	local "xm-task/helpers/errors"
+)
+
+func TestCompanyAttachments(t *testing.T) {
+	// checks that the checksum covers the whole file and the file can be read again
+	t.Run("it should checksum and rewind the file", func(t *testing.T) {
+		file := strings.NewReader("test")
+		_, err := file.Read(make([]byte, 2))
+		require.NoError(t, err)
+
+		size, checksum, err := fileChecksum(file, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), size)
+		assert.Equal(t, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", checksum)
+
+		data, err := io.ReadAll(file)
+		require.NoError(t, err)
+		assert.Equal(t, "test", string(data))
+	})
+
+	// checks that files over the limit are rejected
+	t.Run("it should reject large files", func(t *testing.T) {
+		_, _, err := fileChecksum(strings.NewReader("too large"), 4)
+		assert.ErrorIs(t, err, local.ErrRequestTooLarge)
+	})
+
+	// checks that contents missing from the blob storage are reported as not found
+	t.Run("it should report missing contents as not found", func(t *testing.T) {
+		assert.ErrorIs(t, daoError("dao.GetBlob", blob.ErrNotFound), local.ErrNotFound)
+	})
+
+	// checks that sniffed types are compared without their parameters
+	t.Run("it should allow the configured types", func(t *testing.T) {
+		s := &ServiceFacade{}
+		assert.True(t, s.attachmentTypeAllowed(http.DetectContentType([]byte("%PDF-1.7\n"))))
+		assert.True(t, s.attachmentTypeAllowed(http.DetectContentType([]byte("plain text"))))
+		assert.False(t, s.attachmentTypeAllowed(http.DetectContentType([]byte("<html><body></body></html>"))))
+	})
+}
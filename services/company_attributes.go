@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+const attributesSchemaURL = "attributes.json"
+
+// validateAttributes checks company attributes against the schema of the company type,
+// types without a schema accept no attributes at all.
+func (s *ServiceFacade) validateAttributes(ct dmodels.CompanyType, attributes map[string]interface{}) error {
+	if ct.AttributesSchema == nil {
+		if len(attributes) > 0 {
+			return fmt.Errorf("%w: company type %q has no attributes", local.ErrValidation, ct.Name)
+		}
+		return nil
+	}
+
+	schema, err := s.attributesSchema(ct.AttributesSchema)
+	if err != nil {
+		return fmt.Errorf("attributes schema of %q: %v", ct.Name, err)
+	}
+
+	if attributes == nil {
+		attributes = map[string]interface{}{}
+	}
+	if err := schema.Validate(attributes); err != nil {
+		return fmt.Errorf("%w: attributes: %s", local.ErrValidation, schemaViolations(err))
+	}
+
+	return nil
+}
+
+// attributesSchema compiles the schema once per distinct document, so that changed schemas
+// are picked up as soon as the cached company types are dropped.
+func (s *ServiceFacade) attributesSchema(schema dmodels.JSONObject) (*jsonschema.Schema, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	if compiled, ok := s.schemas.Load(string(data)); ok {
+		return compiled.(*jsonschema.Schema), nil
+	}
+
+	compiled, err := compileSchema(data)
+	if err != nil {
+		return nil, err
+	}
+	s.schemas.Store(string(data), compiled)
+
+	return compiled, nil
+}
+
+// compileSchema compiles a standalone schema, references to other documents are not followed.
+func compileSchema(data []byte) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("cannot load %q, external references are not supported", url)
+	}
+
+	if err := compiler.AddResource(attributesSchemaURL, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(attributesSchemaURL)
+}
+
+// checkAttributesSchema makes sure the schema of a company type compiles.
+func checkAttributesSchema(schema dmodels.JSONObject) error {
+	if schema == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("%w: %v", local.ErrValidation, err)
+	}
+	if _, err := compileSchema(data); err != nil {
+		return fmt.Errorf("%w: incorrect attributes_schema: %v", local.ErrValidation, err)
+	}
+	return nil
+}
+
+// schemaViolations lists the innermost failures of a validation, "/path: message" each.
+func schemaViolations(err error) string {
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err.Error()
+	}
+
+	violations := make([]string, 0)
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			location := e.InstanceLocation
+			if location == "" {
+				location = "/"
+			}
+			violations = append(violations, fmt.Sprintf("%s: %s", location, e.Message))
+			return
+		}
+		for _, cause := range e.Causes {
+			walk(cause)
+		}
+	}
+	walk(verr)
+
+	return strings.Join(violations, "; ")
+}
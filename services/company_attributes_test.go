@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+func TestValidateAttributes(t *testing.T) {
+	s := &ServiceFacade{}
+	ct := dmodels.CompanyType{
+		Name: "Corporations",
+		AttributesSchema: dmodels.JSONObject{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"website": map[string]interface{}{"type": "string"},
+				"founded": map[string]interface{}{"type": "integer", "minimum": 1800},
+			},
+			"required":             []interface{}{"website"},
+			"additionalProperties": false,
+		},
+	}
+
+	// checks that attributes matching the schema pass
+	t.Run("it should accept valid attributes", func(t *testing.T) {
+		err := s.validateAttributes(ct, map[string]interface{}{"website": "https://xm.com", "founded": float64(2006)})
+		require.NoError(t, err)
+	})
+
+	// checks that every violation is reported with its location
+	t.Run("it should report violations", func(t *testing.T) {
+		err := s.validateAttributes(ct, map[string]interface{}{"founded": float64(1700), "sector": "fx"})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, local.ErrValidation))
+		assert.Contains(t, err.Error(), "/founded")
+		assert.Contains(t, err.Error(), "website")
+		assert.Contains(t, err.Error(), "sector")
+	})
+
+	// checks that types without a schema accept no attributes
+	t.Run("it should reject attributes without a schema", func(t *testing.T) {
+		plain := dmodels.CompanyType{Name: "NonProfit"}
+		require.NoError(t, s.validateAttributes(plain, map[string]interface{}{}))
+
+		err := s.validateAttributes(plain, map[string]interface{}{"website": "https://xm.com"})
+		assert.True(t, errors.Is(err, local.ErrValidation))
+	})
+
+	// checks that schemas cannot reach other documents
+	t.Run("it should reject external references", func(t *testing.T) {
+		err := checkAttributesSchema(dmodels.JSONObject{"$ref": "file:///etc/passwd"})
+		assert.True(t, errors.Is(err, local.ErrValidation))
+	})
+}
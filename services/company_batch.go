@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/smodels"
+)
+
+// BatchCompanies applies a list of creates, updates and deletes. In atomic mode nothing is
+// written unless every operation succeeds, in best effort mode failed operations are skipped.
+func (s *ServiceFacade) BatchCompanies(batch smodels.CompanyBatch, user dmodels.User) ([]dmodels.CompanyBatchResult, error) {
+	atomic := batch.Mode == smodels.BatchModeAtomic
+	results := make([]dmodels.CompanyBatchResult, len(batch.Operations))
+
+	policy := s.deletePolicy()
+	ops := make([]dmodels.CompanyBatchOperation, 0, len(batch.Operations))
+	positions := make([]int, 0, len(batch.Operations))
+	failed := false
+	for i, op := range batch.Operations {
+		results[i] = s.prepareBatchOperation(op, user)
+		if results[i].Err != nil {
+			failed = true
+			continue
+		}
+		ops = append(ops, dmodels.CompanyBatchOperation{Op: results[i].Op, Company: results[i].Company, DeletePolicy: policy})
+		positions = append(positions, i)
+	}
+
+	if atomic && failed {
+		for _, i := range positions {
+			results[i].Err = local.ErrFailedDependency
+		}
+		return results, nil
+	}
+
+	err := s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+		// the subsidiaries are checked under the lock of the deletes, so that none can be moved under them meanwhile
+		if err := tx.LockCompanyHierarchy(user.OrganizationID); err != nil {
+			return fmt.Errorf("dao.LockCompanyHierarchy: %v", err)
+		}
+		allowed := 0
+		for j, i := range positions {
+			if ops[j].Op == dmodels.BatchOpDelete {
+				if err := canModifySubsidiaries(tx, ops[j].Company.ID.String(), policy, user); err != nil {
+					results[i].Err = err
+					failed = true
+					continue
+				}
+			}
+			ops[allowed], positions[allowed] = ops[j], i
+			allowed++
+		}
+		ops, positions = ops[:allowed], positions[:allowed]
+		if atomic && failed {
+			return nil
+		}
+
+		errs, err := tx.ApplyCompanyBatch(ops, atomic, user.OrganizationID, user.ID)
+		if err != nil {
+			return fmt.Errorf("dao.ApplyCompanyBatch: %v", err)
+		}
+
+		for j, i := range positions {
+			results[i].Company = ops[j].Company
+			results[i].Affected = ops[j].Affected
+			if errs[j] != nil {
+				results[i].Err = daoError("dao.ApplyCompanyBatch", errs[j])
+				failed = true
+			}
+		}
+		// atomic batches are rolled back by the dao already
+		if atomic && failed {
+			return nil
+		}
+
+		batchEvents(results, events)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if atomic && failed {
+		for _, i := range positions {
+			if results[i].Err == nil {
+				results[i].Err = local.ErrFailedDependency
+			}
+		}
+		return results, nil
+	}
+
+	go s.kafka.Flush(1000)
+
+	return results, nil
+}
+
+func (s *ServiceFacade) prepareBatchOperation(op smodels.CompanyBatchOperation, user dmodels.User) dmodels.CompanyBatchResult {
+	result := dmodels.CompanyBatchResult{Op: op.Op}
+
+	if op.Op == dmodels.BatchOpCreate {
+		var company smodels.Company
+		decoder := json.NewDecoder(bytes.NewReader(op.Company))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&company); err != nil {
+			result.Err = fmt.Errorf("%w: %v", local.ErrValidation, err)
+			return result
+		}
+		if err := company.Validate(); err != nil {
+			result.Err = fmt.Errorf("%w: %v", local.ErrValidation, err)
+			return result
+		}
+
+		ct, err := s.companyType(company.Type, "")
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if err := s.validateAttributes(ct, company.Attributes); err != nil {
+			result.Err = err
+			return result
+		}
+
+		result.Company = dmodels.Company{
+			ID:             uuid.NewV4(),
+			Name:           company.Name,
+			Description:    company.Description,
+			Employees:      company.Employees,
+			Registered:     company.Registered,
+			TypeID:         ct.ID,
+			Attributes:     company.Attributes,
+			Language:       s.searchLanguage(),
+			CreatedBy:      ownerOf(user),
+			OwnerID:        ownerOf(user),
+			OrganizationID: user.OrganizationID,
+		}
+		return result
+	}
+
+	if op.Op != dmodels.BatchOpUpdate && op.Op != dmodels.BatchOpDelete {
+		result.Err = fmt.Errorf("%w: unknown operation %q", local.ErrValidation, op.Op)
+		return result
+	}
+
+	cUUID, err := uuid.FromString(op.ID)
+	if err != nil {
+		result.Err = fmt.Errorf("%w: incorrect company id", local.ErrValidation)
+		return result
+	}
+
+	current, err := s.dao.GetCompanyByID(op.ID, user.OrganizationID)
+	if err != nil {
+		result.Err = daoError("dao.GetCompanyByID", err)
+		return result
+	}
+	if err := canModify(current.OwnerID, user); err != nil {
+		result.Err = err
+		return result
+	}
+	if op.Version != 0 && op.Version != current.Version {
+		result.Err = fmt.Errorf("version %d: %w", current.Version, local.ErrPreconditionFailed)
+		return result
+	}
+
+	if op.Op == dmodels.BatchOpDelete {
+		result.Company = dmodels.Company{ID: cUUID, Version: op.Version}
+		result.Deleted = current
+		return result
+	}
+
+	patched, err := applyCompanyPatch(current, smodels.MergePatchContentType, op.Company)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	ct, err := s.companyType(patched.Type, current.Type)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if err := s.validateAttributes(ct, patched.Attributes); err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Company = dmodels.Company{
+		ID:             cUUID,
+		Name:           patched.Name,
+		Description:    patched.Description,
+		Employees:      patched.Employees,
+		Registered:     patched.Registered,
+		TypeID:         ct.ID,
+		Attributes:     patched.Attributes,
+		UpdatedAt:      time.Now(),
+		Version:        current.Version,
+		OrganizationID: user.OrganizationID,
+	}
+	return result
+}
+
+// batchEvents adds the usual per company events of the applied operations.
+func batchEvents(results []dmodels.CompanyBatchResult, events *pendingEvents) {
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		switch result.Op {
+		case dmodels.BatchOpCreate:
+			events.add(createdCompaniesTopic, result.Company)
+		case dmodels.BatchOpUpdate:
+			events.add(updatedCompaniesTopic, result.Company)
+		case dmodels.BatchOpDelete:
+			events.add(deletedCompaniesTopic, result.Deleted)
+			subsidiaryEvents(result.Affected, events)
+		}
+	}
+}
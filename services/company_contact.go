@@ -0,0 +1,238 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/smodels"
+)
+
+func (s *ServiceFacade) ListCompanyContacts(companyID string, orgID uuid.UUID) ([]dmodels.CompanyContact, error) {
+	if _, err := s.dao.GetCompanyByID(companyID, orgID); err != nil {
+		return nil, daoError("dao.GetCompanyByID", err)
+	}
+
+	contacts, err := s.dao.ListCompanyContacts(companyID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("dao.ListCompanyContacts: %v", err)
+	}
+
+	return contacts, nil
+}
+
+func (s *ServiceFacade) GetCompanyContact(companyID, id string, orgID uuid.UUID) (dmodels.CompanyContact, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return dmodels.CompanyContact{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	contact, err := s.dao.GetCompanyContact(companyID, id, orgID)
+	if err != nil {
+		return dmodels.CompanyContact{}, daoError("dao.GetCompanyContact", err)
+	}
+
+	return contact, nil
+}
+
+// PrimaryCompanyContact returns the primary contact of the company, nil if there is none.
+func (s *ServiceFacade) PrimaryCompanyContact(companyID string, orgID uuid.UUID) (*dmodels.CompanyContact, error) {
+	contact, err := s.dao.PrimaryCompanyContact(companyID, orgID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dao.PrimaryCompanyContact: %v", err)
+	}
+
+	return &contact, nil
+}
+
+// CreateCompanyContact adds a contact to the company, a primary one replaces the current primary contact.
+func (s *ServiceFacade) CreateCompanyContact(companyID string, contact smodels.CompanyContact, user dmodels.User) (dmodels.CompanyContact, error) {
+	company, err := s.modifiableCompany(companyID, user)
+	if err != nil {
+		return dmodels.CompanyContact{}, err
+	}
+
+	created, err := s.dao.CreateCompanyContact(dmodels.CompanyContact{
+		ID:        uuid.NewV4(),
+		CompanyID: company.ID,
+		Name:      contact.Name,
+		Email:     contact.Email,
+		Phone:     contact.Phone,
+		Position:  contact.Position,
+		Primary:   contact.Primary,
+	}, user.OrganizationID)
+	if err != nil {
+		return dmodels.CompanyContact{}, daoError("dao.CreateCompanyContact", err)
+	}
+
+	return created, nil
+}
+
+func (s *ServiceFacade) UpdateCompanyContact(companyID, id string, contact smodels.CompanyContact, user dmodels.User) (dmodels.CompanyContact, error) {
+	cUUID, err := uuid.FromString(id)
+	if err != nil {
+		return dmodels.CompanyContact{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	company, err := s.modifiableCompany(companyID, user)
+	if err != nil {
+		return dmodels.CompanyContact{}, err
+	}
+
+	updated, err := s.dao.UpdateCompanyContact(dmodels.CompanyContact{
+		ID:        cUUID,
+		CompanyID: company.ID,
+		Name:      contact.Name,
+		Email:     contact.Email,
+		Phone:     contact.Phone,
+		Position:  contact.Position,
+		Primary:   contact.Primary,
+	}, user.OrganizationID)
+	if err != nil {
+		return dmodels.CompanyContact{}, daoError("dao.UpdateCompanyContact", err)
+	}
+
+	return updated, nil
+}
+
+func (s *ServiceFacade) DeleteCompanyContact(companyID, id string, user dmodels.User) error {
+	if _, err := uuid.FromString(id); err != nil {
+		return fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	if _, err := s.modifiableCompany(companyID, user); err != nil {
+		return err
+	}
+
+	if err := s.dao.DeleteCompanyContact(companyID, id, user.OrganizationID); err != nil {
+		return daoError("dao.DeleteCompanyContact", err)
+	}
+
+	return nil
+}
+
+func (s *ServiceFacade) ListCompanyAddresses(companyID string, orgID uuid.UUID) ([]dmodels.CompanyAddress, error) {
+	if _, err := s.dao.GetCompanyByID(companyID, orgID); err != nil {
+		return nil, daoError("dao.GetCompanyByID", err)
+	}
+
+	addresses, err := s.dao.ListCompanyAddresses(companyID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("dao.ListCompanyAddresses: %v", err)
+	}
+
+	return addresses, nil
+}
+
+func (s *ServiceFacade) GetCompanyAddress(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAddress, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return dmodels.CompanyAddress{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	address, err := s.dao.GetCompanyAddress(companyID, id, orgID)
+	if err != nil {
+		return dmodels.CompanyAddress{}, daoError("dao.GetCompanyAddress", err)
+	}
+
+	return address, nil
+}
+
+// PrimaryCompanyAddress returns the primary address of the company, nil if there is none.
+func (s *ServiceFacade) PrimaryCompanyAddress(companyID string, orgID uuid.UUID) (*dmodels.CompanyAddress, error) {
+	address, err := s.dao.PrimaryCompanyAddress(companyID, orgID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dao.PrimaryCompanyAddress: %v", err)
+	}
+
+	return &address, nil
+}
+
+// CreateCompanyAddress adds an address to the company, a primary one replaces the current primary address.
+func (s *ServiceFacade) CreateCompanyAddress(companyID string, address smodels.CompanyAddress, user dmodels.User) (dmodels.CompanyAddress, error) {
+	company, err := s.modifiableCompany(companyID, user)
+	if err != nil {
+		return dmodels.CompanyAddress{}, err
+	}
+
+	created, err := s.dao.CreateCompanyAddress(dmodels.CompanyAddress{
+		ID:         uuid.NewV4(),
+		CompanyID:  company.ID,
+		Line1:      address.Line1,
+		Line2:      address.Line2,
+		City:       address.City,
+		Region:     address.Region,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+		Primary:    address.Primary,
+	}, user.OrganizationID)
+	if err != nil {
+		return dmodels.CompanyAddress{}, daoError("dao.CreateCompanyAddress", err)
+	}
+
+	return created, nil
+}
+
+func (s *ServiceFacade) UpdateCompanyAddress(companyID, id string, address smodels.CompanyAddress, user dmodels.User) (dmodels.CompanyAddress, error) {
+	aUUID, err := uuid.FromString(id)
+	if err != nil {
+		return dmodels.CompanyAddress{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	company, err := s.modifiableCompany(companyID, user)
+	if err != nil {
+		return dmodels.CompanyAddress{}, err
+	}
+
+	updated, err := s.dao.UpdateCompanyAddress(dmodels.CompanyAddress{
+		ID:         aUUID,
+		CompanyID:  company.ID,
+		Line1:      address.Line1,
+		Line2:      address.Line2,
+		City:       address.City,
+		Region:     address.Region,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+		Primary:    address.Primary,
+	}, user.OrganizationID)
+	if err != nil {
+		return dmodels.CompanyAddress{}, daoError("dao.UpdateCompanyAddress", err)
+	}
+
+	return updated, nil
+}
+
+func (s *ServiceFacade) DeleteCompanyAddress(companyID, id string, user dmodels.User) error {
+	if _, err := uuid.FromString(id); err != nil {
+		return fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	if _, err := s.modifiableCompany(companyID, user); err != nil {
+		return err
+	}
+
+	if err := s.dao.DeleteCompanyAddress(companyID, id, user.OrganizationID); err != nil {
+		return daoError("dao.DeleteCompanyAddress", err)
+	}
+
+	return nil
+}
+
+// modifiableCompany returns the live company if the user may change it.
+func (s *ServiceFacade) modifiableCompany(id string, user dmodels.User) (dmodels.CompanyShow, error) {
+	company, err := s.dao.GetCompanyByID(id, user.OrganizationID)
+	if err != nil {
+		return dmodels.CompanyShow{}, daoError("dao.GetCompanyByID", err)
+	}
+	if err := canModify(company.OwnerID, user); err != nil {
+		return dmodels.CompanyShow{}, err
+	}
+	return company, nil
+}
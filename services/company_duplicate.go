@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	uuid "github.com/satori/go.uuid"
+	"xm-task/conf"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+// DuplicatesError is returned by CreateCompany when companies with similar names exist,
+// the creation can be confirmed by allowing duplicates.
+type DuplicatesError struct {
+	Companies []dmodels.SimilarCompany
+}
+
+func (e *DuplicatesError) Error() string {
+	return fmt.Sprintf("%s: %d companies with similar names", local.ReasonPossibleDuplicates, len(e.Companies))
+}
+
+func (e *DuplicatesError) Unwrap() error {
+	return local.ErrPossibleDuplicates
+}
+
+// checkDuplicates fails with DuplicatesError if live companies of the organization have names similar to the name.
+func (s *ServiceFacade) checkDuplicates(name string, orgID uuid.UUID) error {
+	limit := s.cfg.Duplicates.MaxResults
+	if limit <= 0 {
+		limit = conf.DefaultDuplicatesMaxResults
+	}
+
+	similar, err := s.dao.SimilarCompanies(name, orgID, s.duplicatesThreshold(), limit)
+	if err != nil {
+		return fmt.Errorf("dao.SimilarCompanies: %v", err)
+	}
+	if len(similar) > 0 {
+		return &DuplicatesError{Companies: similar}
+	}
+	return nil
+}
+
+// CompanyDuplicates groups the live companies of the organization with similar names into clusters,
+// the most similar first. A zero threshold means the configured one. The report is built from a limited
+// number of pairs, the returned flag tells whether some were left out.
+func (s *ServiceFacade) CompanyDuplicates(orgID uuid.UUID, threshold float64) ([]dmodels.CompanyDuplicateCluster, bool, error) {
+	if threshold == 0 {
+		threshold = s.duplicatesThreshold()
+	}
+	limit := s.cfg.Duplicates.MaxReportPairs
+	if limit <= 0 {
+		limit = conf.DefaultDuplicatesMaxReportPairs
+	}
+
+	pairs, err := s.dao.CompanyDuplicatePairs(orgID, threshold, limit+1)
+	if err != nil {
+		return nil, false, fmt.Errorf("dao.CompanyDuplicatePairs: %v", err)
+	}
+
+	truncated := len(pairs) > limit
+	if truncated {
+		pairs = pairs[:limit]
+	}
+
+	return clusterDuplicates(pairs), truncated, nil
+}
+
+func (s *ServiceFacade) duplicatesThreshold() float64 {
+	if s.cfg.Duplicates.Threshold <= 0 || s.cfg.Duplicates.Threshold > 1 {
+		return conf.DefaultDuplicatesThreshold
+	}
+	return s.cfg.Duplicates.Threshold
+}
+
+// clusterDuplicates joins the pairs sharing a company into clusters. Clusters are ordered by their
+// highest similarity and companies within a cluster by name.
+func clusterDuplicates(pairs []dmodels.CompanyDuplicatePair) []dmodels.CompanyDuplicateCluster {
+	parent := make(map[uuid.UUID]uuid.UUID)
+	var find func(id uuid.UUID) uuid.UUID
+	find = func(id uuid.UUID) uuid.UUID {
+		if parent[id] == id {
+			return id
+		}
+		root := find(parent[id])
+		parent[id] = root
+		return root
+	}
+
+	companies := make(map[uuid.UUID]dmodels.SimilarCompany)
+	for _, pair := range pairs {
+		for _, company := range []dmodels.SimilarCompany{
+			{ID: pair.FirstID, Name: pair.FirstName, Slug: pair.FirstSlug},
+			{ID: pair.SecondID, Name: pair.SecondName, Slug: pair.SecondSlug},
+		} {
+			if _, ok := parent[company.ID]; !ok {
+				parent[company.ID] = company.ID
+				companies[company.ID] = company
+			}
+		}
+		if first, second := find(pair.FirstID), find(pair.SecondID); first != second {
+			parent[second] = first
+		}
+	}
+
+	byRoot := make(map[uuid.UUID]*dmodels.CompanyDuplicateCluster)
+	for _, pair := range pairs {
+		root := find(pair.FirstID)
+		cluster, ok := byRoot[root]
+		if !ok {
+			cluster = &dmodels.CompanyDuplicateCluster{}
+			byRoot[root] = cluster
+		}
+		if pair.Similarity > cluster.Similarity {
+			cluster.Similarity = pair.Similarity
+		}
+	}
+	for id, company := range companies {
+		cluster := byRoot[find(id)]
+		cluster.Companies = append(cluster.Companies, company)
+	}
+
+	clusters := make([]dmodels.CompanyDuplicateCluster, 0, len(byRoot))
+	for _, cluster := range byRoot {
+		sort.Slice(cluster.Companies, func(i, j int) bool {
+			if cluster.Companies[i].Name != cluster.Companies[j].Name {
+				return cluster.Companies[i].Name < cluster.Companies[j].Name
+			}
+			return cluster.Companies[i].ID.String() < cluster.Companies[j].ID.String()
+		})
+		clusters = append(clusters, *cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Similarity != clusters[j].Similarity {
+			return clusters[i].Similarity > clusters[j].Similarity
+		}
+		return clusters[i].Companies[0].Name < clusters[j].Companies[0].Name
+	})
+
+	return clusters
+}
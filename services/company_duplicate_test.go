@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+func TestCompanyDuplicates(t *testing.T) {
+	// checks that companies linked through each other end up in one cluster
+	t.Run("it should cluster similar pairs", func(t *testing.T) {
+		acme, acmeLtd, acmeDot := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+		initech, initrode := uuid.NewV4(), uuid.NewV4()
+
+		clusters := clusterDuplicates([]dmodels.CompanyDuplicatePair{
+			{FirstID: acmeLtd, FirstName: "Acme Ltd", SecondID: acmeDot, SecondName: "Acme Ltd.", Similarity: 0.9},
+			{FirstID: initech, FirstName: "Initech", SecondID: initrode, SecondName: "Initrode", Similarity: 0.7},
+			{FirstID: acme, FirstName: "Acme", SecondID: acmeLtd, SecondName: "Acme Ltd", Similarity: 0.65},
+		})
+		require.Len(t, clusters, 2)
+
+		assert.Equal(t, 0.9, clusters[0].Similarity)
+		names := make([]string, 0)
+		for _, company := range clusters[0].Companies {
+			names = append(names, company.Name)
+		}
+		assert.Equal(t, []string{"Acme", "Acme Ltd", "Acme Ltd."}, names)
+
+		assert.Equal(t, 0.7, clusters[1].Similarity)
+		assert.Len(t, clusters[1].Companies, 2)
+	})
+
+	// checks that the handlers can tell duplicates apart from other conflicts
+	t.Run("it should wrap possible duplicates", func(t *testing.T) {
+		var err error = &DuplicatesError{Companies: []dmodels.SimilarCompany{{Name: "Acme Ltd"}}}
+
+		assert.True(t, errors.Is(err, local.ErrPossibleDuplicates))
+		assert.False(t, errors.Is(err, local.ErrConflict))
+
+		var duplicates *DuplicatesError
+		require.True(t, errors.As(err, &duplicates))
+		assert.Len(t, duplicates.Companies, 1)
+	})
+}
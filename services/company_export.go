@@ -0,0 +1,158 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	uuid "github.com/satori/go.uuid"
+	"xm-task/dmodels"
+	"xm-task/smodels"
+)
+
+// exportPageSize is how many companies are read from the database and encoded at once.
+const exportPageSize = 1000
+
+// exportEncoder writes export rows in one of the supported formats.
+type exportEncoder interface {
+	Write(rows []smodels.CompanyExportRow) error
+	Close() error
+}
+
+// ExportCompanies writes companies ordered by id to w page by page, so the export never has
+// to fit in memory. Nothing is written to w if the first page cannot be read.
+func (s *ServiceFacade) ExportCompanies(w io.Writer, params smodels.CompanyExportParams) (smodels.CompanyExportSummary, error) {
+	summary := smodels.CompanyExportSummary{Last: params.After}
+	query := dmodels.CompanyListQuery{Filter: companyFilter(params.CompanyFilterParams)}
+
+	var encoder exportEncoder
+	for {
+		query.Limit = exportPageSize
+		if params.Limit > 0 && params.Limit-summary.Exported < exportPageSize {
+			query.Limit = int(params.Limit - summary.Exported)
+		}
+		if query.Limit == 0 {
+			break
+		}
+		if summary.Last != "" {
+			query.Cursor = &dmodels.CompanyCursor{ID: uuid.FromStringOrNil(summary.Last)}
+		}
+
+		companies, err := s.dao.ListCompanies(query)
+		if err != nil {
+			return summary, fmt.Errorf("dao.ListCompanies: %v", err)
+		}
+
+		if encoder == nil {
+			encoder = newExportEncoder(w, params.Format)
+		}
+
+		rows := make([]smodels.CompanyExportRow, 0, len(companies))
+		for _, company := range companies {
+			rows = append(rows, smodels.CompanyExportRow{
+				ID:          company.ID.String(),
+				Name:        company.Name,
+				Description: company.Description,
+				Employees:   company.Employees,
+				Registered:  company.Registered,
+				Type:        company.Type,
+				CreatedAt:   company.CreatedAt,
+			})
+		}
+		if err := encoder.Write(rows); err != nil {
+			return summary, fmt.Errorf("encoder.Write: %v", err)
+		}
+
+		summary.Exported += int64(len(rows))
+		if len(rows) > 0 {
+			summary.Last = rows[len(rows)-1].ID
+		}
+		if len(rows) < query.Limit {
+			break
+		}
+	}
+
+	if encoder == nil {
+		encoder = newExportEncoder(w, params.Format)
+	}
+	if err := encoder.Close(); err != nil {
+		return summary, fmt.Errorf("encoder.Close: %v", err)
+	}
+
+	return summary, nil
+}
+
+func newExportEncoder(w io.Writer, format string) exportEncoder {
+	switch format {
+	case smodels.ExportFormatNDJSON:
+		return ndjsonEncoder{json.NewEncoder(w)}
+	case smodels.ExportFormatParquet:
+		return parquetEncoder{parquet.NewGenericWriter[smodels.CompanyExportRow](w, parquet.Compression(&parquet.Snappy))}
+	}
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (e *csvEncoder) Write(rows []smodels.CompanyExportRow) error {
+	if !e.header {
+		e.header = true
+		e.w.Write([]string{"id", "name", "description", "employees_count", "registered", "type", "created_at"})
+	}
+	for _, row := range rows {
+		e.w.Write([]string{
+			row.ID,
+			row.Name,
+			row.Description,
+			strconv.FormatUint(row.Employees, 10),
+			strconv.FormatBool(row.Registered),
+			row.Type,
+			row.CreatedAt.UTC().Format(time.RFC3339Nano),
+		})
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	return e.Write(nil)
+}
+
+type ndjsonEncoder struct {
+	*json.Encoder
+}
+
+func (e ndjsonEncoder) Write(rows []smodels.CompanyExportRow) error {
+	for _, row := range rows {
+		if err := e.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e ndjsonEncoder) Close() error {
+	return nil
+}
+
+// parquetEncoder writes a row group per page, the file footer is written on Close.
+type parquetEncoder struct {
+	*parquet.GenericWriter[smodels.CompanyExportRow]
+}
+
+func (e parquetEncoder) Write(rows []smodels.CompanyExportRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if _, err := e.GenericWriter.Write(rows); err != nil {
+		return err
+	}
+	return e.Flush()
+}
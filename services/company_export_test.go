@@ -0,0 +1,55 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xm-task/smodels"
+)
+
+func TestExportEncoders(t *testing.T) {
+	rows := []smodels.CompanyExportRow{{
+		ID:          "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		Name:        "Acme, Inc",
+		Description: "Anvils",
+		Employees:   42,
+		Registered:  true,
+		Type:        "Corporations",
+		CreatedAt:   time.Date(2023, 9, 1, 10, 30, 0, 123456000, time.UTC),
+	}}
+
+	// checks that csv gets a header even when nothing is exported
+	t.Run("it should write csv header once", func(t *testing.T) {
+		var buf bytes.Buffer
+		encoder := newExportEncoder(&buf, smodels.ExportFormatCSV)
+		require.NoError(t, encoder.Write(rows))
+		require.NoError(t, encoder.Close())
+
+		assert.Equal(t, "id,name,description,employees_count,registered,type,created_at\n"+
+			"6ba7b810-9dad-11d1-80b4-00c04fd430c8,\"Acme, Inc\",Anvils,42,true,Corporations,2023-09-01T10:30:00.123456Z\n",
+			buf.String())
+
+		buf.Reset()
+		require.NoError(t, newExportEncoder(&buf, smodels.ExportFormatCSV).Close())
+		assert.Equal(t, "id,name,description,employees_count,registered,type,created_at\n", buf.String())
+	})
+
+	// checks that every page ends up in a readable parquet file
+	t.Run("it should write parquet file", func(t *testing.T) {
+		var buf bytes.Buffer
+		encoder := newExportEncoder(&buf, smodels.ExportFormatParquet)
+		require.NoError(t, encoder.Write(rows))
+		require.NoError(t, encoder.Write(rows))
+		require.NoError(t, encoder.Close())
+
+		read, err := parquet.Read[smodels.CompanyExportRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		require.Len(t, read, 2)
+		assert.Equal(t, rows[0].Name, read[1].Name)
+		assert.True(t, rows[0].CreatedAt.Equal(read[1].CreatedAt))
+	})
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	uuid "github.com/satori/go.uuid"
+	"xm-task/conf"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/smodels"
+)
+
+// feedBufferSize is how many events a subscription may lag behind, slower ones are closed.
+const feedBufferSize = 64
+
+// CompanyFeedSubscription receives the company events of the organization matching its filters.
+type CompanyFeedSubscription struct {
+	// Backlog are the events following the LastEventID of the subscription. Reset is set instead
+	// when some of them are no longer kept, the client has to fetch the companies again.
+	Backlog []dmodels.CompanyEvent
+	Reset   bool
+
+	orgID     uuid.UUID
+	companies map[uuid.UUID]bool
+	types     map[string]bool
+	replayed  map[int64]bool
+	events    chan dmodels.CompanyEvent
+	closed    bool
+}
+
+// Events is closed when the subscription falls behind, the instance stops listening or shuts down,
+// the client resumes the feed then.
+func (sub *CompanyFeedSubscription) Events() <-chan dmodels.CompanyEvent {
+	return sub.events
+}
+
+// Replayed tells whether the event was already sent with the backlog.
+func (sub *CompanyFeedSubscription) Replayed(id int64) bool {
+	return sub.replayed[id]
+}
+
+func (sub *CompanyFeedSubscription) matches(event dmodels.CompanyEvent) bool {
+	return event.OrganizationID == sub.orgID &&
+		(len(sub.companies) == 0 || sub.companies[event.CompanyID]) &&
+		(len(sub.types) == 0 || sub.types[event.Type])
+}
+
+// companyFeed fans the events received by the listener of the instance out to its subscriptions.
+type companyFeed struct {
+	mu            sync.Mutex
+	listening     bool
+	subscriptions map[*CompanyFeedSubscription]bool
+}
+
+func (f *companyFeed) subscribe(sub *CompanyFeedSubscription, maxSubscriptions int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// events would be missed until the listener is back
+	if !f.listening {
+		return fmt.Errorf("%w: the feed is not available", local.ErrUnavailable)
+	}
+	if len(f.subscriptions) >= maxSubscriptions {
+		return fmt.Errorf("%w: too many subscriptions", local.ErrUnavailable)
+	}
+	if f.subscriptions == nil {
+		f.subscriptions = make(map[*CompanyFeedSubscription]bool)
+	}
+	f.subscriptions[sub] = true
+	return nil
+}
+
+func (f *companyFeed) unsubscribe(sub *CompanyFeedSubscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.close(sub)
+}
+
+func (f *companyFeed) close(sub *CompanyFeedSubscription) {
+	if !sub.closed {
+		sub.closed = true
+		close(sub.events)
+	}
+	delete(f.subscriptions, sub)
+}
+
+// publish never blocks the listener, subscriptions which cannot take the event are closed.
+func (f *companyFeed) publish(event dmodels.CompanyEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for sub := range f.subscriptions {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			f.close(sub)
+		}
+	}
+}
+
+// setListening closes every subscription once the listener stops, since they would miss events.
+func (f *companyFeed) setListening(listening bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.listening = listening
+	if !listening {
+		for sub := range f.subscriptions {
+			f.close(sub)
+		}
+	}
+}
+
+// SubscribeCompanyEvents follows the changes of the companies of the organization. Events following
+// params.LastEventID are replayed first, they are kept as long as they are among the Feed.ReplaySize
+// latest events.
+func (s *ServiceFacade) SubscribeCompanyEvents(params smodels.CompanyFeedParams, orgID uuid.UUID) (*CompanyFeedSubscription, error) {
+	sub := &CompanyFeedSubscription{
+		orgID:     orgID,
+		companies: make(map[uuid.UUID]bool, len(params.CompanyIDs)),
+		types:     make(map[string]bool, len(params.Types)),
+		replayed:  make(map[int64]bool),
+		events:    make(chan dmodels.CompanyEvent, feedBufferSize),
+	}
+	for _, id := range params.CompanyIDs {
+		sub.companies[uuid.FromStringOrNil(id)] = true
+	}
+	for _, name := range params.Types {
+		sub.types[name] = true
+	}
+
+	maxSubscriptions := s.cfg.Feed.MaxSubscribers
+	if maxSubscriptions <= 0 {
+		maxSubscriptions = conf.DefaultFeedMaxSubscribers
+	}
+	// the subscription starts before the replay, so that no event is missed in between
+	if err := s.feed.subscribe(sub, maxSubscriptions); err != nil {
+		return nil, err
+	}
+	if params.LastEventID == 0 {
+		return sub, nil
+	}
+
+	if err := s.replayCompanyEvents(sub, params.LastEventID); err != nil {
+		s.feed.unsubscribe(sub)
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *ServiceFacade) UnsubscribeCompanyEvents(sub *CompanyFeedSubscription) {
+	s.feed.unsubscribe(sub)
+}
+
+// ListenCompanyEvents passes the events added by every instance to the subscriptions of this one until the
+// context is done or the database connection is lost. Subscriptions are only accepted meanwhile. The cached
+// company types are dropped whenever any instance changes them, and on connecting, as changes made while
+// the connection was lost are not notified.
+func (s *ServiceFacade) ListenCompanyEvents(ctx context.Context) error {
+	defer s.feed.setListening(false)
+
+	return s.dao.ListenCompanyEvents(ctx, func() {
+		s.dao.RemoveCompanyTypes()
+		s.feed.setListening(true)
+	}, func(id int64) error {
+		event, err := s.dao.GetCompanyEvent(id)
+		if err != nil {
+			return fmt.Errorf("dao.GetCompanyEvent: %v", err)
+		}
+		s.feed.publish(event)
+		return nil
+	}, func() {
+		s.dao.RemoveCompanyTypes()
+	})
+}
+
+// TrimCompanyEvents removes the events which are no longer replayed.
+func (s *ServiceFacade) TrimCompanyEvents() (int64, error) {
+	trimmed, err := s.dao.TrimCompanyEvents(s.feedReplaySize())
+	if err != nil {
+		return 0, fmt.Errorf("dao.TrimCompanyEvents: %v", err)
+	}
+	return trimmed, nil
+}
+
+// replayCompanyEvents fills the backlog of the subscription with the events following lastEventID.
+func (s *ServiceFacade) replayCompanyEvents(sub *CompanyFeedSubscription, lastEventID int64) error {
+	first, err := s.dao.FirstCompanyEventID()
+	if err != nil {
+		return fmt.Errorf("dao.FirstCompanyEventID: %v", err)
+	}
+	// the events in between were trimmed, ids skipped by rolled back transactions may reset the feed needlessly
+	if first > lastEventID+1 {
+		sub.Reset = true
+		return nil
+	}
+
+	limit := s.feedReplaySize()
+	events, err := s.dao.ListCompanyEvents(lastEventID, limit+1, sub.orgID)
+	if err != nil {
+		return fmt.Errorf("dao.ListCompanyEvents: %v", err)
+	}
+	if len(events) > limit {
+		sub.Reset = true
+		return nil
+	}
+
+	for _, event := range events {
+		if sub.matches(event) {
+			sub.Backlog = append(sub.Backlog, event)
+			sub.replayed[event.ID] = true
+		}
+	}
+	return nil
+}
+
+// addCompanyEvent records the event published to the topic for the live feed.
+func addCompanyEvent(tx dao.DAO, topic string, value interface{}) error {
+	name, ok := topicEvents[topic]
+	if !ok {
+		return nil
+	}
+	event, err := eventCompany(value)
+	if err != nil {
+		return err
+	}
+	event.Event = name
+
+	if err := tx.AddCompanyEvent(event); err != nil {
+		return fmt.Errorf("dao.AddCompanyEvent: %v", err)
+	}
+	return nil
+}
+
+// eventCompany returns the company an event is published with.
+func eventCompany(value interface{}) (dmodels.CompanyEvent, error) {
+	switch company := value.(type) {
+	case dmodels.Company:
+		return dmodels.CompanyEvent{
+			OrganizationID: company.OrganizationID,
+			CompanyID:      company.ID,
+			Version:        company.Version,
+		}, nil
+	case dmodels.CompanyShow:
+		return dmodels.CompanyEvent{
+			OrganizationID: company.OrganizationID,
+			CompanyID:      company.ID,
+			Version:        company.Version,
+		}, nil
+	}
+	return dmodels.CompanyEvent{}, fmt.Errorf("unexpected %T event", value)
+}
+
+func (s *ServiceFacade) feedReplaySize() int {
+	if s.cfg.Feed.ReplaySize > 0 {
+		return s.cfg.Feed.ReplaySize
+	}
+	return conf.DefaultFeedReplaySize
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/smodels"
+)
+
+func TestCompanyFeed(t *testing.T) {
+	orgID := uuid.NewV4()
+	companyID := uuid.NewV4()
+
+	// checks that subscriptions are only accepted while the instance listens and get the matching events
+	t.Run("it should filter the events", func(t *testing.T) {
+		s := &ServiceFacade{}
+		params := smodels.CompanyFeedParams{CompanyIDs: []string{companyID.String()}, Types: []string{"Corporations"}}
+		_, err := s.SubscribeCompanyEvents(params, orgID)
+		assert.True(t, errors.Is(err, local.ErrUnavailable))
+
+		s.feed.setListening(true)
+		sub, err := s.SubscribeCompanyEvents(params, orgID)
+		require.NoError(t, err)
+
+		s.feed.publish(dmodels.CompanyEvent{ID: 1, OrganizationID: uuid.NewV4(), CompanyID: companyID, Type: "Corporations"})
+		s.feed.publish(dmodels.CompanyEvent{ID: 2, OrganizationID: orgID, CompanyID: uuid.NewV4(), Type: "Corporations"})
+		s.feed.publish(dmodels.CompanyEvent{ID: 3, OrganizationID: orgID, CompanyID: companyID, Type: "NonProfit"})
+		s.feed.publish(dmodels.CompanyEvent{ID: 4, OrganizationID: orgID, CompanyID: companyID, Type: "Corporations"})
+
+		require.Len(t, sub.Events(), 1)
+		assert.Equal(t, int64(4), (<-sub.Events()).ID)
+
+		s.feed.setListening(false)
+		_, open := <-sub.Events()
+		assert.False(t, open)
+	})
+
+	// checks that subscriptions falling behind are closed instead of blocking the others
+	t.Run("it should close slow subscriptions", func(t *testing.T) {
+		s := &ServiceFacade{}
+		s.feed.setListening(true)
+		slow, err := s.SubscribeCompanyEvents(smodels.CompanyFeedParams{}, orgID)
+		require.NoError(t, err)
+		other, err := s.SubscribeCompanyEvents(smodels.CompanyFeedParams{}, orgID)
+		require.NoError(t, err)
+
+		for i := 0; i <= feedBufferSize; i++ {
+			s.feed.publish(dmodels.CompanyEvent{ID: int64(i + 1), OrganizationID: orgID, CompanyID: companyID})
+			if i < feedBufferSize {
+				<-other.Events()
+			}
+		}
+
+		received := 0
+		for range slow.Events() {
+			received++
+		}
+		assert.Equal(t, feedBufferSize, received)
+		assert.Len(t, other.Events(), 1)
+		s.UnsubscribeCompanyEvents(other)
+	})
+}
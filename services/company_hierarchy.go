@@ -0,0 +1,174 @@
+package services
+
+import (
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"xm-task/conf"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/helpers/rbac"
+)
+
+// CompanyAncestors returns the parents of the company up to the given number of levels, the nearest one first.
+func (s *ServiceFacade) CompanyAncestors(id string, depth int, orgID uuid.UUID) ([]dmodels.CompanyNode, error) {
+	depth, err := s.treeDepth(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.dao.GetCompanyByID(id, orgID); err != nil {
+		return nil, daoError("dao.GetCompanyByID", err)
+	}
+
+	nodes, err := s.dao.CompanyAncestors(id, orgID, depth)
+	if err != nil {
+		return nil, fmt.Errorf("dao.CompanyAncestors: %v", err)
+	}
+
+	return nodes, nil
+}
+
+// CompanySubtree returns the company along with its subsidiaries down to the given number of levels.
+// The result is truncated to the configured size, the returned flag tells whether it happened.
+func (s *ServiceFacade) CompanySubtree(id string, depth int, orgID uuid.UUID) ([]dmodels.CompanyNode, bool, error) {
+	depth, err := s.treeDepth(depth)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if _, err := s.dao.GetCompanyByID(id, orgID); err != nil {
+		return nil, false, daoError("dao.GetCompanyByID", err)
+	}
+
+	size := s.cfg.Hierarchy.MaxSubtreeSize
+	if size <= 0 {
+		size = conf.DefaultHierarchyMaxSubtreeSize
+	}
+
+	nodes, err := s.dao.CompanySubtree(id, orgID, depth, size+1)
+	if err != nil {
+		return nil, false, fmt.Errorf("dao.CompanySubtree: %v", err)
+	}
+
+	truncated := len(nodes) > size
+	if truncated {
+		nodes = nodes[:size]
+	}
+
+	return nodes, truncated, nil
+}
+
+// CompanyGroup sums up the company and all of its subsidiaries regardless of the depth limit.
+func (s *ServiceFacade) CompanyGroup(id string, orgID uuid.UUID) (dmodels.CompanyGroup, error) {
+	if _, err := s.dao.GetCompanyByID(id, orgID); err != nil {
+		return dmodels.CompanyGroup{}, daoError("dao.GetCompanyByID", err)
+	}
+
+	group, err := s.dao.CompanyGroup(id, orgID)
+	if err != nil {
+		return dmodels.CompanyGroup{}, fmt.Errorf("dao.CompanyGroup: %v", err)
+	}
+
+	return group, nil
+}
+
+// SetCompanyParent moves the company under the given parent, an empty parent makes it a top level company.
+// A non-zero version has to match the stored one.
+func (s *ServiceFacade) SetCompanyParent(id string, parentID string, version uint64, user dmodels.User) (dmodels.CompanyShow, error) {
+	current, err := s.dao.GetCompanyByID(id, user.OrganizationID)
+	if err != nil {
+		return dmodels.CompanyShow{}, daoError("dao.GetCompanyByID", err)
+	}
+	if err := canModify(current.OwnerID, user); err != nil {
+		return dmodels.CompanyShow{}, err
+	}
+
+	var parent *uuid.UUID
+	if parentID != "" {
+		pUUID, err := uuid.FromString(parentID)
+		if err != nil {
+			return dmodels.CompanyShow{}, fmt.Errorf("%w: incorrect parent id", local.ErrValidation)
+		}
+		parent = &pUUID
+	}
+
+	var company dmodels.CompanyShow
+	err = s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+		if err := tx.SetCompanyParent(id, user.OrganizationID, parent, version, user.ID); err != nil {
+			return daoError("dao.SetCompanyParent", err)
+		}
+
+		company, err = tx.GetCompanyByID(id, user.OrganizationID)
+		if err != nil {
+			return daoError("dao.GetCompanyByID", err)
+		}
+		events.add(updatedCompaniesTopic, company)
+		return nil
+	})
+	if err != nil {
+		return dmodels.CompanyShow{}, err
+	}
+
+	return company, nil
+}
+
+func (s *ServiceFacade) treeDepth(depth int) (int, error) {
+	limit := s.cfg.Hierarchy.MaxDepth
+	if limit <= 0 {
+		limit = conf.DefaultHierarchyMaxDepth
+	}
+	if depth == 0 {
+		return limit, nil
+	}
+	if depth < 0 || depth > limit {
+		return 0, fmt.Errorf("%w: depth should be between 1 and %d", local.ErrValidation, limit)
+	}
+	return depth, nil
+}
+
+// deletePolicy returns the configured delete policy, anything unknown restricts deletes.
+func (s *ServiceFacade) deletePolicy() string {
+	switch s.cfg.Hierarchy.DeletePolicy {
+	case dmodels.DeletePolicyCascade, dmodels.DeletePolicyReparent:
+		return s.cfg.Hierarchy.DeletePolicy
+	}
+	return dmodels.DeletePolicyRestrict
+}
+
+// canModifySubsidiaries checks that the user may change every subsidiary the delete policy touches:
+// the whole subtree for cascades and the direct subsidiaries for re-parenting.
+func canModifySubsidiaries(tx dao.DAO, id string, policy string, user dmodels.User) error {
+	if policy == dmodels.DeletePolicyRestrict || rbac.Can(user.Role, rbac.CompaniesManage) {
+		return nil
+	}
+
+	depth := 1
+	if policy == dmodels.DeletePolicyCascade {
+		depth = 0
+	}
+
+	nodes, err := tx.CompanySubtree(id, user.OrganizationID, depth, 0)
+	if err != nil {
+		return fmt.Errorf("dao.CompanySubtree: %v", err)
+	}
+	for _, node := range nodes {
+		if err := canModify(node.OwnerID, user); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subsidiaryEvents reports the subsidiaries changed by a delete, trashed ones as deleted.
+func subsidiaryEvents(affected []dmodels.CompanyShow, events *pendingEvents) {
+	for _, company := range affected {
+		if company.DeletedAt != nil {
+			events.add(deletedCompaniesTopic, company)
+		} else {
+			events.add(updatedCompaniesTopic, company)
+		}
+	}
+}
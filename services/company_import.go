@@ -0,0 +1,405 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/xuri/excelize/v2"
+	"xm-task/conf"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/smodels"
+)
+
+// xlsxUnzipRatio limits the unzipped parts of XLSX files relative to their size.
+const xlsxUnzipRatio = 16
+
+// rowReader reads a spreadsheet row by row, it returns io.EOF after the last row.
+type rowReader interface {
+	Read() ([]string, error)
+	Close() error
+}
+
+type csvRows struct {
+	*csv.Reader
+}
+
+func (r csvRows) Close() error {
+	return nil
+}
+
+type xlsxRows struct {
+	file *excelize.File
+	rows *excelize.Rows
+}
+
+func (r xlsxRows) Read() ([]string, error) {
+	if !r.rows.Next() {
+		if err := r.rows.Error(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return r.rows.Columns()
+}
+
+func (r xlsxRows) Close() error {
+	r.rows.Close()
+	return r.file.Close()
+}
+
+// importRow is a parsed file row waiting to be written with the rest of its chunk.
+type importRow struct {
+	report  smodels.CompanyImportRow
+	company dmodels.Company
+}
+
+// ImportCompanies creates companies from a CSV or XLSX file. Rows are processed in chunks,
+// each row is reported through the report callback as soon as its chunk is done, so neither
+// a CSV file nor the report has to fit in memory. XLSX files are read whole, they are limited
+// by Import.MaxFileSize. Invalid rows are skipped, in dry run mode nothing is written at all.
+func (s *ServiceFacade) ImportCompanies(r io.Reader, params smodels.CompanyImportParams, user dmodels.User,
+	report func(smodels.CompanyImportRow) error) (smodels.CompanyImportSummary, error) {
+	summary := smodels.CompanyImportSummary{DryRun: params.DryRun}
+
+	maxFileSize := s.cfg.Import.MaxFileSize
+	if maxFileSize <= 0 {
+		maxFileSize = conf.DefaultImportMaxFileSize
+	}
+	rows, err := openRows(r, params, maxFileSize)
+	if err != nil {
+		return summary, err
+	}
+	defer rows.Close()
+
+	header, err := rows.Read()
+	if err == io.EOF {
+		return summary, fmt.Errorf("%w: file is empty", local.ErrValidation)
+	}
+	if err != nil {
+		return summary, fmt.Errorf("%w: %v", local.ErrValidation, err)
+	}
+	columns, err := importColumns(header, params.Mapping)
+	if err != nil {
+		return summary, err
+	}
+
+	chunkSize := s.cfg.Import.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = conf.DefaultImportChunkSize
+	}
+
+	// names are checked against the previous chunks as well, which are not written in dry run mode
+	taken := make(map[string]bool)
+	chunk := make([]importRow, 0, chunkSize)
+	for line := 2; ; line++ {
+		record, err := rows.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return summary, fmt.Errorf("%w: row %d: %v", local.ErrValidation, line, err)
+		}
+		if blankRecord(record) {
+			continue
+		}
+
+		row, err := s.prepareImportRow(line, record, columns, user)
+		if err != nil {
+			return summary, err
+		}
+		chunk = append(chunk, row)
+
+		if len(chunk) == chunkSize {
+			if err := s.importChunk(chunk, taken, params.DryRun, user, &summary, report); err != nil {
+				return summary, err
+			}
+			chunk = chunk[:0]
+		}
+	}
+
+	return summary, s.importChunk(chunk, taken, params.DryRun, user, &summary, report)
+}
+
+// openRows reads CSV files as they come, XLSX files are read into memory up to maxSize bytes first
+// and their unzipped parts are limited to xlsxUnzipRatio times that.
+func openRows(r io.Reader, params smodels.CompanyImportParams, maxSize int64) (rowReader, error) {
+	if params.Format == smodels.ImportFormatCSV {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		reader.ReuseRecord = true
+		return csvRows{reader}, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read xlsx file: %v", local.ErrValidation, err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: xlsx file is bigger than %d bytes", local.ErrValidation, maxSize)
+	}
+
+	// Worksheets bigger than the unzip XML limit are extracted to a temporary file and read from there.
+	file, err := excelize.OpenReader(bytes.NewReader(data), excelize.Options{UnzipSizeLimit: maxSize * xlsxUnzipRatio})
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot open xlsx file: %v", local.ErrValidation, err)
+	}
+
+	sheet := params.Sheet
+	if sheet == "" {
+		sheet = file.GetSheetName(0)
+	}
+	rows, err := file.Rows(sheet)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: cannot read sheet %q: %v", local.ErrValidation, sheet, err)
+	}
+
+	return xlsxRows{file: file, rows: rows}, nil
+}
+
+// importColumns finds the column index of every field, headers are matched case insensitively.
+func importColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	indexes := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if _, ok := indexes[column]; !ok {
+			indexes[column] = i
+		}
+	}
+
+	columns := make(map[string]int, len(smodels.ImportFields))
+	for _, field := range smodels.ImportFields {
+		column, mapped := mapping[field]
+		if !mapped {
+			column = field
+		}
+
+		i, ok := indexes[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			if mapped || field == "name" || field == "type" {
+				return nil, fmt.Errorf("%w: column %q not found", local.ErrValidation, column)
+			}
+			continue
+		}
+		columns[field] = i
+	}
+
+	return columns, nil
+}
+
+func blankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// prepareImportRow parses and validates a row. Problems with the row itself end up in the
+// report, only failures to look up the company type or its schema are returned.
+func (s *ServiceFacade) prepareImportRow(line int, record []string, columns map[string]int, user dmodels.User) (importRow, error) {
+	value := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := importRow{report: smodels.CompanyImportRow{Row: line, Status: smodels.ImportRowInvalid, Name: value("name")}}
+
+	company := smodels.Company{
+		Name:        value("name"),
+		Description: value("description"),
+		Type:        value("type"),
+	}
+
+	if employees := value("employees_count"); employees != "" {
+		n, err := strconv.ParseUint(employees, 10, 64)
+		if err != nil {
+			row.report.Error = fmt.Sprintf("incorrect employees_count %q", employees)
+			return row, nil
+		}
+		company.Employees = n
+	}
+
+	if registered := value("registered"); registered != "" {
+		b, err := parseImportBool(registered)
+		if err != nil {
+			row.report.Error = fmt.Sprintf("incorrect registered %q", registered)
+			return row, nil
+		}
+		company.Registered = b
+	}
+
+	if attributes := value("attributes"); attributes != "" {
+		if err := json.Unmarshal([]byte(attributes), &company.Attributes); err != nil || company.Attributes == nil {
+			row.report.Error = "attributes should be a JSON object"
+			return row, nil
+		}
+	}
+
+	if err := company.Validate(); err != nil {
+		row.report.Error = err.Error()
+		return row, nil
+	}
+	row.report.Name = company.Name
+
+	ct, err := s.companyType(company.Type, "")
+	if err == nil {
+		err = s.validateAttributes(ct, company.Attributes)
+	}
+	if errors.Is(err, local.ErrValidation) {
+		row.report.Error = strings.TrimPrefix(err.Error(), local.ErrValidation.Error()+": ")
+		return row, nil
+	}
+	if err != nil {
+		return row, err
+	}
+
+	row.report.Status = smodels.ImportRowValid
+	row.company = dmodels.Company{
+		ID:             uuid.NewV4(),
+		Name:           company.Name,
+		Description:    company.Description,
+		Employees:      company.Employees,
+		Registered:     company.Registered,
+		TypeID:         ct.ID,
+		Attributes:     company.Attributes,
+		Language:       s.searchLanguage(),
+		CreatedBy:      ownerOf(user),
+		OwnerID:        ownerOf(user),
+		OrganizationID: user.OrganizationID,
+	}
+	return row, nil
+}
+
+// claimImportNames marks the valid rows with taken names invalid and takes the names of the rest,
+// it returns their create operations along with their positions in the chunk.
+func claimImportNames(chunk []importRow, taken map[string]bool) ([]dmodels.CompanyBatchOperation, []int) {
+	ops := make([]dmodels.CompanyBatchOperation, 0, len(chunk))
+	positions := make([]int, 0, len(chunk))
+	for i := range chunk {
+		row := &chunk[i]
+		if row.report.Status != smodels.ImportRowValid {
+			continue
+		}
+		// names are unique regardless of the case
+		name := strings.ToLower(row.company.Name)
+		if taken[name] {
+			row.report.Status = smodels.ImportRowInvalid
+			row.report.Error = fmt.Sprintf("company %q already exists", row.company.Name)
+			continue
+		}
+		taken[name] = true
+		ops = append(ops, dmodels.CompanyBatchOperation{Op: dmodels.BatchOpCreate, Company: row.company})
+		positions = append(positions, i)
+	}
+	return ops, positions
+}
+
+func parseImportBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// importChunk checks names of valid rows against the taken ones and existing companies, writes
+// the rest unless it is a dry run and reports every row of the chunk.
+func (s *ServiceFacade) importChunk(chunk []importRow, taken map[string]bool, dryRun bool, user dmodels.User,
+	summary *smodels.CompanyImportSummary, report func(smodels.CompanyImportRow) error) error {
+	names := make([]string, 0, len(chunk))
+	for _, row := range chunk {
+		if row.report.Status == smodels.ImportRowValid {
+			names = append(names, row.company.Name)
+		}
+	}
+
+	existing, err := s.dao.ExistingCompanyNames(names, user.OrganizationID)
+	if err != nil {
+		return fmt.Errorf("dao.ExistingCompanyNames: %v", err)
+	}
+	for _, name := range existing {
+		taken[strings.ToLower(name)] = true
+	}
+	ops, positions := claimImportNames(chunk, taken)
+
+	if !dryRun && len(ops) > 0 {
+		var errs []error
+		err := s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+			errs, err = tx.ApplyCompanyBatch(ops, false, user.OrganizationID, user.ID)
+			if err != nil {
+				return fmt.Errorf("dao.ApplyCompanyBatch: %v", err)
+			}
+
+			results := make([]dmodels.CompanyBatchResult, len(ops))
+			for j := range ops {
+				results[j] = dmodels.CompanyBatchResult{Op: dmodels.BatchOpCreate, Company: ops[j].Company, Err: errs[j]}
+			}
+			batchEvents(results, events)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for j, i := range positions {
+			row := &chunk[i]
+			if errs[j] != nil {
+				delete(taken, strings.ToLower(row.company.Name))
+				row.report.Status = smodels.ImportRowFailed
+				row.report.Error = importError(daoError("dao.ApplyCompanyBatch", errs[j]))
+				continue
+			}
+			row.report.Status = smodels.ImportRowCreated
+			row.report.ID = ops[j].Company.ID.String()
+		}
+
+		go s.kafka.Flush(1000)
+	}
+
+	for _, row := range chunk {
+		summary.Total++
+		switch row.report.Status {
+		case smodels.ImportRowValid:
+			summary.Valid++
+		case smodels.ImportRowInvalid:
+			summary.Invalid++
+		case smodels.ImportRowCreated:
+			summary.Valid++
+			summary.Created++
+		case smodels.ImportRowFailed:
+			summary.Valid++
+			summary.Failed++
+		}
+
+		if err := report(row.report); err != nil {
+			return fmt.Errorf("report: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// importError is the reason reported for a row which passed validation but was not written.
+func importError(err error) string {
+	if errors.Is(err, local.ErrConflict) {
+		return "company already exists"
+	}
+	return local.ServiceError
+}
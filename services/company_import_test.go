@@ -0,0 +1,117 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/smodels"
+)
+
+func readAll(t *testing.T, rows rowReader) [][]string {
+	defer rows.Close()
+
+	records := make([][]string, 0)
+	for {
+		record, err := rows.Read()
+		if err == io.EOF {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, append([]string{}, record...))
+	}
+}
+
+func TestImportRows(t *testing.T) {
+	// checks that csv rows are read with quoted values
+	t.Run("it should read csv rows", func(t *testing.T) {
+		file := "\ufeffName,Type\n\"Acme, Inc\",Corporations\n"
+		rows, err := openRows(strings.NewReader(file), smodels.CompanyImportParams{Format: smodels.ImportFormatCSV}, 1<<20)
+		require.NoError(t, err)
+
+		assert.Equal(t, [][]string{{"\ufeffName", "Type"}, {"Acme, Inc", "Corporations"}}, readAll(t, rows))
+	})
+
+	// checks that xlsx rows are read from the requested sheet
+	t.Run("it should read xlsx sheet", func(t *testing.T) {
+		f := excelize.NewFile()
+		_, err := f.NewSheet("Companies")
+		require.NoError(t, err)
+		require.NoError(t, f.SetSheetRow("Companies", "A1", &[]string{"Name", "Type"}))
+		require.NoError(t, f.SetSheetRow("Companies", "A2", &[]string{"Acme Inc", "Corporations"}))
+		var buf bytes.Buffer
+		require.NoError(t, f.Write(&buf))
+
+		params := smodels.CompanyImportParams{Format: smodels.ImportFormatXLSX, Sheet: "Companies"}
+		rows, err := openRows(&buf, params, 1<<20)
+		require.NoError(t, err)
+
+		assert.Equal(t, [][]string{{"Name", "Type"}, {"Acme Inc", "Corporations"}}, readAll(t, rows))
+	})
+
+	// checks that xlsx files are not read past the size limit
+	t.Run("it should reject big xlsx files", func(t *testing.T) {
+		f := excelize.NewFile()
+		var buf bytes.Buffer
+		require.NoError(t, f.Write(&buf))
+
+		params := smodels.CompanyImportParams{Format: smodels.ImportFormatXLSX}
+		_, err := openRows(&buf, params, int64(buf.Len()-1))
+		assert.True(t, errors.Is(err, local.ErrValidation))
+	})
+}
+
+func TestClaimImportNames(t *testing.T) {
+	row := func(name string) importRow {
+		return importRow{
+			report:  smodels.CompanyImportRow{Status: smodels.ImportRowValid},
+			company: dmodels.Company{Name: name},
+		}
+	}
+
+	// checks that names taken by previous chunks are rejected regardless of the case
+	t.Run("it should reject names taken by previous chunks", func(t *testing.T) {
+		taken := map[string]bool{"existing": true}
+		first := []importRow{row("Acme Inc"), row("Existing")}
+		ops, positions := claimImportNames(first, taken)
+		require.Len(t, ops, 1)
+		assert.Equal(t, []int{0}, positions)
+		assert.Equal(t, smodels.ImportRowInvalid, first[1].report.Status)
+
+		second := []importRow{row("ACME INC"), row("Other Inc"), row("other inc")}
+		ops, positions = claimImportNames(second, taken)
+		require.Len(t, ops, 1)
+		assert.Equal(t, "Other Inc", ops[0].Company.Name)
+		assert.Equal(t, []int{1}, positions)
+		assert.Equal(t, smodels.ImportRowInvalid, second[0].report.Status)
+		assert.Equal(t, smodels.ImportRowInvalid, second[2].report.Status)
+	})
+}
+
+func TestImportColumns(t *testing.T) {
+	header := []string{"\ufeffName", "Kind", "Employees_Count", "Notes"}
+
+	// checks that headers are matched case insensitively and mapped columns win
+	t.Run("it should resolve columns", func(t *testing.T) {
+		columns, err := importColumns(header, map[string]string{"type": "kind"})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]int{"name": 0, "type": 1, "employees_count": 2}, columns)
+	})
+
+	// checks that a required or mapped column has to be present
+	t.Run("it should reject missing columns", func(t *testing.T) {
+		_, err := importColumns(header, nil)
+		assert.True(t, errors.Is(err, local.ErrValidation))
+
+		_, err = importColumns(header, map[string]string{"type": "Kind", "description": "About"})
+		assert.True(t, errors.Is(err, local.ErrValidation))
+	})
+}
@@ -0,0 +1,396 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"sort"
+
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"xm-task/conf"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+const (
+	// logoHashLength is how much of the SHA-256 of the original ends up in the logo URLs.
+	logoHashLength = 16
+	logoQuality    = 90
+	// exifOrientation is the EXIF tag which tells how a JPEG should be rotated for display.
+	exifOrientation = 0x0112
+)
+
+// logoFormats are the accepted image formats, other decoders may be registered by dependencies.
+var logoFormats = map[string]bool{"png": true, "jpeg": true, "webp": true}
+
+func (s *ServiceFacade) GetCompanyLogo(companyID string, orgID uuid.UUID) (dmodels.CompanyLogo, error) {
+	logo, err := s.dao.GetCompanyLogo(companyID, orgID)
+	if err != nil {
+		return dmodels.CompanyLogo{}, daoError("dao.GetCompanyLogo", err)
+	}
+
+	return logo, nil
+}
+
+// OpenCompanyLogo returns the logo along with the content of the thumbnail with the size, zero means
+// the original. It serves the public logo URLs, so the hash has to match the current logo.
+// The caller closes the content.
+func (s *ServiceFacade) OpenCompanyLogo(companyID, hash string, size int) (dmodels.CompanyLogo, io.ReadCloser, error) {
+	if _, err := uuid.FromString(companyID); err != nil {
+		return dmodels.CompanyLogo{}, nil, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	logo, err := s.dao.FindCompanyLogo(companyID)
+	if err != nil {
+		return dmodels.CompanyLogo{}, nil, daoError("dao.FindCompanyLogo", err)
+	}
+	if logo.Hash != hash {
+		return dmodels.CompanyLogo{}, nil, fmt.Errorf("logo %s: %w", hash, local.ErrNotFound)
+	}
+	key, ok := logo.StorageKey(size)
+	if !ok {
+		return dmodels.CompanyLogo{}, nil, fmt.Errorf("logo size %d: %w", size, local.ErrNotFound)
+	}
+
+	content, err := s.dao.GetBlob(key)
+	if err != nil {
+		return dmodels.CompanyLogo{}, nil, daoError("dao.GetBlob", err)
+	}
+
+	return logo, content, nil
+}
+
+// UploadCompanyLogo replaces the logo of the company. The image is decoded and encoded again, which drops
+// its metadata, JPEGs keep their format and the rest is stored as PNG. Thumbnails of Logos.Sizes are
+// generated along with it, images are never scaled up.
+func (s *ServiceFacade) UploadCompanyLogo(companyID string, file io.Reader, user dmodels.User) (dmodels.CompanyLogo, error) {
+	company, err := s.modifiableCompany(companyID, user)
+	if err != nil {
+		return dmodels.CompanyLogo{}, err
+	}
+
+	maxSize := s.cfg.Logos.MaxFileSize
+	if maxSize <= 0 {
+		maxSize = conf.DefaultLogosMaxFileSize
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return dmodels.CompanyLogo{}, fmt.Errorf("file.Read: %v", err)
+	}
+	if int64(len(data)) > maxSize {
+		return dmodels.CompanyLogo{}, fmt.Errorf("%w: logo is larger than %d bytes", local.ErrRequestTooLarge, maxSize)
+	}
+	if len(data) == 0 {
+		return dmodels.CompanyLogo{}, fmt.Errorf("%w: file is empty", local.ErrValidation)
+	}
+
+	img, format, err := decodeLogo(data, s.logoMaxDimension())
+	if err != nil {
+		return dmodels.CompanyLogo{}, err
+	}
+	original, contentType, err := encodeLogo(img, format)
+	if err != nil {
+		return dmodels.CompanyLogo{}, err
+	}
+	sum := sha256.Sum256(original)
+
+	logo := dmodels.CompanyLogo{
+		CompanyID:   company.ID,
+		Hash:        hex.EncodeToString(sum[:])[:logoHashLength],
+		ContentType: contentType,
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Size:        int64(len(original)),
+		AuthorID:    uuid.NullUUID{UUID: user.ID, Valid: true},
+	}
+
+	// every upload gets its own keys, the blobs of the replaced logo are queued for removal
+	prefix := fmt.Sprintf("logos/%s/%s", company.ID, uuid.NewV4())
+	blobs := [][]byte{original}
+	logo.StorageKeys = append(logo.StorageKeys, prefix+"/original")
+	for _, size := range s.logoSizes() {
+		thumb, _, err := encodeLogo(thumbnail(img, size), format)
+		if err != nil {
+			return dmodels.CompanyLogo{}, err
+		}
+		blobs = append(blobs, thumb)
+		logo.Sizes = append(logo.Sizes, int64(size))
+		logo.StorageKeys = append(logo.StorageKeys, fmt.Sprintf("%s/%d", prefix, size))
+	}
+
+	for i, key := range logo.StorageKeys {
+		checksum := sha256.Sum256(blobs[i])
+		err := s.dao.PutBlob(key, bytes.NewReader(blobs[i]), int64(len(blobs[i])), contentType, hex.EncodeToString(checksum[:]))
+		if err != nil {
+			// nothing refers to the blobs yet, a failed removal only leaves orphans behind
+			s.deleteBlobs(logo.StorageKeys[:i])
+			return dmodels.CompanyLogo{}, fmt.Errorf("dao.PutBlob: %v", err)
+		}
+	}
+
+	previous, _ := s.dao.GetCompanyLogo(companyID, user.OrganizationID)
+
+	keys := logo.StorageKeys
+	err = s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+		logo, err = tx.SetCompanyLogo(logo, user.OrganizationID)
+		if err != nil {
+			return daoError("dao.SetCompanyLogo", err)
+		}
+		return logoEvents(tx, companyID, user.OrganizationID, events)
+	})
+	if err != nil {
+		s.deleteBlobs(keys)
+		return dmodels.CompanyLogo{}, err
+	}
+	s.dropBlobs(previous.StorageKeys)
+
+	return logo, nil
+}
+
+// DeleteCompanyLogo removes the logo, its blobs are removed the same way as the ones of attachments.
+func (s *ServiceFacade) DeleteCompanyLogo(companyID string, user dmodels.User) error {
+	if _, err := s.modifiableCompany(companyID, user); err != nil {
+		return err
+	}
+
+	logo, err := s.dao.GetCompanyLogo(companyID, user.OrganizationID)
+	if err != nil {
+		return daoError("dao.GetCompanyLogo", err)
+	}
+	err = s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+		if err := tx.DeleteCompanyLogo(companyID, user.OrganizationID); err != nil {
+			return daoError("dao.DeleteCompanyLogo", err)
+		}
+		return logoEvents(tx, companyID, user.OrganizationID, events)
+	})
+	if err != nil {
+		return err
+	}
+	s.dropBlobs(logo.StorageKeys)
+
+	return nil
+}
+
+// logoEvents reports the company as updated, since its version and logo_url change along with the logo.
+func logoEvents(tx dao.DAO, companyID string, orgID uuid.UUID, events *pendingEvents) error {
+	company, err := tx.GetCompanyByID(companyID, orgID)
+	if err != nil {
+		return daoError("dao.GetCompanyByID", err)
+	}
+	events.add(updatedCompaniesTopic, company)
+	return nil
+}
+
+func (s *ServiceFacade) deleteBlobs(keys []string) {
+	for _, key := range keys {
+		_ = s.dao.DeleteBlob(key)
+	}
+}
+
+func (s *ServiceFacade) logoMaxDimension() int {
+	if s.cfg.Logos.MaxDimension > 0 {
+		return s.cfg.Logos.MaxDimension
+	}
+	return conf.DefaultLogosMaxDimension
+}
+
+// logoSizes returns the configured thumbnail sizes in ascending order without duplicates.
+func (s *ServiceFacade) logoSizes() []int {
+	configured := s.cfg.Logos.Sizes
+	if len(configured) == 0 {
+		configured = conf.DefaultLogosSizes
+	}
+
+	sizes := make([]int, 0, len(configured))
+	for _, size := range configured {
+		if size > 0 {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Ints(sizes)
+
+	unique := sizes[:0]
+	for i, size := range sizes {
+		if i == 0 || size != sizes[i-1] {
+			unique = append(unique, size)
+		}
+	}
+	return unique
+}
+
+// decodeLogo checks the format and the dimensions before the image is decoded, so that large images
+// are rejected without allocating them. JPEGs are rotated according to their EXIF orientation.
+func decodeLogo(data []byte, maxDimension int) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !logoFormats[format] {
+		return nil, "", fmt.Errorf("%w: logo should be a PNG, JPEG or WebP image", local.ErrUnsupportedMediaType)
+	}
+	if cfg.Width > maxDimension || cfg.Height > maxDimension {
+		return nil, "", fmt.Errorf("%w: logo should be at most %dx%d pixels", local.ErrValidation, maxDimension, maxDimension)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: invalid image: %v", local.ErrValidation, err)
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	return img, format, nil
+}
+
+// encodeLogo returns the encoded image and its content type.
+func encodeLogo(img image.Image, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: logoQuality}); err != nil {
+			return nil, "", fmt.Errorf("jpeg.Encode: %v", err)
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", fmt.Errorf("png.Encode: %v", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// thumbnail scales the image down to fit into a square of the size, keeping its aspect ratio.
+func thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width >= height {
+		width, height = size, max(1, height*size/width)
+	} else {
+		width, height = max(1, width*size/height), size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// orient applies the EXIF orientation, 1 to 8, to the image.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	// orientations from 5 on swap the sides
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = width-1-x, y
+			case 3: // rotated by 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated by 90° clockwise
+				dx, dy = height-1-y, x
+			case 7: // transversed
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotated by 90° counterclockwise
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+
+	return dst
+}
+
+// jpegOrientation looks for the EXIF orientation in the APP1 segments of the JPEG, it returns 1,
+// the default one, when there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		// the metadata segments come before the image data
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		if segment := data[i+4 : i+2+length]; marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+
+	return 1
+}
+
+// tiffOrientation reads the orientation from the first IFD of the EXIF TIFF structure.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		// the orientation is a single SHORT kept in the value field
+		if order.Uint16(tiff[entry:]) == exifOrientation && order.Uint16(tiff[entry+2:]) == 3 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+
+	return 1
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xm-task/conf"
+	local "xm-task/helpers/errors"
+)
+
+func TestCompanyLogos(t *testing.T) {
+	// checks that thumbnails keep the aspect ratio and small images are not scaled up
+	t.Run("it should scale logos down", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(0, 0, 300, 150))
+		assert.Equal(t, image.Rect(0, 0, 128, 64), thumbnail(img, 128).Bounds())
+		assert.Equal(t, image.Rect(0, 0, 300, 150), thumbnail(img, 512).Bounds())
+	})
+
+	// checks that JPEGs are rotated according to their EXIF orientation
+	t.Run("it should orient JPEG logos", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 32; x++ {
+				c := color.RGBA{R: 255, A: 255}
+				if x >= 16 {
+					c = color.RGBA{B: 255, A: 255}
+				}
+				img.Set(x, y, c)
+			}
+		}
+		var buf bytes.Buffer
+		require.NoError(t, jpeg.Encode(&buf, img, nil))
+
+		// a big endian TIFF with the single orientation entry, 6 is rotated by 90° clockwise
+		tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, 6, 0, 0, 0, 0, 0, 0}
+		segment := append([]byte("Exif\x00\x00"), tiff...)
+		data := append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0, byte(len(segment) + 2)}, segment...)
+		data = append(data, buf.Bytes()[2:]...)
+		assert.Equal(t, 6, jpegOrientation(data))
+
+		decoded, format, err := decodeLogo(data, 64)
+		require.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		require.Equal(t, image.Rect(0, 0, 16, 32), decoded.Bounds())
+
+		r, _, b, _ := decoded.At(8, 4).RGBA()
+		assert.Greater(t, r, b)
+		r, _, b, _ = decoded.At(8, 28).RGBA()
+		assert.Greater(t, b, r)
+	})
+
+	// checks that other formats and oversized images are rejected
+	t.Run("it should reject invalid logos", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black}), nil))
+		_, _, err := decodeLogo(buf.Bytes(), 64)
+		assert.ErrorIs(t, err, local.ErrUnsupportedMediaType)
+
+		buf.Reset()
+		require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 128, 16)), nil))
+		_, _, err = decodeLogo(buf.Bytes(), 64)
+		assert.ErrorIs(t, err, local.ErrValidation)
+	})
+
+	// checks that the configured sizes are sorted and deduplicated
+	t.Run("it should normalize the thumbnail sizes", func(t *testing.T) {
+		s := &ServiceFacade{cfg: conf.Config{Logos: conf.Logos{Sizes: []int{256, 64, 0, 256}}}}
+		assert.Equal(t, []int{64, 256}, s.logoSizes())
+	})
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/helpers/rbac"
+)
+
+// TransferCompany hands the company over to the member of its organization with the given email.
+// A non-zero version has to match the stored one.
+func (s *ServiceFacade) TransferCompany(id string, email string, version uint64, user dmodels.User) (dmodels.CompanyShow, error) {
+	current, err := s.dao.GetCompanyByID(id, user.OrganizationID)
+	if err != nil {
+		return dmodels.CompanyShow{}, daoError("dao.GetCompanyByID", err)
+	}
+	if err := canModify(current.OwnerID, user); err != nil {
+		return dmodels.CompanyShow{}, err
+	}
+
+	owner, err := s.dao.GetMember(email, user.OrganizationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dmodels.CompanyShow{}, fmt.Errorf("%w: unknown member %q", local.ErrValidation, email)
+	}
+	if err != nil {
+		return dmodels.CompanyShow{}, fmt.Errorf("dao.GetMember: %v", err)
+	}
+
+	var company dmodels.CompanyShow
+	err = s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+		if err := tx.TransferCompany(id, user.OrganizationID, owner.ID, version, user.ID); err != nil {
+			return daoError("dao.TransferCompany", err)
+		}
+
+		company, err = tx.GetCompanyByID(id, user.OrganizationID)
+		if err != nil {
+			return daoError("dao.GetCompanyByID", err)
+		}
+		events.add(updatedCompaniesTopic, company)
+		return nil
+	})
+	if err != nil {
+		return dmodels.CompanyShow{}, err
+	}
+
+	return company, nil
+}
+
+// canModify allows changes to the owner of the company and to the roles managing every company.
+func canModify(ownerID *uuid.UUID, user dmodels.User) error {
+	if rbac.Can(user.Role, rbac.CompaniesManage) || (ownerID != nil && *ownerID == user.ID) {
+		return nil
+	}
+	return fmt.Errorf("user %s is not the owner: %w", user.ID, local.ErrForbidden)
+}
+
+// ownerOf returns the owner of the companies created by the user, commands run without one.
+func ownerOf(user dmodels.User) *uuid.UUID {
+	if user.ID == uuid.Nil {
+		return nil
+	}
+	id := user.ID
+	return &id
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	uuid "github.com/satori/go.uuid"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/smodels"
+)
+
+// patchAttempts limits retries when the company changes between reading and writing it.
+const patchAttempts = 3
+
+// PatchCompany applies a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) to the
+// stored company. A non-zero version has to match the stored one.
+func (s *ServiceFacade) PatchCompany(id string, contentType string, patch []byte, version uint64, user dmodels.User) (dmodels.CompanyShow, error) {
+	return s.patchCompany(id, user.OrganizationID, contentType, patch, version, user.ID, func(current dmodels.CompanyShow) error {
+		return canModify(current.OwnerID, user)
+	}, nil)
+}
+
+// patchCompany applies the patch on behalf of the author, authorize is called with every company read before it is changed.
+// A non-nil within is called in the transaction of the change, so that its writes are committed along with it.
+func (s *ServiceFacade) patchCompany(id string, orgID uuid.UUID, contentType string, patch []byte, version uint64,
+	authorID uuid.UUID, authorize func(current dmodels.CompanyShow) error, within func(tx dao.DAO) error) (dmodels.CompanyShow, error) {
+	cUUID, err := uuid.FromString(id)
+	if err != nil {
+		return dmodels.CompanyShow{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	for attempt := 1; ; attempt++ {
+		current, err := s.dao.GetCompanyByID(id, orgID)
+		if err != nil {
+			return dmodels.CompanyShow{}, daoError("dao.GetCompanyByID", err)
+		}
+		if err := authorize(current); err != nil {
+			return dmodels.CompanyShow{}, err
+		}
+		if version != 0 && version != current.Version {
+			return dmodels.CompanyShow{}, fmt.Errorf("version %d: %w", current.Version, local.ErrPreconditionFailed)
+		}
+
+		patched, err := applyCompanyPatch(current, contentType, patch)
+		if err != nil {
+			return dmodels.CompanyShow{}, err
+		}
+
+		ct, err := s.companyType(patched.Type, current.Type)
+		if err != nil {
+			return dmodels.CompanyShow{}, err
+		}
+		if err := s.validateAttributes(ct, patched.Attributes); err != nil {
+			return dmodels.CompanyShow{}, err
+		}
+
+		// the version read above guards against changes made after the patch was applied
+		var company dmodels.CompanyShow
+		err = s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+			updatedCompany, err := tx.UpdateCompany(dmodels.Company{
+				ID:             cUUID,
+				Name:           patched.Name,
+				Description:    patched.Description,
+				Employees:      patched.Employees,
+				Registered:     patched.Registered,
+				TypeID:         ct.ID,
+				Attributes:     patched.Attributes,
+				UpdatedAt:      time.Now(),
+				Version:        current.Version,
+				OrganizationID: orgID,
+			}, authorID)
+			if err != nil {
+				return daoError("dao.UpdateCompany", err)
+			}
+			events.add(updatedCompaniesTopic, updatedCompany)
+
+			company, err = tx.GetCompanyByID(id, orgID)
+			if err != nil {
+				return daoError("dao.GetCompanyByID", err)
+			}
+			if within != nil {
+				return within(tx)
+			}
+			return nil
+		})
+		if errors.Is(err, local.ErrPreconditionFailed) && version == 0 && attempt < patchAttempts {
+			continue
+		}
+		if err != nil {
+			return dmodels.CompanyShow{}, err
+		}
+
+		return company, nil
+	}
+}
+
+func applyCompanyPatch(current dmodels.CompanyShow, contentType string, patch []byte) (smodels.Company, error) {
+	doc, err := json.Marshal(smodels.Company{
+		Name:        current.Name,
+		Description: current.Description,
+		Employees:   current.Employees,
+		Registered:  current.Registered,
+		Type:        current.Type,
+		Attributes:  current.Attributes,
+	})
+	if err != nil {
+		return smodels.Company{}, err
+	}
+
+	switch contentType {
+	case smodels.MergePatchContentType, smodels.JSONContentType:
+		if !json.Valid(patch) || !bytes.HasPrefix(bytes.TrimSpace(patch), []byte("{")) {
+			return smodels.Company{}, fmt.Errorf("%w: merge patch should be a JSON object", local.ErrValidation)
+		}
+		doc, err = jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			return smodels.Company{}, fmt.Errorf("%w: %v", local.ErrUnprocessable, err)
+		}
+	case smodels.JSONPatchContentType:
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return smodels.Company{}, fmt.Errorf("%w: %v", local.ErrValidation, err)
+		}
+		doc, err = operations.Apply(doc)
+		if err != nil {
+			return smodels.Company{}, fmt.Errorf("%w: %v", local.ErrUnprocessable, err)
+		}
+	default:
+		return smodels.Company{}, fmt.Errorf("%w: %s", local.ErrUnsupportedMediaType, contentType)
+	}
+
+	var patched smodels.Company
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return smodels.Company{}, fmt.Errorf("%w: %v", local.ErrValidation, err)
+	}
+
+	if err := patched.Validate(); err != nil {
+		return smodels.Company{}, fmt.Errorf("%w: %v", local.ErrValidation, err)
+	}
+
+	return patched, nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/smodels"
+)
+
+func TestApplyCompanyPatch(t *testing.T) {
+	current := dmodels.CompanyShow{
+		Name:        "Test Company",
+		Description: "some description",
+		Employees:   100,
+		Registered:  true,
+		Type:        "Corporations",
+	}
+
+	// checks that merge patch keeps missing fields and writes zero values
+	t.Run("it should apply merge patch", func(t *testing.T) {
+		patched, err := applyCompanyPatch(current, smodels.MergePatchContentType,
+			[]byte(`{"registered": false, "employees_count": 0}`))
+		require.NoError(t, err)
+
+		assert.Equal(t, "Test Company", patched.Name)
+		assert.Equal(t, "some description", patched.Description)
+		assert.Equal(t, uint64(0), patched.Employees)
+		assert.False(t, patched.Registered)
+	})
+
+	// checks json patch operations
+	t.Run("it should apply json patch", func(t *testing.T) {
+		patched, err := applyCompanyPatch(current, smodels.JSONPatchContentType,
+			[]byte(`[{"op": "test", "path": "/employees_count", "value": 100}, {"op": "replace", "path": "/description", "value": ""}]`))
+		require.NoError(t, err)
+
+		assert.Equal(t, "", patched.Description)
+		assert.Equal(t, uint64(100), patched.Employees)
+	})
+
+	// checks failed test operation
+	t.Run("it should reject failed test operation", func(t *testing.T) {
+		_, err := applyCompanyPatch(current, smodels.JSONPatchContentType,
+			[]byte(`[{"op": "test", "path": "/employees_count", "value": 1}]`))
+		assert.True(t, errors.Is(err, local.ErrUnprocessable))
+	})
+
+	// checks that the patched company is validated as a whole
+	t.Run("it should validate patched company", func(t *testing.T) {
+		_, err := applyCompanyPatch(current, smodels.MergePatchContentType, []byte(`{"type": null}`))
+		assert.True(t, errors.Is(err, local.ErrValidation))
+
+		_, err = applyCompanyPatch(current, smodels.MergePatchContentType, []byte(`{"unknown": 1}`))
+		assert.True(t, errors.Is(err, local.ErrValidation))
+	})
+
+	// checks unsupported content type
+	t.Run("it should reject unknown content type", func(t *testing.T) {
+		_, err := applyCompanyPatch(current, "text/plain", []byte(`{}`))
+		assert.True(t, errors.Is(err, local.ErrUnsupportedMediaType))
+	})
+}
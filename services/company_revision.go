@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/smodels"
+)
+
+func (s *ServiceFacade) ListCompanyRevisions(companyID string, orgID uuid.UUID) ([]dmodels.CompanyRevision, error) {
+	revisions, err := s.dao.ListCompanyRevisions(companyID, orgID)
+	if err != nil {
+		return nil, daoError("dao.ListCompanyRevisions", err)
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("dao.ListCompanyRevisions: %w", local.ErrNotFound)
+	}
+
+	return revisions, nil
+}
+
+func (s *ServiceFacade) GetCompanyRevision(companyID string, orgID uuid.UUID, revision uint64) (dmodels.CompanyRevision, error) {
+	rev, err := s.dao.GetCompanyRevision(companyID, orgID, revision)
+	if err != nil {
+		return dmodels.CompanyRevision{}, daoError("dao.GetCompanyRevision", err)
+	}
+
+	return rev, nil
+}
+
+func (s *ServiceFacade) DiffCompanyRevisions(companyID string, orgID uuid.UUID, from, to uint64) (smodels.RevisionDiff, error) {
+	fromRev, err := s.dao.GetCompanyRevision(companyID, orgID, from)
+	if err != nil {
+		return smodels.RevisionDiff{}, daoError("dao.GetCompanyRevision", err)
+	}
+
+	toRev, err := s.dao.GetCompanyRevision(companyID, orgID, to)
+	if err != nil {
+		return smodels.RevisionDiff{}, daoError("dao.GetCompanyRevision", err)
+	}
+
+	return smodels.RevisionDiff{
+		From:    from,
+		To:      to,
+		Changes: diffSnapshots(fromRev.Snapshot, toRev.Snapshot),
+	}, nil
+}
+
+// RevertCompany writes the values of the given revision back to the company,
+// the revert itself is recorded as a new revision.
+func (s *ServiceFacade) RevertCompany(companyID string, revision uint64, user dmodels.User) (dmodels.CompanyShow, error) {
+	rev, err := s.dao.GetCompanyRevision(companyID, user.OrganizationID, revision)
+	if err != nil {
+		return dmodels.CompanyShow{}, daoError("dao.GetCompanyRevision", err)
+	}
+
+	cUUID, err := uuid.FromString(companyID)
+	if err != nil {
+		return dmodels.CompanyShow{}, fmt.Errorf("uuid.FromString: %v", err)
+	}
+
+	current, err := s.dao.GetCompanyByID(companyID, user.OrganizationID)
+	if err != nil {
+		return dmodels.CompanyShow{}, daoError("dao.GetCompanyByID", err)
+	}
+	if err := canModify(current.OwnerID, user); err != nil {
+		return dmodels.CompanyShow{}, err
+	}
+
+	// the type of the revision could have been merged into another one or deprecated since
+	ct, err := s.dao.GetCompanyTypeByID(rev.Snapshot.TypeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dmodels.CompanyShow{}, fmt.Errorf("%w: company type of revision %d no longer exists", local.ErrUnprocessable, revision)
+	}
+	if err != nil {
+		return dmodels.CompanyShow{}, fmt.Errorf("dao.GetCompanyTypeByID: %v", err)
+	}
+	if ct.Deprecated() && ct.Name != current.Type {
+		return dmodels.CompanyShow{}, fmt.Errorf("%w: company type %q is deprecated", local.ErrUnprocessable, ct.Name)
+	}
+
+	// revisions recorded before attributes existed have none, the schema could have changed since too
+	attributes := rev.Snapshot.Attributes
+	if attributes == nil {
+		attributes = dmodels.JSONObject{}
+	}
+	err = s.validateAttributes(ct, attributes)
+	if errors.Is(err, local.ErrValidation) {
+		return dmodels.CompanyShow{}, fmt.Errorf("%w: %s", local.ErrUnprocessable,
+			strings.TrimPrefix(err.Error(), local.ErrValidation.Error()+": "))
+	}
+	if err != nil {
+		return dmodels.CompanyShow{}, err
+	}
+
+	var company dmodels.CompanyShow
+	err = s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+		revertedCompany, err := tx.RevertCompany(dmodels.Company{
+			ID:             cUUID,
+			Name:           rev.Snapshot.Name,
+			Description:    rev.Snapshot.Description,
+			Employees:      rev.Snapshot.Employees,
+			Registered:     rev.Snapshot.Registered,
+			TypeID:         rev.Snapshot.TypeID,
+			Attributes:     attributes,
+			UpdatedAt:      time.Now(),
+			OrganizationID: user.OrganizationID,
+		}, user.ID, revision)
+		if err != nil {
+			return daoError("dao.RevertCompany", err)
+		}
+		events.add(updatedCompaniesTopic, revertedCompany)
+
+		company, err = tx.GetCompanyByID(companyID, user.OrganizationID)
+		if err != nil {
+			return daoError("dao.GetCompanyByID", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return dmodels.CompanyShow{}, err
+	}
+
+	return company, nil
+}
+
+// attributeKeys returns the keys of both attribute sets in order.
+func attributeKeys(from, to dmodels.JSONObject) []string {
+	keys := make([]string, 0, len(from)+len(to))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func diffSnapshots(from, to dmodels.CompanySnapshot) []smodels.FieldChange {
+	changes := make([]smodels.FieldChange, 0)
+	add := func(field string, a, b interface{}) {
+		if a != b {
+			changes = append(changes, smodels.FieldChange{Field: field, From: a, To: b})
+		}
+	}
+
+	add("name", from.Name, to.Name)
+	add("description", from.Description, to.Description)
+	add("employees_count", from.Employees, to.Employees)
+	add("registered", from.Registered, to.Registered)
+	add("type", from.Type, to.Type)
+	add("deleted", from.DeletedAt != nil, to.DeletedAt != nil)
+	add("owner_id", uuidString(from.OwnerID), uuidString(to.OwnerID))
+	add("parent_id", uuidString(from.ParentID), uuidString(to.ParentID))
+	for _, key := range attributeKeys(from.Attributes, to.Attributes) {
+		if !reflect.DeepEqual(from.Attributes[key], to.Attributes[key]) {
+			changes = append(changes, smodels.FieldChange{
+				Field: "attributes." + key,
+				From:  from.Attributes[key],
+				To:    to.Attributes[key],
+			})
+		}
+	}
+
+	return changes
+}
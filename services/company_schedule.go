@@ -0,0 +1,229 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	uuid "github.com/satori/go.uuid"
+	"xm-task/conf"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/helpers/rbac"
+	"xm-task/smodels"
+)
+
+// ScheduleConflictError is returned by CreateCompanySchedule when pending changes of the company touch
+// the same fields. Their order cannot be relied on even at different times: the changes may be applied by
+// different workers at once, and a change retried after an error takes effect after the later ones.
+type ScheduleConflictError struct {
+	Schedules []dmodels.CompanySchedule
+}
+
+func (e *ScheduleConflictError) Error() string {
+	return fmt.Sprintf("%s: %d pending changes", local.ReasonScheduleConflict, len(e.Schedules))
+}
+
+func (e *ScheduleConflictError) Unwrap() error {
+	return local.ErrScheduleConflict
+}
+
+func (s *ServiceFacade) ListCompanySchedules(companyID string, status string, orgID uuid.UUID) ([]dmodels.CompanySchedule, error) {
+	if _, err := s.dao.GetCompanyByID(companyID, orgID); err != nil {
+		return nil, daoError("dao.GetCompanyByID", err)
+	}
+
+	schedules, err := s.dao.ListCompanySchedules(companyID, status, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("dao.ListCompanySchedules: %v", err)
+	}
+
+	return schedules, nil
+}
+
+func (s *ServiceFacade) GetCompanySchedule(companyID, id string, orgID uuid.UUID) (dmodels.CompanySchedule, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return dmodels.CompanySchedule{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	schedule, err := s.dao.GetCompanySchedule(companyID, id, orgID)
+	if err != nil {
+		return dmodels.CompanySchedule{}, daoError("dao.GetCompanySchedule", err)
+	}
+
+	return schedule, nil
+}
+
+// CreateCompanySchedule schedules the change of the company. The change is checked against the current
+// company now and once again when it takes effect, since the company may change in between.
+func (s *ServiceFacade) CreateCompanySchedule(companyID string, schedule smodels.CompanySchedule, user dmodels.User) (dmodels.CompanySchedule, error) {
+	company, err := s.modifiableCompany(companyID, user)
+	if err != nil {
+		return dmodels.CompanySchedule{}, err
+	}
+
+	patch, err := json.Marshal(schedule.Changes)
+	if err != nil {
+		return dmodels.CompanySchedule{}, fmt.Errorf("%w: %v", local.ErrValidation, err)
+	}
+	patched, err := applyCompanyPatch(company, smodels.MergePatchContentType, patch)
+	if err != nil {
+		return dmodels.CompanySchedule{}, err
+	}
+	ct, err := s.companyType(patched.Type, company.Type)
+	if err != nil {
+		return dmodels.CompanySchedule{}, err
+	}
+	if err := s.validateAttributes(ct, patched.Attributes); err != nil {
+		return dmodels.CompanySchedule{}, err
+	}
+
+	created, conflicts, err := s.dao.CreateCompanySchedule(dmodels.CompanySchedule{
+		ID:             uuid.NewV4(),
+		OrganizationID: user.OrganizationID,
+		CompanyID:      company.ID,
+		Changes:        schedule.Changes,
+		Fields:         scheduleFields(schedule.Changes),
+		EffectiveAt:    schedule.EffectiveAt.UTC(),
+		Status:         dmodels.ScheduleStatusPending,
+		AuthorID:       uuid.NullUUID{UUID: user.ID, Valid: true},
+	})
+	if err != nil {
+		return dmodels.CompanySchedule{}, daoError("dao.CreateCompanySchedule", err)
+	}
+	if len(conflicts) > 0 {
+		return dmodels.CompanySchedule{}, &ScheduleConflictError{Schedules: conflicts}
+	}
+	created.AuthorEmail = user.Email
+
+	return created, nil
+}
+
+// CancelCompanySchedule cancels a pending change, changes which took effect or are being applied cannot be cancelled.
+func (s *ServiceFacade) CancelCompanySchedule(companyID, id string, user dmodels.User) (dmodels.CompanySchedule, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return dmodels.CompanySchedule{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+	if _, err := s.modifiableCompany(companyID, user); err != nil {
+		return dmodels.CompanySchedule{}, err
+	}
+
+	schedule, err := s.dao.CancelCompanySchedule(companyID, id, user.OrganizationID)
+	if err != nil {
+		return dmodels.CompanySchedule{}, daoError("dao.CancelCompanySchedule", err)
+	}
+
+	return schedule, nil
+}
+
+// ApplyDueSchedules applies a batch of changes which took effect, the earliest first, and returns how many
+// were applied and how many failed. Changes which hit an unexpected error stay pending and are retried
+// once their claim times out, the last such error is returned.
+func (s *ServiceFacade) ApplyDueSchedules() (int, int, error) {
+	limit := s.cfg.Schedules.BatchSize
+	if limit <= 0 {
+		limit = conf.DefaultSchedulesBatchSize
+	}
+	claimTimeout := s.cfg.Schedules.ClaimTimeout
+	if claimTimeout <= 0 {
+		claimTimeout = conf.DefaultSchedulesClaimTimeout
+	}
+
+	schedules, err := s.dao.ClaimDueCompanySchedules(limit, claimTimeout)
+	if err != nil {
+		return 0, 0, fmt.Errorf("dao.ClaimDueCompanySchedules: %v", err)
+	}
+
+	var applied, failed int
+	var lastErr error
+	for _, schedule := range schedules {
+		err := s.applySchedule(schedule)
+		if err == nil {
+			applied++
+			continue
+		}
+		reason, ok := scheduleFailure(err)
+		if !ok {
+			lastErr = fmt.Errorf("schedule %s: %w", schedule.ID, err)
+			continue
+		}
+
+		if err := s.dao.FinishCompanySchedule(schedule.ID, dmodels.ScheduleStatusFailed, reason); err != nil {
+			lastErr = fmt.Errorf("dao.FinishCompanySchedule: %v", err)
+			continue
+		}
+		failed++
+	}
+
+	return applied, failed, lastErr
+}
+
+// applySchedule patches the company on behalf of the author of the change. The author has to be a member of the
+// organization still, with a role allowed to change the company as it is by now. The update is published like any
+// other one. The change is marked applied in the transaction of the update, so that it is not applied twice.
+func (s *ServiceFacade) applySchedule(schedule dmodels.CompanySchedule) error {
+	patch, err := json.Marshal(schedule.Changes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", local.ErrValidation, err)
+	}
+
+	// authors removed from the users are no longer members of any organization
+	if !schedule.AuthorID.Valid || schedule.AuthorEmail == "" {
+		return fmt.Errorf("author removed: %w", local.ErrNotMember)
+	}
+	author, err := s.GetMember(schedule.AuthorEmail, schedule.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.patchCompany(schedule.CompanyID.String(), schedule.OrganizationID, smodels.MergePatchContentType, patch, 0,
+		author.ID, func(current dmodels.CompanyShow) error {
+			if !rbac.Can(author.Role, rbac.CompaniesWrite) {
+				return fmt.Errorf("role %s: %w", author.Role, local.ErrForbidden)
+			}
+			return canModify(current.OwnerID, author)
+		}, func(tx dao.DAO) error {
+			if err := tx.FinishCompanySchedule(schedule.ID, dmodels.ScheduleStatusApplied, ""); err != nil {
+				return fmt.Errorf("dao.FinishCompanySchedule: %v", err)
+			}
+			return nil
+		})
+	return err
+}
+
+// scheduleFailure tells whether the change cannot be applied at all, along with the reason stored with it.
+func scheduleFailure(err error) (string, bool) {
+	switch {
+	case errors.Is(err, local.ErrNotFound):
+		return "company not found", true
+	case errors.Is(err, local.ErrNotMember):
+		return "author is no longer a member", true
+	case errors.Is(err, local.ErrForbidden):
+		return "author is not allowed to change the company", true
+	case errors.Is(err, local.ErrConflict):
+		return "company name is taken", true
+	case errors.Is(err, local.ErrValidation), errors.Is(err, local.ErrUnprocessable):
+		return err.Error(), true
+	}
+	return "", false
+}
+
+// scheduleFields returns the sorted paths the merge patch touches. Attributes are tracked per key
+// unless the patch replaces all of them.
+func scheduleFields(changes map[string]interface{}) []string {
+	fields := make([]string, 0, len(changes))
+	for field, value := range changes {
+		attributes, ok := value.(map[string]interface{})
+		if field != "attributes" || !ok || len(attributes) == 0 {
+			fields = append(fields, field)
+			continue
+		}
+		for key := range attributes {
+			fields = append(fields, field+"."+key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
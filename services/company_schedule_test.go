@@ -0,0 +1,54 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/helpers/rbac"
+)
+
+func TestCompanySchedules(t *testing.T) {
+	// checks that attributes are tracked per key, so that changes of different attributes do not conflict
+	t.Run("it should list the touched fields", func(t *testing.T) {
+		fields := scheduleFields(map[string]interface{}{
+			"registered": true,
+			"name":       "Acme Corp",
+			"attributes": map[string]interface{}{"vat_id": "DE123456789", "size": nil},
+		})
+		assert.Equal(t, []string{"attributes.size", "attributes.vat_id", "name", "registered"}, fields)
+
+		fields = scheduleFields(map[string]interface{}{"attributes": nil})
+		assert.Equal(t, []string{"attributes"}, fields)
+	})
+
+	// checks that only changes which can never be applied are marked as failed
+	t.Run("it should tell failures from transient errors", func(t *testing.T) {
+		reason, ok := scheduleFailure(daoError("dao.GetCompanyByID", gorm.ErrRecordNotFound))
+		assert.True(t, ok)
+		assert.Equal(t, "company not found", reason)
+
+		reason, ok = scheduleFailure(canModify(nil, dmodels.User{ID: uuid.NewV4(), Role: rbac.RoleEditor}))
+		assert.True(t, ok)
+		assert.Equal(t, "author is not allowed to change the company", reason)
+
+		reason, ok = scheduleFailure(fmt.Errorf("dao.GetMember: %w", local.ErrNotMember))
+		assert.True(t, ok)
+		assert.Equal(t, "author is no longer a member", reason)
+
+		_, ok = scheduleFailure(errors.New("dao.UpdateCompany: connection refused"))
+		assert.False(t, ok)
+	})
+
+	// checks that the handlers can tell schedule conflicts apart from other conflicts
+	t.Run("it should wrap schedule conflicts", func(t *testing.T) {
+		var err error = &ScheduleConflictError{Schedules: []dmodels.CompanySchedule{{Fields: []string{"name"}}}}
+		assert.True(t, errors.Is(err, local.ErrScheduleConflict))
+		assert.False(t, errors.Is(err, local.ErrConflict))
+	})
+}
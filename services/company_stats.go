@@ -0,0 +1,62 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"xm-task/conf"
+	"xm-task/dmodels"
+	"xm-task/smodels"
+)
+
+// CompanyStats aggregates the companies matched by the filters. Results are cached for the configured TTL
+// per filter and windows, so that dashboards polling the endpoint do not hit the database each time.
+func (s *ServiceFacade) CompanyStats(params smodels.CompanyStatsParams) (dmodels.CompanyStats, error) {
+	filter := companyFilter(params.CompanyFilterParams)
+	key, err := statsKey(filter, params.Windows)
+	if err != nil {
+		return dmodels.CompanyStats{}, err
+	}
+	if stats, ok, _ := s.dao.GetCompanyStats(key); ok {
+		return stats, nil
+	}
+
+	now := time.Now().UTC()
+	query := dmodels.CompanyStatsQuery{
+		Filter:  filter,
+		Windows: make([]time.Time, 0, len(params.Durations)),
+	}
+	for _, d := range params.Durations {
+		query.Windows = append(query.Windows, now.Add(-d))
+	}
+
+	stats, err := s.dao.CompanyStats(query)
+	if err != nil {
+		return dmodels.CompanyStats{}, fmt.Errorf("dao.CompanyStats: %v", err)
+	}
+	stats.ComputedAt = now
+
+	s.dao.AddCompanyStats(key, stats, s.statsCacheTTL())
+
+	return stats, nil
+}
+
+func (s *ServiceFacade) statsCacheTTL() time.Duration {
+	if s.cfg.Stats.CacheTTL <= 0 {
+		return conf.DefaultStatsCacheTTL
+	}
+	return s.cfg.Stats.CacheTTL
+}
+
+// statsKey identifies the statistics of a filter, the organization and owner are part of the filter.
+func statsKey(filter dmodels.CompanyFilter, windows string) (string, error) {
+	data, err := json.Marshal(struct {
+		Filter  dmodels.CompanyFilter
+		Windows string
+	}{filter, windows})
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal: %v", err)
+	}
+	return string(data), nil
+}
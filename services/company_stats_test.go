@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xm-task/smodels"
+)
+
+func TestCompanyStatsCache(t *testing.T) {
+	// checks that cached statistics are never shared across organizations or owners
+	t.Run("it should key statistics by the whole filter", func(t *testing.T) {
+		params := smodels.CompanyStatsParams{}
+		require.NoError(t, params.Validate())
+
+		params.OrganizationID = uuid.NewV4().String()
+		first, err := statsKey(companyFilter(params.CompanyFilterParams), params.Windows)
+		require.NoError(t, err)
+
+		params.OrganizationID = uuid.NewV4().String()
+		second, err := statsKey(companyFilter(params.CompanyFilterParams), params.Windows)
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+
+		params.OwnerID = uuid.NewV4().String()
+		third, err := statsKey(companyFilter(params.CompanyFilterParams), params.Windows)
+		require.NoError(t, err)
+		assert.NotEqual(t, second, third)
+
+		again, err := statsKey(companyFilter(params.CompanyFilterParams), params.Windows)
+		require.NoError(t, err)
+		assert.Equal(t, third, again)
+	})
+
+	// checks that windows accept days along with Go durations
+	t.Run("it should parse windows", func(t *testing.T) {
+		params := smodels.CompanyStatsParams{Windows: "12h, 7d"}
+		require.NoError(t, params.Validate())
+		assert.Equal(t, []time.Duration{time.Hour * 12, time.Hour * 24 * 7}, params.Durations)
+		assert.Equal(t, []string{"12h", "7d"}, params.WindowNames())
+
+		params = smodels.CompanyStatsParams{Windows: "400d"}
+		assert.Error(t, params.Validate())
+
+		params = smodels.CompanyStatsParams{Windows: "-1h"}
+		assert.Error(t, params.Validate())
+	})
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/smodels"
+)
+
+func (s *ServiceFacade) ListCompanyTypes(withDeprecated bool) ([]dmodels.CompanyType, error) {
+	types, err := s.dao.ListCompanyTypes(withDeprecated)
+	if err != nil {
+		return nil, fmt.Errorf("dao.ListCompanyTypes: %v", err)
+	}
+
+	return types, nil
+}
+
+func (s *ServiceFacade) CreateCompanyType(ct smodels.CompanyType) (dmodels.CompanyType, error) {
+	if err := checkAttributesSchema(ct.AttributesSchema); err != nil {
+		return dmodels.CompanyType{}, err
+	}
+
+	created, err := s.dao.CreateCompanyType(dmodels.CompanyType{Name: ct.Name, AttributesSchema: ct.AttributesSchema})
+	if err != nil {
+		return dmodels.CompanyType{}, daoError("dao.CreateCompanyType", err)
+	}
+
+	return created, nil
+}
+
+func (s *ServiceFacade) UpdateCompanyType(id uint64, update smodels.CompanyTypeUpdate) (dmodels.CompanyType, error) {
+	ct, err := s.dao.GetCompanyTypeByID(id)
+	if err != nil {
+		return dmodels.CompanyType{}, daoError("dao.GetCompanyTypeByID", err)
+	}
+
+	if update.Name != nil {
+		ct.Name = *update.Name
+	}
+	if update.AttributesSchema != nil {
+		schema, err := update.Schema()
+		if err != nil {
+			return dmodels.CompanyType{}, fmt.Errorf("%w: %v", local.ErrValidation, err)
+		}
+		// companies keep their attributes, they are checked against the new schema on their next change
+		if err := checkAttributesSchema(schema); err != nil {
+			return dmodels.CompanyType{}, err
+		}
+		ct.AttributesSchema = schema
+	}
+	if update.Deprecated != nil && *update.Deprecated != ct.Deprecated() {
+		ct.DeprecatedAt = nil
+		if *update.Deprecated {
+			now := time.Now()
+			ct.DeprecatedAt = &now
+		}
+	}
+
+	ct, err = s.dao.UpdateCompanyType(ct)
+	if err != nil {
+		return dmodels.CompanyType{}, daoError("dao.UpdateCompanyType", err)
+	}
+
+	s.dao.RemoveCompanyTypes()
+
+	return ct, nil
+}
+
+// MergeCompanyTypes moves companies of the type to the target type and removes the type.
+// It returns the target type and the number of moved companies.
+func (s *ServiceFacade) MergeCompanyTypes(id, into uint64, user dmodels.User) (dmodels.CompanyType, int, error) {
+	if id == into {
+		return dmodels.CompanyType{}, 0, fmt.Errorf("%w: cannot merge company type into itself", local.ErrValidation)
+	}
+
+	if _, err := s.dao.GetCompanyTypeByID(id); err != nil {
+		return dmodels.CompanyType{}, 0, daoError("dao.GetCompanyTypeByID", err)
+	}
+
+	target, err := s.dao.GetCompanyTypeByID(into)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dmodels.CompanyType{}, 0, fmt.Errorf("%w: unknown company type %d", local.ErrValidation, into)
+	}
+	if err != nil {
+		return dmodels.CompanyType{}, 0, fmt.Errorf("dao.GetCompanyTypeByID: %v", err)
+	}
+	if target.Deprecated() {
+		return dmodels.CompanyType{}, 0, fmt.Errorf("%w: company type %q is deprecated", local.ErrValidation, target.Name)
+	}
+
+	var companies []dmodels.Company
+	err = s.mutate(func(tx dao.DAO, events *pendingEvents) error {
+		companies, err = tx.MergeCompanyTypes(id, into, user.ID)
+		if err != nil {
+			return daoError("dao.MergeCompanyTypes", err)
+		}
+		for _, company := range companies {
+			events.add(updatedCompaniesTopic, company)
+		}
+		return nil
+	})
+	if err != nil {
+		return dmodels.CompanyType{}, 0, err
+	}
+
+	s.dao.RemoveCompanyTypes()
+	go s.kafka.Flush(1000)
+
+	return target, len(companies), nil
+}
+
+// companyType looks a type up by name through the cache. Deprecated types are rejected,
+// unless the type is the one the company already has, which is passed as current.
+func (s *ServiceFacade) companyType(name, current string) (dmodels.CompanyType, error) {
+	ct, ok, _ := s.dao.GetCompanyType(name)
+	if !ok {
+		var err error
+		ct, err = s.dao.GetCompanyTypeByName(name)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dmodels.CompanyType{}, fmt.Errorf("%w: unknown company type %q", local.ErrValidation, name)
+		}
+		if err != nil {
+			return dmodels.CompanyType{}, fmt.Errorf("dao.GetCompanyTypeByName: %v", err)
+		}
+		s.dao.AddCompanyType(ct)
+	}
+
+	if ct.Deprecated() && name != current {
+		return dmodels.CompanyType{}, fmt.Errorf("%w: company type %q is deprecated", local.ErrValidation, name)
+	}
+
+	return ct, nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+// cursorPayload is the JSON form of an opaque page cursor. The sort
+// signature is kept so that a cursor cannot be replayed against another order.
+type cursorPayload struct {
+	Sort     string        `json:"s"`
+	Values   []interface{} `json:"v"`
+	ID       string        `json:"id"`
+	Backward bool          `json:"b,omitempty"`
+}
+
+func parseCompanySort(fields []string) []dmodels.CompanySort {
+	sorts := make([]dmodels.CompanySort, 0, len(fields))
+	for _, field := range fields {
+		sorts = append(sorts, dmodels.CompanySort{
+			Field: strings.TrimPrefix(field, "-"),
+			Desc:  strings.HasPrefix(field, "-"),
+		})
+	}
+	if len(sorts) == 0 {
+		sorts = append(sorts, dmodels.CompanySort{Field: dmodels.CompanySortCreatedAt})
+	}
+	return sorts
+}
+
+func sortSignature(sorts []dmodels.CompanySort) string {
+	parts := make([]string, 0, len(sorts))
+	for _, s := range sorts {
+		if s.Desc {
+			parts = append(parts, "-"+s.Field)
+		} else {
+			parts = append(parts, s.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func encodeCursor(sorts []dmodels.CompanySort, company dmodels.CompanyShow, backward bool) string {
+	payload := cursorPayload{
+		Sort:     sortSignature(sorts),
+		Values:   make([]interface{}, 0, len(sorts)),
+		ID:       company.ID.String(),
+		Backward: backward,
+	}
+	for _, s := range sorts {
+		payload.Values = append(payload.Values, companySortValue(company, s.Field))
+	}
+
+	data, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(sorts []dmodels.CompanySort, cursor string) (*dmodels.CompanyCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, local.ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, local.ErrInvalidCursor
+	}
+	if payload.Sort != sortSignature(sorts) || len(payload.Values) != len(sorts) {
+		return nil, fmt.Errorf("%w: sort order has changed", local.ErrInvalidCursor)
+	}
+
+	id, err := uuid.FromString(payload.ID)
+	if err != nil {
+		return nil, local.ErrInvalidCursor
+	}
+
+	values := make([]interface{}, 0, len(sorts))
+	for i, s := range sorts {
+		value, err := parseSortValue(s.Field, payload.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", local.ErrInvalidCursor, err)
+		}
+		values = append(values, value)
+	}
+
+	return &dmodels.CompanyCursor{
+		Values:   values,
+		ID:       id,
+		Backward: payload.Backward,
+	}, nil
+}
+
+func companySortValue(company dmodels.CompanyShow, field string) interface{} {
+	switch field {
+	case dmodels.CompanySortName:
+		return company.Name
+	case dmodels.CompanySortEmployees:
+		return company.Employees
+	case dmodels.CompanySortRegistered:
+		return company.Registered
+	case dmodels.CompanySortType:
+		return company.Type
+	case dmodels.CompanySortCreatedAt:
+		return company.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return nil
+}
+
+func parseSortValue(field string, raw interface{}) (interface{}, error) {
+	switch field {
+	case dmodels.CompanySortName, dmodels.CompanySortType:
+		if v, ok := raw.(string); ok {
+			return v, nil
+		}
+	case dmodels.CompanySortEmployees:
+		if v, ok := raw.(json.Number); ok {
+			return strconv.ParseUint(v.String(), 10, 64)
+		}
+	case dmodels.CompanySortRegistered:
+		if v, ok := raw.(bool); ok {
+			return v, nil
+		}
+	case dmodels.CompanySortCreatedAt:
+		if v, ok := raw.(string); ok {
+			return time.Parse(time.RFC3339Nano, v)
+		}
+	}
+	return nil, fmt.Errorf("unexpected value for %s", field)
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+func TestCompanyCursor(t *testing.T) {
+	sorts := parseCompanySort([]string{"-employees_count", "name", "created_at"})
+	company := dmodels.CompanyShow{
+		ID:        uuid.NewV4(),
+		Name:      "Acme",
+		Employees: 42,
+		CreatedAt: time.Date(2023, 9, 1, 10, 30, 0, 123456000, time.UTC),
+	}
+
+	// checks that a cursor keeps typed sort values and direction
+	t.Run("it should decode encoded cursor", func(t *testing.T) {
+		cursor, err := decodeCursor(sorts, encodeCursor(sorts, company, true))
+		require.NoError(t, err)
+
+		assert.Equal(t, company.ID, cursor.ID)
+		assert.True(t, cursor.Backward)
+		assert.Equal(t, []interface{}{uint64(42), "Acme", company.CreatedAt}, cursor.Values)
+	})
+
+	// checks that a cursor cannot be reused with another sort order
+	t.Run("it should reject cursor for another sort", func(t *testing.T) {
+		other := parseCompanySort([]string{"name"})
+		_, err := decodeCursor(other, encodeCursor(sorts, company, false))
+		assert.True(t, errors.Is(err, local.ErrInvalidCursor))
+	})
+
+	// checks garbage input
+	t.Run("it should reject malformed cursor", func(t *testing.T) {
+		_, err := decodeCursor(sorts, "not a cursor")
+		assert.True(t, errors.Is(err, local.ErrInvalidCursor))
+	})
+}
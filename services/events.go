@@ -0,0 +1,69 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"xm-task/dao"
+)
+
+const (
+	createdCompaniesTopic  = "created-companies"
+	updatedCompaniesTopic  = "updated-companies"
+	deletedCompaniesTopic  = "deleted-companies"
+	restoredCompaniesTopic = "restored-companies"
+)
+
+// pendingEvents are the events of the changes made by mutate.
+type pendingEvents []pendingEvent
+
+type pendingEvent struct {
+	topic string
+	value interface{}
+}
+
+func (events *pendingEvents) add(topic string, value interface{}) {
+	*events = append(*events, pendingEvent{topic: topic, value: value})
+}
+
+// mutate runs fn in a transaction and queues the webhook deliveries and the live feed events of the events it adds
+// in the same one, so that they are recorded if and only if the changes are committed. The events are produced
+// to Kafka after the commit.
+func (s *ServiceFacade) mutate(fn func(tx dao.DAO, events *pendingEvents) error) error {
+	var events pendingEvents
+	err := s.dao.Transaction(func(tx dao.DAO) error {
+		if err := fn(tx, &events); err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := queueWebhooks(tx, event.topic, event.value); err != nil {
+				return err
+			}
+			if err := addCompanyEvent(tx, event.topic, event.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		s.produce(event.topic, event.value)
+	}
+	return nil
+}
+
+// produce publishes the event, the webhooks and the live feed are handled by mutate.
+func (s *ServiceFacade) produce(topic string, value interface{}) {
+	message, _ := json.Marshal(value)
+	err := s.kafka.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          message,
+	}, nil)
+	if err != nil {
+		log.Printf("Failed to produce message: %s\n", err)
+	}
+}
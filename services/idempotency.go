@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"xm-task/conf"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+// BeginIdempotentRequest takes the key for the request with the given fingerprint. A completed key
+// is returned for replaying, nil means that the caller holds the key and has to complete or release it.
+func (s *ServiceFacade) BeginIdempotentRequest(userID uuid.UUID, key, fingerprint string) (*dmodels.IdempotencyKey, error) {
+	window := s.cfg.Idempotency.Window
+	if window <= 0 {
+		window = conf.DefaultIdempotencyWindow
+	}
+	lockTimeout := s.cfg.Idempotency.LockTimeout
+	if lockTimeout <= 0 {
+		lockTimeout = conf.DefaultIdempotencyLockTimeout
+	}
+
+	stored, acquired, err := s.dao.AcquireIdempotencyKey(dmodels.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+	}, window, lockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("dao.AcquireIdempotencyKey: %v", err)
+	}
+	if acquired {
+		return nil, nil
+	}
+
+	if stored.Fingerprint != fingerprint {
+		return nil, local.ErrIdempotencyKeyReused
+	}
+	if !stored.Completed() {
+		return nil, local.ErrRequestInProgress
+	}
+
+	return &stored, nil
+}
+
+// CompleteIdempotentRequest stores the response for the retries of the request.
+func (s *ServiceFacade) CompleteIdempotentRequest(key dmodels.IdempotencyKey) error {
+	if key.Headers == nil {
+		key.Headers = dmodels.JSONObject{}
+	}
+	if err := s.dao.SaveIdempotencyResponse(key); err != nil {
+		return fmt.Errorf("dao.SaveIdempotencyResponse: %v", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotentRequest forgets a request which cannot be replayed, so that a retry is handled anew.
+func (s *ServiceFacade) ReleaseIdempotentRequest(userID uuid.UUID, key, fingerprint string) error {
+	if err := s.dao.ReleaseIdempotencyKey(userID, key, fingerprint); err != nil {
+		return fmt.Errorf("dao.ReleaseIdempotencyKey: %v", err)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys removes the keys which outlived the window.
+func (s *ServiceFacade) PurgeIdempotencyKeys() (int64, error) {
+	purged, err := s.dao.PurgeIdempotencyKeys()
+	if err != nil {
+		return 0, fmt.Errorf("dao.PurgeIdempotencyKeys: %v", err)
+	}
+	return purged, nil
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"xm-task/dmodels"
+	"xm-task/helpers/rbac"
+	"xm-task/smodels"
+)
+
+// CreateOrganization creates the organization with the user as its admin.
+func (s *ServiceFacade) CreateOrganization(org smodels.Organization, user dmodels.User) (dmodels.Organization, error) {
+	created, err := s.dao.CreateOrganization(dmodels.Organization{
+		ID:   uuid.NewV4(),
+		Name: org.Name,
+		Role: rbac.RoleAdmin,
+	}, user.ID)
+	if err != nil {
+		return dmodels.Organization{}, daoError("dao.CreateOrganization", err)
+	}
+
+	return created, nil
+}
+
+// ListOrganizations returns the organizations of the user along with the role within each.
+func (s *ServiceFacade) ListOrganizations(user dmodels.User) ([]dmodels.Organization, error) {
+	orgs, err := s.dao.ListUserOrganizations(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("dao.ListUserOrganizations: %v", err)
+	}
+
+	return orgs, nil
+}
@@ -1,10 +1,14 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"io"
 	"net/http"
+	"sync"
 	"xm-task/conf"
 	"xm-task/dao"
 	"xm-task/dmodels"
@@ -17,15 +21,104 @@ type (
 
 		SignInOrRegister(user smodels.User) (bool, error)
 		GetUserByEmail(email string) (dmodels.User, error)
+		GetMember(email string, orgID uuid.UUID) (dmodels.User, error)
+		ListMembers(orgID uuid.UUID) ([]dmodels.User, error)
+		SetMemberRole(email string, role string, user dmodels.User) (dmodels.User, error)
+		RemoveMember(email string, user dmodels.User) error
 
-		CreateCompany(company smodels.Company) (dmodels.Company, error)
-		UpdateCompany(company smodels.Company) (dmodels.Company, error)
-		GetCompanyByID(id string) (dmodels.CompanyShow, error)
-		DeleteCompanyByID(id string) error
+		BeginIdempotentRequest(userID uuid.UUID, key, fingerprint string) (*dmodels.IdempotencyKey, error)
+		CompleteIdempotentRequest(key dmodels.IdempotencyKey) error
+		ReleaseIdempotentRequest(userID uuid.UUID, key, fingerprint string) error
+		PurgeIdempotencyKeys() (int64, error)
 
-		CreateToken(email string) (smodels.TokenDetails, error)
+		CreateOrganization(org smodels.Organization, user dmodels.User) (dmodels.Organization, error)
+		ListOrganizations(user dmodels.User) ([]dmodels.Organization, error)
+
+		CreateCompany(company smodels.Company, allowDuplicates bool, user dmodels.User) (dmodels.CompanyShow, error)
+		UpdateCompany(company smodels.Company, version uint64, user dmodels.User) (dmodels.Company, error)
+		PatchCompany(id string, contentType string, patch []byte, version uint64, user dmodels.User) (dmodels.CompanyShow, error)
+		BatchCompanies(batch smodels.CompanyBatch, user dmodels.User) ([]dmodels.CompanyBatchResult, error)
+		ImportCompanies(r io.Reader, params smodels.CompanyImportParams, user dmodels.User,
+			report func(smodels.CompanyImportRow) error) (smodels.CompanyImportSummary, error)
+		ExportCompanies(w io.Writer, params smodels.CompanyExportParams) (smodels.CompanyExportSummary, error)
+		GetCompanyByID(id string, orgID uuid.UUID) (dmodels.CompanyShow, error)
+		GetCompanyBySlug(slug string, orgID uuid.UUID) (dmodels.CompanyShow, error)
+		ListCompanies(params smodels.CompanyListParams) (dmodels.CompanyPage, error)
+		SearchCompanies(params smodels.CompanySearchParams) ([]dmodels.CompanySearchResult, int64, error)
+		CompanyStats(params smodels.CompanyStatsParams) (dmodels.CompanyStats, error)
+		CompanyDuplicates(orgID uuid.UUID, threshold float64) ([]dmodels.CompanyDuplicateCluster, bool, error)
+		DeleteCompanyByID(id string, version uint64, user dmodels.User) error
+		RestoreCompanyByID(id string, user dmodels.User) (dmodels.CompanyShow, error)
+		TransferCompany(id string, email string, version uint64, user dmodels.User) (dmodels.CompanyShow, error)
+		PurgeTrash() (int64, error)
+
+		CompanyAncestors(id string, depth int, orgID uuid.UUID) ([]dmodels.CompanyNode, error)
+		CompanySubtree(id string, depth int, orgID uuid.UUID) ([]dmodels.CompanyNode, bool, error)
+		CompanyGroup(id string, orgID uuid.UUID) (dmodels.CompanyGroup, error)
+		SetCompanyParent(id string, parentID string, version uint64, user dmodels.User) (dmodels.CompanyShow, error)
+
+		ListCompanyContacts(companyID string, orgID uuid.UUID) ([]dmodels.CompanyContact, error)
+		GetCompanyContact(companyID, id string, orgID uuid.UUID) (dmodels.CompanyContact, error)
+		PrimaryCompanyContact(companyID string, orgID uuid.UUID) (*dmodels.CompanyContact, error)
+		CreateCompanyContact(companyID string, contact smodels.CompanyContact, user dmodels.User) (dmodels.CompanyContact, error)
+		UpdateCompanyContact(companyID, id string, contact smodels.CompanyContact, user dmodels.User) (dmodels.CompanyContact, error)
+		DeleteCompanyContact(companyID, id string, user dmodels.User) error
+
+		ListCompanyAddresses(companyID string, orgID uuid.UUID) ([]dmodels.CompanyAddress, error)
+		GetCompanyAddress(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAddress, error)
+		PrimaryCompanyAddress(companyID string, orgID uuid.UUID) (*dmodels.CompanyAddress, error)
+		CreateCompanyAddress(companyID string, address smodels.CompanyAddress, user dmodels.User) (dmodels.CompanyAddress, error)
+		UpdateCompanyAddress(companyID, id string, address smodels.CompanyAddress, user dmodels.User) (dmodels.CompanyAddress, error)
+		DeleteCompanyAddress(companyID, id string, user dmodels.User) error
+
+		ListCompanySchedules(companyID string, status string, orgID uuid.UUID) ([]dmodels.CompanySchedule, error)
+		GetCompanySchedule(companyID, id string, orgID uuid.UUID) (dmodels.CompanySchedule, error)
+		CreateCompanySchedule(companyID string, schedule smodels.CompanySchedule, user dmodels.User) (dmodels.CompanySchedule, error)
+		CancelCompanySchedule(companyID, id string, user dmodels.User) (dmodels.CompanySchedule, error)
+		ApplyDueSchedules() (int, int, error)
+
+		ListCompanyAttachments(companyID string, orgID uuid.UUID) ([]dmodels.CompanyAttachment, error)
+		GetCompanyAttachment(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAttachment, error)
+		OpenCompanyAttachment(companyID, id string, orgID uuid.UUID) (dmodels.CompanyAttachment, io.ReadCloser, error)
+		UploadCompanyAttachment(companyID, fileName string, file io.ReadSeeker, user dmodels.User) (dmodels.CompanyAttachment, error)
+		DeleteCompanyAttachment(companyID, id string, user dmodels.User) error
+
+		GetCompanyLogo(companyID string, orgID uuid.UUID) (dmodels.CompanyLogo, error)
+		OpenCompanyLogo(companyID, hash string, size int) (dmodels.CompanyLogo, io.ReadCloser, error)
+		UploadCompanyLogo(companyID string, file io.Reader, user dmodels.User) (dmodels.CompanyLogo, error)
+		DeleteCompanyLogo(companyID string, user dmodels.User) error
+
+		ListWebhooks(orgID uuid.UUID) ([]dmodels.Webhook, error)
+		GetWebhook(id string, orgID uuid.UUID) (dmodels.Webhook, error)
+		CreateWebhook(webhook smodels.Webhook, user dmodels.User) (dmodels.Webhook, error)
+		UpdateWebhook(id string, update smodels.WebhookUpdate, user dmodels.User) (dmodels.Webhook, error)
+		DeleteWebhook(id string, user dmodels.User) error
+		ListWebhookDeliveries(webhookID string, params smodels.WebhookDeliveryListParams, orgID uuid.UUID) ([]dmodels.WebhookDelivery, int64, error)
+		GetWebhookDelivery(webhookID, id string, orgID uuid.UUID) (dmodels.WebhookDelivery, error)
+		RedeliverWebhookDelivery(webhookID, id string, user dmodels.User) (dmodels.WebhookDelivery, error)
+		DeliverDueWebhooks() (int, int, error)
+		PurgeWebhookDeliveries() (int64, error)
+
+		SubscribeCompanyEvents(params smodels.CompanyFeedParams, orgID uuid.UUID) (*CompanyFeedSubscription, error)
+		UnsubscribeCompanyEvents(sub *CompanyFeedSubscription)
+		ListenCompanyEvents(ctx context.Context) error
+		TrimCompanyEvents() (int64, error)
+
+		ListCompanyRevisions(companyID string, orgID uuid.UUID) ([]dmodels.CompanyRevision, error)
+		GetCompanyRevision(companyID string, orgID uuid.UUID, revision uint64) (dmodels.CompanyRevision, error)
+		DiffCompanyRevisions(companyID string, orgID uuid.UUID, from, to uint64) (smodels.RevisionDiff, error)
+		RevertCompany(companyID string, revision uint64, user dmodels.User) (dmodels.CompanyShow, error)
+
+		ListCompanyTypes(withDeprecated bool) ([]dmodels.CompanyType, error)
+		CreateCompanyType(ct smodels.CompanyType) (dmodels.CompanyType, error)
+		UpdateCompanyType(id uint64, update smodels.CompanyTypeUpdate) (dmodels.CompanyType, error)
+		MergeCompanyTypes(id, into uint64, user dmodels.User) (dmodels.CompanyType, int, error)
+
+		CreateToken(email string, orgID uuid.UUID) (smodels.TokenDetails, error)
 		CreateAuth(email string, td smodels.TokenDetails) error
 		ExtractTokenMetadata(c *gin.Context) (smodels.AccessDetails, error)
+		CreateStreamToken(ad smodels.AccessDetails) (smodels.StreamToken, error)
+		ExtractStreamTokenMetadata(tokenString string) (smodels.AccessDetails, error)
 		Refresh(r *http.Request) (smodels.TokenDetails, error)
 		FetchAuth(authD smodels.AccessDetails) (string, error)
 		DeleteAuth(UUID ...string) error
@@ -35,6 +128,12 @@ type (
 		cfg   conf.Config
 		dao   dao.DAO
 		kafka *kafka.Producer
+		// webhooks sends the webhook deliveries, see newWebhookClient.
+		webhooks *http.Client
+		// feed holds the live feed subscriptions of the instance.
+		feed companyFeed
+		// schemas holds compiled attributes schemas of company types by their JSON.
+		schemas sync.Map
 	}
 )
 
@@ -47,8 +146,15 @@ func NewService(cfg conf.Config, dao dao.DAO) (*ServiceFacade, error) {
 	}
 
 	return &ServiceFacade{
-		cfg:   cfg,
-		dao:   dao,
-		kafka: p,
+		cfg:      cfg,
+		dao:      dao,
+		kafka:    p,
+		webhooks: newWebhookClient(cfg.Webhooks),
 	}, nil
 }
+
+// Close delivers pending events and closes the producer, it is used by short living commands.
+func (s *ServiceFacade) Close() {
+	s.kafka.Flush(5000)
+	s.kafka.Close()
+}
@@ -8,6 +8,8 @@ import (
 	"gorm.io/gorm"
 	"os"
 	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/helpers/rbac"
 	"xm-task/smodels"
 )
 
@@ -24,6 +26,9 @@ func (s *ServiceFacade) SignInOrRegister(user smodels.User) (bool, error) {
 				ID:       uuid.NewV4(),
 				Email:    user.Email,
 				Password: string(hashedPassword),
+
+				OrganizationID: dmodels.DefaultOrganizationID,
+				Role:           rbac.DefaultRole,
 			})
 			if err != nil {
 				return false, fmt.Errorf("dao.CreateUser: %v", err)
@@ -45,3 +50,58 @@ func (s *ServiceFacade) GetUserByEmail(email string) (dmodels.User, error) {
 
 	return user, err
 }
+
+// GetMember returns the user along with the role within the organization.
+func (s *ServiceFacade) GetMember(email string, orgID uuid.UUID) (dmodels.User, error) {
+	user, err := s.dao.GetMember(email, orgID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dmodels.User{}, fmt.Errorf("dao.GetMember: %w", local.ErrNotMember)
+	}
+	if err != nil {
+		return dmodels.User{}, fmt.Errorf("dao.GetMember: %v", err)
+	}
+
+	return user, nil
+}
+
+func (s *ServiceFacade) ListMembers(orgID uuid.UUID) ([]dmodels.User, error) {
+	users, err := s.dao.ListMembers(orgID)
+	if err != nil {
+		return nil, fmt.Errorf("dao.ListMembers: %v", err)
+	}
+
+	return users, nil
+}
+
+// SetMemberRole assigns the role within the organization of the given user, the user with
+// the email joins the organization if needed. Tokens minted with the former role are rejected
+// by AuthMiddleware, so the change applies on the next refresh.
+// Admins cannot change their own role, commands run without a user.
+func (s *ServiceFacade) SetMemberRole(email string, role string, user dmodels.User) (dmodels.User, error) {
+	if !rbac.ValidRole(role) {
+		return dmodels.User{}, fmt.Errorf("%w: unknown role %q", local.ErrValidation, role)
+	}
+	if email == user.Email {
+		return dmodels.User{}, fmt.Errorf("%w: cannot change own role", local.ErrValidation)
+	}
+
+	if err := s.dao.SetMemberRole(email, user.OrganizationID, role); err != nil {
+		return dmodels.User{}, daoError("dao.SetMemberRole", err)
+	}
+
+	return s.GetMember(email, user.OrganizationID)
+}
+
+// RemoveMember removes the user with the email from the organization of the given user,
+// the companies owned by the former member are kept.
+func (s *ServiceFacade) RemoveMember(email string, user dmodels.User) error {
+	if email == user.Email {
+		return fmt.Errorf("%w: cannot leave the organization", local.ErrValidation)
+	}
+
+	if err := s.dao.RemoveMember(email, user.OrganizationID); err != nil {
+		return daoError("dao.RemoveMember", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,457 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"xm-task/conf"
+	"xm-task/dao"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+	"xm-task/smodels"
+)
+
+const (
+	// WebhookSignatureHeader carries "t=<unix time>,v1=<signature>", the signature is the hex encoded
+	// HMAC-SHA256 of "<unix time>.<body>" keyed with the secret of the webhook.
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+
+	webhookSecretLength = 32
+	// webhookResponseLimit is how much of the responses is kept in the delivery log.
+	webhookResponseLimit = 1024
+	webhookDialTimeout   = time.Second * 10
+)
+
+// topicEvents maps the Kafka topics to the events delivered to webhooks and the live feed.
+var topicEvents = map[string]string{
+	createdCompaniesTopic:  smodels.EventCompanyCreated,
+	updatedCompaniesTopic:  smodels.EventCompanyUpdated,
+	deletedCompaniesTopic:  smodels.EventCompanyDeleted,
+	restoredCompaniesTopic: smodels.EventCompanyRestored,
+}
+
+func (s *ServiceFacade) ListWebhooks(orgID uuid.UUID) ([]dmodels.Webhook, error) {
+	webhooks, err := s.dao.ListWebhooks(orgID)
+	if err != nil {
+		return nil, fmt.Errorf("dao.ListWebhooks: %v", err)
+	}
+
+	return webhooks, nil
+}
+
+func (s *ServiceFacade) GetWebhook(id string, orgID uuid.UUID) (dmodels.Webhook, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return dmodels.Webhook{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	webhook, err := s.dao.GetWebhook(id, orgID)
+	if err != nil {
+		return dmodels.Webhook{}, daoError("dao.GetWebhook", err)
+	}
+
+	return webhook, nil
+}
+
+// CreateWebhook registers the endpoint for the events of the organization of the user.
+// The returned webhook holds the secret, it is not shown afterwards.
+func (s *ServiceFacade) CreateWebhook(webhook smodels.Webhook, user dmodels.User) (dmodels.Webhook, error) {
+	if err := s.checkWebhookURL(webhook.URL); err != nil {
+		return dmodels.Webhook{}, err
+	}
+
+	secret := make([]byte, webhookSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return dmodels.Webhook{}, fmt.Errorf("rand.Read: %v", err)
+	}
+
+	created, err := s.dao.CreateWebhook(dmodels.Webhook{
+		ID:             uuid.NewV4(),
+		OrganizationID: user.OrganizationID,
+		URL:            webhook.URL,
+		Events:         webhook.Events,
+		Secret:         hex.EncodeToString(secret),
+		Enabled:        true,
+		AuthorID:       uuid.NullUUID{UUID: user.ID, Valid: true},
+	})
+	if err != nil {
+		return dmodels.Webhook{}, daoError("dao.CreateWebhook", err)
+	}
+
+	return created, nil
+}
+
+// UpdateWebhook changes the webhook. Enabling a disabled webhook resets its failures, the deliveries
+// which were pending when it got disabled are sent again.
+func (s *ServiceFacade) UpdateWebhook(id string, update smodels.WebhookUpdate, user dmodels.User) (dmodels.Webhook, error) {
+	webhook, err := s.GetWebhook(id, user.OrganizationID)
+	if err != nil {
+		return dmodels.Webhook{}, err
+	}
+
+	if update.URL != nil {
+		if err := s.checkWebhookURL(*update.URL); err != nil {
+			return dmodels.Webhook{}, err
+		}
+		webhook.URL = *update.URL
+	}
+	if update.Events != nil {
+		webhook.Events = update.Events
+	}
+	if update.Enabled != nil && *update.Enabled != webhook.Enabled {
+		webhook.Enabled = *update.Enabled
+		webhook.Failures = 0
+		webhook.DisabledAt = nil
+		if !webhook.Enabled {
+			now := time.Now()
+			webhook.DisabledAt = &now
+		}
+	}
+
+	updated, err := s.dao.UpdateWebhook(webhook)
+	if err != nil {
+		return dmodels.Webhook{}, daoError("dao.UpdateWebhook", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteWebhook removes the webhook, its pending deliveries are dropped.
+func (s *ServiceFacade) DeleteWebhook(id string, user dmodels.User) error {
+	if _, err := uuid.FromString(id); err != nil {
+		return fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	if err := s.dao.DeleteWebhook(id, user.OrganizationID); err != nil {
+		return daoError("dao.DeleteWebhook", err)
+	}
+
+	return nil
+}
+
+func (s *ServiceFacade) ListWebhookDeliveries(webhookID string, params smodels.WebhookDeliveryListParams, orgID uuid.UUID) ([]dmodels.WebhookDelivery, int64, error) {
+	if _, err := s.GetWebhook(webhookID, orgID); err != nil {
+		return nil, 0, err
+	}
+
+	deliveries, total, err := s.dao.ListWebhookDeliveries(webhookID, params.Status, params.Limit, params.Offset, orgID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("dao.ListWebhookDeliveries: %v", err)
+	}
+
+	return deliveries, total, nil
+}
+
+func (s *ServiceFacade) GetWebhookDelivery(webhookID, id string, orgID uuid.UUID) (dmodels.WebhookDelivery, error) {
+	if _, err := uuid.FromString(webhookID); err != nil {
+		return dmodels.WebhookDelivery{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+	if _, err := uuid.FromString(id); err != nil {
+		return dmodels.WebhookDelivery{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	delivery, err := s.dao.GetWebhookDelivery(webhookID, id, orgID)
+	if err != nil {
+		return dmodels.WebhookDelivery{}, daoError("dao.GetWebhookDelivery", err)
+	}
+
+	return delivery, nil
+}
+
+// RedeliverWebhookDelivery queues the event of the delivery again as a new delivery with all the attempts.
+// Disabled webhooks have to be enabled first.
+func (s *ServiceFacade) RedeliverWebhookDelivery(webhookID, id string, user dmodels.User) (dmodels.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(webhookID, user.OrganizationID)
+	if err != nil {
+		return dmodels.WebhookDelivery{}, err
+	}
+	if !webhook.Enabled {
+		return dmodels.WebhookDelivery{}, fmt.Errorf("%w: webhook is disabled", local.ErrUnprocessable)
+	}
+	if _, err := uuid.FromString(id); err != nil {
+		return dmodels.WebhookDelivery{}, fmt.Errorf("uuid.FromString: %w", local.ErrNotFound)
+	}
+
+	delivery, err := s.dao.RedeliverWebhookDelivery(webhookID, id, user.OrganizationID)
+	if err != nil {
+		return dmodels.WebhookDelivery{}, daoError("dao.RedeliverWebhookDelivery", err)
+	}
+
+	return delivery, nil
+}
+
+// DeliverDueWebhooks sends a batch of due deliveries and returns how many succeeded and how many ran out
+// of attempts. Deliveries which could not be recorded stay claimed and are sent again once their claim
+// times out, the last such error is returned.
+func (s *ServiceFacade) DeliverDueWebhooks() (int, int, error) {
+	limit := s.cfg.Webhooks.BatchSize
+	if limit <= 0 {
+		limit = conf.DefaultWebhooksBatchSize
+	}
+	claimTimeout := s.cfg.Webhooks.ClaimTimeout
+	if claimTimeout <= 0 {
+		claimTimeout = conf.DefaultWebhooksClaimTimeout
+	}
+	concurrency := s.cfg.Webhooks.Concurrency
+	if concurrency <= 0 {
+		concurrency = conf.DefaultWebhooksConcurrency
+	}
+	disableAfter := s.cfg.Webhooks.DisableAfter
+	if disableAfter <= 0 {
+		disableAfter = conf.DefaultWebhooksDisableAfter
+	}
+
+	deliveries, err := s.dao.ClaimDueWebhookDeliveries(limit, claimTimeout)
+	if err != nil {
+		return 0, 0, fmt.Errorf("dao.ClaimDueWebhookDeliveries: %v", err)
+	}
+
+	var (
+		mu                sync.Mutex
+		wg                sync.WaitGroup
+		delivered, failed int
+		lastErr           error
+	)
+	slots := make(chan struct{}, concurrency)
+	for _, delivery := range deliveries {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(delivery dmodels.WebhookDelivery) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			attempt := s.deliverWebhook(delivery)
+			err := s.dao.RecordWebhookAttempt(attempt, disableAfter)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				lastErr = fmt.Errorf("dao.RecordWebhookAttempt: %v", err)
+			case attempt.Status == dmodels.DeliveryStatusSucceeded:
+				delivered++
+			case attempt.Status == dmodels.DeliveryStatusFailed:
+				failed++
+			}
+		}(delivery)
+	}
+	wg.Wait()
+
+	return delivered, failed, lastErr
+}
+
+// PurgeWebhookDeliveries removes the finished deliveries kept longer than Webhooks.Retention.
+func (s *ServiceFacade) PurgeWebhookDeliveries() (int64, error) {
+	retention := s.cfg.Webhooks.Retention
+	if retention <= 0 {
+		retention = conf.DefaultWebhooksRetention
+	}
+
+	purged, err := s.dao.PurgeWebhookDeliveries(time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("dao.PurgeWebhookDeliveries: %v", err)
+	}
+	return purged, nil
+}
+
+// deliverWebhook posts the payload of the delivery to its webhook. Responses other than 2xx,
+// redirects included, are failures. Failed deliveries are retried with exponential backoff
+// until they run out of attempts.
+func (s *ServiceFacade) deliverWebhook(delivery dmodels.WebhookDelivery) dmodels.WebhookAttempt {
+	attempt := dmodels.WebhookAttempt{
+		DeliveryID: delivery.ID,
+		WebhookID:  delivery.WebhookID,
+		Status:     dmodels.DeliveryStatusSucceeded,
+	}
+
+	err := s.postWebhook(delivery, &attempt)
+	if err == nil {
+		attempt.NextAttemptAt = time.Now()
+		return attempt
+	}
+	attempt.Error = err.Error()
+
+	maxAttempts := s.cfg.Webhooks.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = conf.DefaultWebhooksMaxAttempts
+	}
+	attempts := delivery.Attempts + 1
+	attempt.Status = dmodels.DeliveryStatusPending
+	attempt.NextAttemptAt = time.Now().Add(s.webhookBackoff(attempts))
+	if attempts >= maxAttempts {
+		attempt.Status = dmodels.DeliveryStatusFailed
+		attempt.NextAttemptAt = time.Now()
+	}
+	return attempt
+}
+
+// postWebhook sends the signed payload and records the response in the attempt.
+func (s *ServiceFacade) postWebhook(delivery dmodels.WebhookDelivery, attempt *dmodels.WebhookAttempt) error {
+	body, err := json.Marshal(delivery.Payload)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("http.NewRequest: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", conf.Service+"-webhooks")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(WebhookSignatureHeader, signWebhook(delivery.Secret, time.Now().Unix(), body))
+
+	resp, err := s.webhooks.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	attempt.ResponseStatus = resp.StatusCode
+	attempt.ResponseBody = strings.ReplaceAll(strings.ToValidUTF8(string(response), "\uFFFD"), "\x00", "")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// webhookBackoff returns the delay after the given number of failed attempts, it doubles with every attempt.
+func (s *ServiceFacade) webhookBackoff(attempts int) time.Duration {
+	backoff := s.cfg.Webhooks.RetryBackoff
+	if backoff <= 0 {
+		backoff = conf.DefaultWebhooksRetryBackoff
+	}
+	maxBackoff := s.cfg.Webhooks.MaxRetryBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = conf.DefaultWebhooksMaxRetryBackoff
+	}
+
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// queueWebhooks queues the event published to the topic for the webhooks of the organization of the company.
+func queueWebhooks(tx dao.DAO, topic string, value interface{}) error {
+	event, ok := topicEvents[topic]
+	if !ok {
+		return nil
+	}
+	company, err := eventCompany(value)
+	if err != nil {
+		return err
+	}
+
+	eventID := uuid.NewV4()
+	message, err := json.Marshal(smodels.WebhookEvent{
+		ID:        eventID.String(),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      value,
+	})
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %v", err)
+	}
+	var payload dmodels.JSONObject
+	if err := json.Unmarshal(message, &payload); err != nil {
+		return fmt.Errorf("json.Unmarshal: %v", err)
+	}
+
+	if _, err := tx.QueueWebhookDeliveries(company.OrganizationID, event, eventID, payload); err != nil {
+		return fmt.Errorf("dao.QueueWebhookDeliveries: %v", err)
+	}
+	return nil
+}
+
+// checkWebhookURL requires an absolute URL, using HTTPS unless Webhooks.AllowInsecure is set.
+func (s *ServiceFacade) checkWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: invalid url", local.ErrValidation)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%w: url should be an absolute URL", local.ErrValidation)
+	}
+	if u.Scheme != "https" && !s.cfg.Webhooks.AllowInsecure {
+		return fmt.Errorf("%w: url should be an absolute HTTPS URL", local.ErrValidation)
+	}
+	return nil
+}
+
+// signWebhook returns the value of WebhookSignatureHeader for the body sent at the time.
+func signWebhook(secret string, timestamp int64, body []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookClient does not follow redirects nor use proxies. Unless Webhooks.AllowInsecure is set,
+// it only connects to public addresses, so that webhooks cannot reach the internal network.
+func newWebhookClient(cfg conf.Webhooks) *http.Client {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = conf.DefaultWebhooksTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: webhookDialTimeout}
+	if !cfg.AllowInsecure {
+		dialer.Control = publicAddressOnly
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookDialTimeout,
+			IdleConnTimeout:     time.Minute,
+			ForceAttemptHTTP2:   true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicAddressOnly is checked with the resolved address of every connection.
+func publicAddressOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return fmt.Errorf("address %s is not public", host)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xm-task/conf"
+	"xm-task/dmodels"
+	local "xm-task/helpers/errors"
+)
+
+func TestWebhooks(t *testing.T) {
+	// checks that the retry delay doubles with every attempt and stops at the maximum
+	t.Run("it should back off exponentially", func(t *testing.T) {
+		s := &ServiceFacade{cfg: conf.Config{Webhooks: conf.Webhooks{RetryBackoff: time.Second, MaxRetryBackoff: time.Second * 5}}}
+		assert.Equal(t, time.Second, s.webhookBackoff(1))
+		assert.Equal(t, time.Second*2, s.webhookBackoff(2))
+		assert.Equal(t, time.Second*4, s.webhookBackoff(3))
+		assert.Equal(t, time.Second*5, s.webhookBackoff(4))
+		assert.Equal(t, time.Second*5, s.webhookBackoff(40))
+	})
+
+	// checks that deliveries are signed and that failed ones are retried until they run out of attempts
+	t.Run("it should sign and retry deliveries", func(t *testing.T) {
+		status := http.StatusOK
+		var signature string
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signature = r.Header.Get(WebhookSignatureHeader)
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte("ok\x00"))
+		}))
+		defer server.Close()
+
+		cfg := conf.Webhooks{MaxAttempts: 2, AllowInsecure: true}
+		s := &ServiceFacade{cfg: conf.Config{Webhooks: cfg}, webhooks: newWebhookClient(cfg)}
+		delivery := dmodels.WebhookDelivery{
+			ID:      uuid.NewV4(),
+			Event:   "company.created",
+			Payload: dmodels.JSONObject{"event": "company.created"},
+			URL:     server.URL,
+			Secret:  "secret",
+		}
+
+		attempt := s.deliverWebhook(delivery)
+		assert.Equal(t, dmodels.DeliveryStatusSucceeded, attempt.Status)
+		assert.Equal(t, http.StatusOK, attempt.ResponseStatus)
+		assert.Equal(t, "ok", attempt.ResponseBody)
+
+		parts := strings.SplitN(signature, ",", 2)
+		require.Len(t, parts, 2)
+		timestamp, err := strconv.ParseInt(strings.TrimPrefix(parts[0], "t="), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, signWebhook("secret", timestamp, body), signature)
+
+		status = http.StatusInternalServerError
+		attempt = s.deliverWebhook(delivery)
+		assert.Equal(t, dmodels.DeliveryStatusPending, attempt.Status)
+		assert.True(t, attempt.NextAttemptAt.After(time.Now()))
+		assert.NotEmpty(t, attempt.Error)
+
+		delivery.Attempts = 1
+		attempt = s.deliverWebhook(delivery)
+		assert.Equal(t, dmodels.DeliveryStatusFailed, attempt.Status)
+	})
+
+	// checks that webhooks cannot reach the internal network
+	t.Run("it should only connect to public addresses", func(t *testing.T) {
+		assert.Error(t, publicAddressOnly("tcp4", "127.0.0.1:80", nil))
+		assert.Error(t, publicAddressOnly("tcp4", "10.0.0.1:443", nil))
+		assert.Error(t, publicAddressOnly("tcp4", "169.254.169.254:80", nil))
+		assert.NoError(t, publicAddressOnly("tcp4", "93.184.216.34:443", nil))
+	})
+
+	// checks that webhook urls are absolute
+	t.Run("it should reject urls without a host", func(t *testing.T) {
+		s := &ServiceFacade{cfg: conf.Config{Webhooks: conf.Webhooks{AllowInsecure: true}}}
+		assert.NoError(t, s.checkWebhookURL("https://example.com/hooks"))
+		for _, raw := range []string{"https:///hooks", "https:example.com", "/hooks", "http://"} {
+			assert.ErrorIs(t, s.checkWebhookURL(raw), local.ErrValidation, raw)
+		}
+	})
+}
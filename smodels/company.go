@@ -1,21 +1,40 @@
 package smodels
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 )
 
 type Company struct {
-	ID          string `json:"id,omitempty"`
-	Name        string `json:"name"            binding:"required"`
-	Description string `json:"description"`
-	Employees   uint64 `json:"employees_count"`
-	Registered  bool   `json:"registered"`
-	Type        string `json:"type"            binding:"required"`
+	ID          string     `json:"id,omitempty"`
+	Slug        string     `json:"slug,omitempty"`
+	Name        string     `json:"name"                 binding:"required"`
+	Description string     `json:"description"`
+	Employees   uint64     `json:"employees_count"`
+	Registered  bool       `json:"registered"`
+	Type        string     `json:"type"                 binding:"required"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	OwnerID     string     `json:"owner_id,omitempty"`
+	ParentID    string     `json:"parent_id,omitempty"`
+	// Attributes are validated against the schema of the company type.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	// PrimaryContact and PrimaryAddress are only set when embedded on request.
+	PrimaryContact *CompanyContact `json:"primary_contact,omitempty"`
+	PrimaryAddress *CompanyAddress `json:"primary_address,omitempty"`
+	// LogoURL points to the original logo, thumbnails replace its last segment with their size.
+	LogoURL string `json:"logo_url,omitempty"`
 }
 
 func (c *Company) Validate() error {
 	c.ID = ""
+	c.Slug = ""
+	c.OwnerID = ""
+	c.ParentID = ""
+	c.PrimaryContact = nil
+	c.PrimaryAddress = nil
+	c.LogoURL = ""
 
 	c.Name = strings.Trim(c.Name, " ")
 	if len(c.Name) < 5 || len(c.Name) > 15 {
@@ -31,5 +50,267 @@ func (c *Company) Validate() error {
 		return fmt.Errorf("type should be specified")
 	}
 
+	if c.Attributes == nil {
+		c.Attributes = map[string]interface{}{}
+	}
+	if data, err := json.Marshal(c.Attributes); err != nil || len(data) > MaxAttributesSize {
+		return fmt.Errorf("too big attributes (should be less than %d bytes)", MaxAttributesSize)
+	}
+
+	return nil
+}
+
+// MaxAttributesSize limits the encoded attributes of a company.
+const MaxAttributesSize = 8 << 10
+
+const (
+	JSONContentType       = "application/json"
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+type CompanyTransfer struct {
+	OwnerEmail string `json:"owner_email" binding:"required"`
+}
+
+// OwnerMe filters companies owned by the signed in user.
+const OwnerMe = "me"
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var companySortFields = map[string]bool{
+	"name":            true,
+	"employees_count": true,
+	"registered":      true,
+	"type":            true,
+	"created_at":      true,
+}
+
+// CompanyFilterParams are the filters shared by every company listing.
+type CompanyFilterParams struct {
+	Type         string  `form:"type"`
+	Registered   *bool   `form:"registered"`
+	EmployeesMin *uint64 `form:"employees_min"`
+	EmployeesMax *uint64 `form:"employees_max"`
+	NamePrefix   string  `form:"name_prefix"`
+	// Owner can only be "me", it is resolved to OwnerID for signed in users.
+	Owner   string `form:"owner"`
+	OwnerID string `form:"-"`
+	// Attributes is a JSON object, companies whose attributes contain it are listed.
+	Attributes string `form:"attributes"`
+	// OrganizationID is the organization of the caller, listings never cross organizations.
+	OrganizationID string `form:"-"`
+	// ParentID lists the direct subsidiaries of the company.
+	ParentID string `form:"-"`
+	Trashed  bool   `form:"-"`
+}
+
+func (p *CompanyFilterParams) Validate() error {
+	if p.EmployeesMin != nil && p.EmployeesMax != nil && *p.EmployeesMin > *p.EmployeesMax {
+		return fmt.Errorf("employees_min should not exceed employees_max")
+	}
+
+	if p.Owner != "" && p.Owner != OwnerMe {
+		return fmt.Errorf("owner should be %q", OwnerMe)
+	}
+
+	if p.Attributes != "" {
+		if len(p.Attributes) > MaxAttributesSize {
+			return fmt.Errorf("too big attributes filter")
+		}
+		if _, err := p.AttributesFilter(); err != nil {
+			return fmt.Errorf("attributes should be a JSON object")
+		}
+	}
+
+	p.Type = strings.Trim(p.Type, " ")
+	p.NamePrefix = strings.Trim(p.NamePrefix, " ")
+	return nil
+}
+
+// AttributesFilter decodes the attributes filter, nil means no filter.
+func (p *CompanyFilterParams) AttributesFilter() (map[string]interface{}, error) {
+	if p.Attributes == "" {
+		return nil, nil
+	}
+	var attributes map[string]interface{}
+	if err := json.Unmarshal([]byte(p.Attributes), &attributes); err != nil {
+		return nil, err
+	}
+	if attributes == nil {
+		return nil, fmt.Errorf("attributes filter is null")
+	}
+	return attributes, nil
+}
+
+type CompanyListParams struct {
+	CompanyFilterParams
+	Limit  int    `form:"limit"`
+	Offset *int   `form:"offset"`
+	Cursor string `form:"cursor"`
+	Sort   string `form:"sort"`
+}
+
+func (p *CompanyListParams) Validate() error {
+	if p.Limit == 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit < 0 || p.Limit > MaxPageLimit {
+		return fmt.Errorf("limit should be between 1 and %d", MaxPageLimit)
+	}
+
+	if p.Offset != nil {
+		if *p.Offset < 0 {
+			return fmt.Errorf("offset should not be negative")
+		}
+		if p.Cursor != "" {
+			return fmt.Errorf("offset and cursor cannot be used together")
+		}
+	}
+
+	if err := p.CompanyFilterParams.Validate(); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, field := range p.SortFields() {
+		name := strings.TrimPrefix(field, "-")
+		if !companySortFields[name] {
+			return fmt.Errorf("cannot sort by %q", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate sort field %q", name)
+		}
+		seen[name] = true
+	}
+
 	return nil
 }
+
+// SortFields splits the sort parameter, a leading "-" means descending order.
+func (p *CompanyListParams) SortFields() []string {
+	fields := make([]string, 0)
+	for _, field := range strings.Split(p.Sort, ",") {
+		field = strings.TrimSpace(field)
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+type CompanyList struct {
+	Companies []Company `json:"companies"`
+	Total     int64     `json:"total"`
+	Limit     int       `json:"limit"`
+	Offset    *int      `json:"offset,omitempty"`
+	Links     Links     `json:"links"`
+}
+
+type Links struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+type CompanySearchParams struct {
+	CompanyFilterParams
+	Query  string `form:"q"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+func (p *CompanySearchParams) Validate() error {
+	p.Query = strings.TrimSpace(p.Query)
+	if p.Query == "" {
+		return fmt.Errorf("search query should be specified")
+	}
+	if len(p.Query) > 256 {
+		return fmt.Errorf("too long search query (should be less than 256)")
+	}
+
+	if p.Limit == 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit < 0 || p.Limit > MaxPageLimit {
+		return fmt.Errorf("limit should be between 1 and %d", MaxPageLimit)
+	}
+	if p.Offset < 0 {
+		return fmt.Errorf("offset should not be negative")
+	}
+
+	return p.CompanyFilterParams.Validate()
+}
+
+type CompanySearchResult struct {
+	Company
+	Rank       float64    `json:"rank"`
+	Highlights Highlights `json:"highlights"`
+}
+
+// Highlights hold matched fragments wrapped into <mark></mark> tags.
+type Highlights struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type CompanySearchList struct {
+	Results []CompanySearchResult `json:"results"`
+	Total   int64                 `json:"total"`
+	Limit   int                   `json:"limit"`
+	Offset  int                   `json:"offset"`
+	Links   Links                 `json:"links"`
+}
+
+const (
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "best_effort"
+
+	MaxBatchOperations = 500
+)
+
+type CompanyBatch struct {
+	Mode       string                  `json:"mode"`
+	Operations []CompanyBatchOperation `json:"operations" binding:"required"`
+}
+
+// CompanyBatchOperation holds the company for "create", a merge patch for "update"
+// and nothing for "delete". Version is optional and works like If-Match.
+type CompanyBatchOperation struct {
+	Op      string          `json:"op"`
+	ID      string          `json:"id,omitempty"`
+	Version uint64          `json:"version,omitempty"`
+	Company json.RawMessage `json:"company,omitempty"`
+}
+
+func (b *CompanyBatch) Validate() error {
+	if b.Mode == "" {
+		b.Mode = BatchModeAtomic
+	}
+	if b.Mode != BatchModeAtomic && b.Mode != BatchModeBestEffort {
+		return fmt.Errorf("mode should be %q or %q", BatchModeAtomic, BatchModeBestEffort)
+	}
+
+	if len(b.Operations) == 0 || len(b.Operations) > MaxBatchOperations {
+		return fmt.Errorf("batch should contain from 1 to %d operations", MaxBatchOperations)
+	}
+
+	return nil
+}
+
+type CompanyBatchResult struct {
+	Index   int    `json:"index"`
+	Op      string `json:"op"`
+	ID      string `json:"id,omitempty"`
+	Version uint64 `json:"version,omitempty"`
+	Status  int    `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+type CompanyBatchResponse struct {
+	Success bool                 `json:"success"`
+	Results []CompanyBatchResult `json:"results"`
+	Errors  []CompanyBatchResult `json:"errors"`
+}
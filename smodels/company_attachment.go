@@ -0,0 +1,35 @@
+package smodels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type CompanyAttachment struct {
+	ID          string `json:"id"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// Checksum is the hex encoded SHA-256 of the content.
+	Checksum    string    `json:"checksum"`
+	AuthorID    string    `json:"author_id,omitempty"`
+	AuthorEmail string    `json:"author_email,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AttachmentFileName strips the directories some clients send along with the name of an uploaded file.
+func AttachmentFileName(name string) (string, error) {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSpace(name)
+
+	if name == "" || name == "." || name == ".." || !utf8.ValidString(name) || utf8.RuneCountInString(name) > 255 ||
+		strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("incorrect file name")
+	}
+	return name, nil
+}
@@ -0,0 +1,131 @@
+package smodels
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const phoneRegex = `^\+?[0-9 ()./-]{3,30}$`
+
+type CompanyContact struct {
+	ID        string    `json:"id,omitempty"`
+	Name      string    `json:"name"     binding:"required"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Position  string    `json:"position"`
+	Primary   bool      `json:"primary"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (c *CompanyContact) Validate() error {
+	c.ID = ""
+
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" || utf8.RuneCountInString(c.Name) > 100 {
+		return fmt.Errorf("incorrect contact name")
+	}
+
+	c.Email = strings.TrimSpace(c.Email)
+	if c.Email != "" && (len(c.Email) > 100 || !regexp.MustCompile(emailRegex).MatchString(c.Email)) {
+		return fmt.Errorf("incorrect email")
+	}
+
+	c.Phone = strings.TrimSpace(c.Phone)
+	if c.Phone != "" && !regexp.MustCompile(phoneRegex).MatchString(c.Phone) {
+		return fmt.Errorf("incorrect phone")
+	}
+
+	c.Position = strings.TrimSpace(c.Position)
+	if utf8.RuneCountInString(c.Position) > 100 {
+		return fmt.Errorf("too long position (should be less than 100)")
+	}
+
+	return nil
+}
+
+type CompanyAddress struct {
+	ID         string `json:"id,omitempty"`
+	Line1      string `json:"line1"       binding:"required"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"        binding:"required"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+	// Country is an ISO 3166-1 alpha-2 code, e.g. "DE".
+	Country   string    `json:"country"     binding:"required"`
+	Primary   bool      `json:"primary"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (a *CompanyAddress) Validate() error {
+	a.ID = ""
+
+	fields := []struct {
+		name     string
+		value    *string
+		max      int
+		required bool
+	}{
+		{"line1", &a.Line1, 200, true},
+		{"line2", &a.Line2, 200, false},
+		{"city", &a.City, 100, true},
+		{"region", &a.Region, 100, false},
+		{"postal_code", &a.PostalCode, 20, false},
+	}
+	for _, field := range fields {
+		*field.value = strings.TrimSpace(*field.value)
+		if field.required && *field.value == "" {
+			return fmt.Errorf("%s should be specified", field.name)
+		}
+		if utf8.RuneCountInString(*field.value) > field.max {
+			return fmt.Errorf("too long %s (should be less than %d)", field.name, field.max)
+		}
+	}
+
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	if !ValidCountry(a.Country) {
+		return fmt.Errorf("incorrect country, should be an ISO 3166-1 alpha-2 code")
+	}
+
+	return nil
+}
+
+// Embeddings of a single company.
+const (
+	EmbedPrimaryContact = "primary_contact"
+	EmbedPrimaryAddress = "primary_address"
+)
+
+// CompanyEmbedParams list the related resources to embed in the company, comma separated.
+type CompanyEmbedParams struct {
+	Embed string `form:"embed"`
+
+	PrimaryContact bool `form:"-"`
+	PrimaryAddress bool `form:"-"`
+}
+
+func (p *CompanyEmbedParams) Validate() error {
+	if p.Embed == "" {
+		return nil
+	}
+	for _, embed := range strings.Split(p.Embed, ",") {
+		switch strings.TrimSpace(embed) {
+		case EmbedPrimaryContact:
+			p.PrimaryContact = true
+		case EmbedPrimaryAddress:
+			p.PrimaryAddress = true
+		default:
+			return fmt.Errorf("embed should be a list of %q and %q", EmbedPrimaryContact, EmbedPrimaryAddress)
+		}
+	}
+	return nil
+}
+
+// Any tells whether anything has to be embedded.
+func (p *CompanyEmbedParams) Any() bool {
+	return p.PrimaryContact || p.PrimaryAddress
+}
@@ -0,0 +1,38 @@
+package smodels
+
+import "fmt"
+
+// CompanyCreateParams confirm the creation of a company although companies with similar names exist.
+type CompanyCreateParams struct {
+	AllowDuplicates bool `form:"allow_duplicates"`
+}
+
+// CompanyDuplicatesParams override the configured similarity threshold of the report.
+type CompanyDuplicatesParams struct {
+	Threshold float64 `form:"threshold"`
+}
+
+func (p *CompanyDuplicatesParams) Validate() error {
+	if p.Threshold < 0 || p.Threshold > 1 {
+		return fmt.Errorf("threshold should be between 0 and 1")
+	}
+	return nil
+}
+
+type SimilarCompany struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Slug       string  `json:"slug"`
+	Similarity float64 `json:"similarity,omitempty"`
+}
+
+type CompanyDuplicateCluster struct {
+	Companies  []SimilarCompany `json:"companies"`
+	Similarity float64          `json:"similarity"`
+}
+
+type CompanyDuplicates struct {
+	Clusters []CompanyDuplicateCluster `json:"clusters"`
+	// Truncated tells that the report was built from a part of the similar pairs.
+	Truncated bool `json:"truncated"`
+}
@@ -0,0 +1,81 @@
+package smodels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatNDJSON  = "ndjson"
+	ExportFormatParquet = "parquet"
+)
+
+// ExportContentTypes maps accepted content types to export formats, the first one is the default.
+var ExportContentTypes = []string{"text/csv", "application/x-ndjson", "application/vnd.apache.parquet"}
+
+var exportFormats = map[string]string{
+	"text/csv":                       ExportFormatCSV,
+	"application/x-ndjson":           ExportFormatNDJSON,
+	"application/vnd.apache.parquet": ExportFormatParquet,
+}
+
+// ExportFormat returns the export format for a content type and the content type for a format.
+func ExportFormat(contentType string) string {
+	return exportFormats[contentType]
+}
+
+func ExportContentType(format string) string {
+	for contentType, f := range exportFormats {
+		if f == format {
+			return contentType
+		}
+	}
+	return ""
+}
+
+// CompanyExportParams export companies ordered by id. After is the id of the last company
+// received before, it is used to resume an interrupted export. Limit 0 exports everything.
+type CompanyExportParams struct {
+	CompanyFilterParams
+	Format string `form:"format"`
+	After  string `form:"after"`
+	Limit  int64  `form:"limit"`
+}
+
+func (p *CompanyExportParams) Validate() error {
+	p.Format = strings.ToLower(strings.TrimSpace(p.Format))
+	if ExportContentType(p.Format) == "" {
+		return fmt.Errorf("format should be %q, %q or %q", ExportFormatCSV, ExportFormatNDJSON, ExportFormatParquet)
+	}
+
+	if p.After != "" {
+		if _, err := uuid.FromString(p.After); err != nil {
+			return fmt.Errorf("after should be a company id")
+		}
+	}
+	if p.Limit < 0 {
+		return fmt.Errorf("limit should not be negative")
+	}
+
+	return p.CompanyFilterParams.Validate()
+}
+
+type CompanyExportRow struct {
+	ID          string    `json:"id"              parquet:"id"`
+	Name        string    `json:"name"            parquet:"name"`
+	Description string    `json:"description"     parquet:"description"`
+	Employees   uint64    `json:"employees_count" parquet:"employees_count"`
+	Registered  bool      `json:"registered"      parquet:"registered"`
+	Type        string    `json:"type"            parquet:"type"`
+	CreatedAt   time.Time `json:"created_at"      parquet:"created_at,timestamp(microsecond)"`
+}
+
+// CompanyExportSummary tells how many companies were written and the id of the last one.
+type CompanyExportSummary struct {
+	Exported int64
+	Last     string
+}
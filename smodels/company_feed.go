@@ -0,0 +1,57 @@
+package smodels
+
+import (
+	"fmt"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// EventFeedReset tells clients of the live feed that events were missed, they have to fetch the companies again.
+const EventFeedReset = "reset"
+
+const maxFeedFilters = 100
+
+// CompanyFeedParams filter the live feed by companies and company types, empty filters match every company.
+// LastEventID resumes the feed after the event, it is taken from the Last-Event-ID header when it is sent.
+type CompanyFeedParams struct {
+	CompanyIDs  []string `form:"company_id"`
+	Types       []string `form:"type"`
+	LastEventID int64    `form:"last_event_id"`
+}
+
+func (p *CompanyFeedParams) Validate() error {
+	if len(p.CompanyIDs) > maxFeedFilters || len(p.Types) > maxFeedFilters {
+		return fmt.Errorf("at most %d companies and types can be followed", maxFeedFilters)
+	}
+	for _, id := range p.CompanyIDs {
+		if _, err := uuid.FromString(id); err != nil {
+			return fmt.Errorf("incorrect company_id %q", id)
+		}
+	}
+	for _, name := range p.Types {
+		if name == "" {
+			return fmt.Errorf("type should not be empty")
+		}
+	}
+	if p.LastEventID < 0 {
+		return fmt.Errorf("last_event_id should not be negative")
+	}
+	return nil
+}
+
+// CompanyEvent notifies of a change of a company, clients fetch the company to see the change.
+type CompanyEvent struct {
+	ID        int64     `json:"id"`
+	Event     string    `json:"event"`
+	CompanyID string    `json:"company_id"`
+	Type      string    `json:"type,omitempty"`
+	Version   uint64    `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StreamToken opens the streams within Feed.TokenTTL, see CreateStreamToken.
+type StreamToken struct {
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expires_at"`
+}
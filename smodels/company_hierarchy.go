@@ -0,0 +1,52 @@
+package smodels
+
+import (
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// CompanyParent moves the company under another one, a null parent makes it a top level company.
+type CompanyParent struct {
+	ParentID *string `json:"parent_id"`
+}
+
+func (p *CompanyParent) Validate() error {
+	if p.ParentID == nil {
+		return nil
+	}
+	if _, err := uuid.FromString(*p.ParentID); err != nil {
+		return fmt.Errorf("incorrect parent_id")
+	}
+	return nil
+}
+
+// CompanyTreeParams limit how many levels a tree query goes, zero means the configured maximum.
+type CompanyTreeParams struct {
+	Depth int `form:"depth"`
+}
+
+func (p *CompanyTreeParams) Validate() error {
+	if p.Depth < 0 {
+		return fmt.Errorf("depth should not be negative")
+	}
+	return nil
+}
+
+type CompanyNode struct {
+	Company
+	Depth int `json:"depth"`
+}
+
+type CompanySubtree struct {
+	Companies []CompanyNode `json:"companies"`
+	// Truncated is set when the subtree has more companies than a single response holds.
+	Truncated bool `json:"truncated"`
+}
+
+type CompanyGroup struct {
+	ID        string `json:"id"`
+	Companies int64  `json:"companies_count"`
+	Employees uint64 `json:"employees_count"`
+	Depth     int    `json:"depth"`
+}
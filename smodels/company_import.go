@@ -0,0 +1,85 @@
+package smodels
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatXLSX = "xlsx"
+)
+
+const (
+	ImportRowValid   = "valid"
+	ImportRowInvalid = "invalid"
+	ImportRowCreated = "created"
+	ImportRowFailed  = "failed"
+)
+
+// ImportFields are the company fields which can be mapped to file columns. By default
+// a field is read from the column with the same header.
+var ImportFields = []string{"name", "description", "employees_count", "registered", "type", "attributes"}
+
+type CompanyImportParams struct {
+	Format string `form:"format"`
+	Sheet  string `form:"sheet"`
+	DryRun bool   `form:"dry_run"`
+	// Mapping maps a company field to the header of the column it is read from.
+	Mapping map[string]string `form:"-"`
+}
+
+func (p *CompanyImportParams) Validate() error {
+	p.Format = strings.ToLower(strings.TrimSpace(p.Format))
+	if p.Format != ImportFormatCSV && p.Format != ImportFormatXLSX {
+		return fmt.Errorf("format should be %q or %q", ImportFormatCSV, ImportFormatXLSX)
+	}
+	if p.Sheet != "" && p.Format != ImportFormatXLSX {
+		return fmt.Errorf("sheet can be specified for %q files only", ImportFormatXLSX)
+	}
+
+	for field, column := range p.Mapping {
+		known := false
+		for _, f := range ImportFields {
+			known = known || f == field
+		}
+		if !known {
+			return fmt.Errorf("cannot map unknown field %q", field)
+		}
+		if strings.TrimSpace(column) == "" {
+			return fmt.Errorf("column for field %q should be specified", field)
+		}
+	}
+
+	return nil
+}
+
+// ImportFormat guesses the file format by its name, it returns an empty string for unknown extensions.
+func ImportFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return ImportFormatCSV
+	case ".xlsx":
+		return ImportFormatXLSX
+	}
+	return ""
+}
+
+// CompanyImportRow is a report line, Row is the row number in the file counting the header.
+type CompanyImportRow struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	Name   string `json:"name,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type CompanyImportSummary struct {
+	DryRun  bool `json:"dry_run"`
+	Total   int  `json:"total"`
+	Valid   int  `json:"valid"`
+	Invalid int  `json:"invalid"`
+	Created int  `json:"created"`
+	Failed  int  `json:"failed"`
+}
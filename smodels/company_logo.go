@@ -0,0 +1,18 @@
+package smodels
+
+import "time"
+
+// LogoOriginal is the size segment of the URL of the original logo.
+const LogoOriginal = "original"
+
+type CompanyLogo struct {
+	// URL points to the original logo, Thumbnails map the sizes to the URLs of the thumbnails.
+	URL         string            `json:"url"`
+	Thumbnails  map[string]string `json:"thumbnails"`
+	ContentType string            `json:"content_type"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	Size        int64             `json:"size"`
+	AuthorID    string            `json:"author_id,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
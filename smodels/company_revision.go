@@ -0,0 +1,25 @@
+package smodels
+
+import "time"
+
+type CompanyRevision struct {
+	Revision     uint64    `json:"revision"`
+	Action       string    `json:"action"`
+	AuthorID     string    `json:"author_id,omitempty"`
+	AuthorEmail  string    `json:"author_email,omitempty"`
+	RevertedFrom *uint64   `json:"reverted_from,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	Company      Company   `json:"company"`
+}
+
+type RevisionDiff struct {
+	From    uint64        `json:"from"`
+	To      uint64        `json:"to"`
+	Changes []FieldChange `json:"changes"`
+}
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
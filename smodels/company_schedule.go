@@ -0,0 +1,81 @@
+package smodels
+
+import (
+	"fmt"
+	"time"
+)
+
+// SchedulableFields are the company fields scheduled changes may set.
+var SchedulableFields = map[string]bool{
+	"name":            true,
+	"description":     true,
+	"employees_count": true,
+	"registered":      true,
+	"type":            true,
+	"attributes":      true,
+}
+
+const (
+	SchedulePending   = "pending"
+	ScheduleApplied   = "applied"
+	ScheduleCancelled = "cancelled"
+	ScheduleFailed    = "failed"
+)
+
+// CompanySchedule is a change of the company which takes effect at EffectiveAt.
+// Changes is a JSON Merge Patch (RFC 7396) of the company, it is applied as the author of the schedule.
+type CompanySchedule struct {
+	ID          string                 `json:"id,omitempty"`
+	EffectiveAt time.Time              `json:"effective_at"           binding:"required"`
+	Changes     map[string]interface{} `json:"changes"                binding:"required"`
+	Fields      []string               `json:"fields,omitempty"`
+	Status      string                 `json:"status,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	AuthorID    string                 `json:"author_id,omitempty"`
+	AuthorEmail string                 `json:"author_email,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	FinishedAt  *time.Time             `json:"finished_at,omitempty"`
+}
+
+func (s *CompanySchedule) Validate() error {
+	s.ID = ""
+	s.Fields = nil
+	s.Status = ""
+	s.Error = ""
+	s.AuthorID = ""
+	s.AuthorEmail = ""
+	s.FinishedAt = nil
+
+	if !s.EffectiveAt.After(time.Now()) {
+		return fmt.Errorf("effective_at should be in the future")
+	}
+
+	if len(s.Changes) == 0 {
+		return fmt.Errorf("changes should not be empty")
+	}
+	for field := range s.Changes {
+		if !SchedulableFields[field] {
+			return fmt.Errorf("field %q cannot be scheduled", field)
+		}
+	}
+
+	return nil
+}
+
+type CompanyScheduleListParams struct {
+	Status string `form:"status"`
+}
+
+func (p *CompanyScheduleListParams) Validate() error {
+	switch p.Status {
+	case "", SchedulePending, ScheduleApplied, ScheduleCancelled, ScheduleFailed:
+		return nil
+	}
+	return fmt.Errorf("incorrect status")
+}
+
+// ScheduleConflict is a pending change which touches the same fields.
+type ScheduleConflict struct {
+	ID     string   `json:"id"`
+	Fields []string `json:"fields"`
+}
@@ -0,0 +1,100 @@
+package smodels
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultStatsWindows are used when no windows are requested.
+	DefaultStatsWindows = "24h,7d,30d"
+	MaxStatsWindows     = 5
+	MaxStatsWindow      = time.Hour * 24 * 366
+)
+
+// CompanyStatsParams aggregate the live companies matched by the filters. Windows is a comma separated
+// list of durations, like "12h" or "30d", the created and deleted companies are counted within each.
+type CompanyStatsParams struct {
+	CompanyFilterParams
+	Windows string `form:"windows"`
+
+	Durations []time.Duration `form:"-"`
+}
+
+func (p *CompanyStatsParams) Validate() error {
+	if p.Windows == "" {
+		p.Windows = DefaultStatsWindows
+	}
+
+	windows := strings.Split(p.Windows, ",")
+	if len(windows) > MaxStatsWindows {
+		return fmt.Errorf("at most %d windows can be requested", MaxStatsWindows)
+	}
+
+	p.Durations = make([]time.Duration, 0, len(windows))
+	for i, window := range windows {
+		window = strings.TrimSpace(window)
+		d, err := parseWindow(window)
+		if err != nil || d <= 0 || d > MaxStatsWindow {
+			return fmt.Errorf("incorrect window %q, should be a duration like 24h or 7d up to 366d", window)
+		}
+		windows[i] = window
+		p.Durations = append(p.Durations, d)
+	}
+	p.Windows = strings.Join(windows, ",")
+
+	return p.CompanyFilterParams.Validate()
+}
+
+// WindowNames returns the windows as requested, in the order of Durations.
+func (p *CompanyStatsParams) WindowNames() []string {
+	return strings.Split(p.Windows, ",")
+}
+
+// parseWindow accepts Go durations and whole days, which Go durations lack.
+func parseWindow(window string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(window, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * time.Hour * 24, nil
+	}
+	return time.ParseDuration(window)
+}
+
+type CompanyStats struct {
+	Total        int64                  `json:"total"`
+	ByType       []CompanyTypeCount     `json:"by_type"`
+	ByRegistered CompanyRegisteredCount `json:"by_registered"`
+	ByEmployees  []CompanyBucketCount   `json:"by_employees"`
+	Windows      []CompanyWindowCount   `json:"windows"`
+	// ComputedAt tells how old cached statistics are.
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+type CompanyTypeCount struct {
+	Type  string `json:"type"`
+	Count int64  `json:"count"`
+}
+
+type CompanyRegisteredCount struct {
+	Registered   int64 `json:"registered"`
+	Unregistered int64 `json:"unregistered"`
+}
+
+type CompanyBucketCount struct {
+	Bucket string  `json:"bucket"`
+	Min    uint64  `json:"min"`
+	Max    *uint64 `json:"max"`
+	Count  int64   `json:"count"`
+}
+
+type CompanyWindowCount struct {
+	Window  string    `json:"window"`
+	Since   time.Time `json:"since"`
+	Created int64     `json:"created"`
+	Deleted int64     `json:"deleted"`
+}
@@ -0,0 +1,74 @@
+package smodels
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type CompanyType struct {
+	ID           uint64     `json:"id,omitempty"`
+	Name         string     `json:"name"                    binding:"required"`
+	Deprecated   bool       `json:"deprecated"`
+	DeprecatedAt *time.Time `json:"deprecated_at,omitempty"`
+	Companies    *int64     `json:"companies,omitempty"`
+	// AttributesSchema is a JSON Schema (draft 2020-12) for the attributes of companies of the type.
+	AttributesSchema map[string]interface{} `json:"attributes_schema,omitempty"`
+}
+
+func (ct *CompanyType) Validate() error {
+	ct.ID = 0
+	return validateCompanyTypeName(&ct.Name)
+}
+
+// CompanyTypeUpdate renames and deprecates a type or replaces its attributes schema, omitted fields
+// are not changed. A null schema removes it.
+type CompanyTypeUpdate struct {
+	Name             *string         `json:"name"`
+	Deprecated       *bool           `json:"deprecated"`
+	AttributesSchema json.RawMessage `json:"attributes_schema"`
+}
+
+func (u *CompanyTypeUpdate) Validate() error {
+	if u.Name == nil && u.Deprecated == nil && u.AttributesSchema == nil {
+		return fmt.Errorf("name, deprecated or attributes_schema should be specified")
+	}
+	if u.AttributesSchema != nil && !bytes.Equal(u.AttributesSchema, []byte("null")) {
+		if _, err := u.Schema(); err != nil {
+			return fmt.Errorf("attributes_schema should be a JSON object")
+		}
+	}
+	if u.Name != nil {
+		return validateCompanyTypeName(u.Name)
+	}
+	return nil
+}
+
+// Schema decodes the attributes schema of the update, it is nil when the schema is removed.
+func (u *CompanyTypeUpdate) Schema() (map[string]interface{}, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(u.AttributesSchema, &schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// CompanyTypeMerge moves companies of a type to the type Into and removes the former.
+type CompanyTypeMerge struct {
+	Into uint64 `json:"into" binding:"required"`
+}
+
+type CompanyTypeMergeResult struct {
+	Type  CompanyType `json:"type"`
+	Moved int         `json:"moved"`
+}
+
+func validateCompanyTypeName(name *string) error {
+	*name = strings.Trim(*name, " ")
+	if len(*name) == 0 || len(*name) > 50 {
+		return fmt.Errorf("incorrect company type name")
+	}
+	return nil
+}
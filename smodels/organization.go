@@ -0,0 +1,23 @@
+package smodels
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Organization struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"             binding:"required"`
+	Role string `json:"role,omitempty"`
+	// Active marks the organization the access token was minted for.
+	Active bool `json:"active"`
+}
+
+func (o *Organization) Validate() error {
+	o.ID = ""
+	o.Name = strings.Trim(o.Name, " ")
+	if len(o.Name) == 0 || len(o.Name) > 100 {
+		return fmt.Errorf("incorrect organization name")
+	}
+	return nil
+}
@@ -7,11 +7,16 @@ type TokenDetails struct {
 	RefreshUuid  string
 	AtExpires    int64
 	RtExpires    int64
+
+	OrganizationID string
+	Role           string
 }
 
 type AccessDetails struct {
-	AccessUuid string
-	Email      string
+	AccessUuid     string
+	Email          string
+	OrganizationID string
+	Role           string
 }
 
 type TestTokenDetails struct {
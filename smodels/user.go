@@ -1,6 +1,11 @@
 package smodels
 
-import "regexp"
+import (
+	"fmt"
+	"regexp"
+
+	"xm-task/helpers/rbac"
+)
 
 const emailRegex = `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
 
@@ -13,3 +18,17 @@ func (u *User) Validate() bool {
 	regex := regexp.MustCompile(emailRegex)
 	return regex.MatchString(u.Email)
 }
+
+// UserRole is the user as listed to admins, only Role is read on assignments.
+type UserRole struct {
+	ID    string `json:"id,omitempty"`
+	Email string `json:"email,omitempty"`
+	Role  string `json:"role" binding:"required"`
+}
+
+func (u *UserRole) Validate() error {
+	if !rbac.ValidRole(u.Role) {
+		return fmt.Errorf("role should be one of %q, %q or %q", rbac.RoleAdmin, rbac.RoleEditor, rbac.RoleViewer)
+	}
+	return nil
+}
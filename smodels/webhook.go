@@ -0,0 +1,184 @@
+package smodels
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Company events, they are delivered to webhooks and match the Kafka topics.
+const (
+	EventCompanyCreated  = "company.created"
+	EventCompanyUpdated  = "company.updated"
+	EventCompanyDeleted  = "company.deleted"
+	EventCompanyRestored = "company.restored"
+)
+
+// WebhookEvents are the events webhooks can subscribe to.
+var WebhookEvents = map[string]bool{
+	EventCompanyCreated:  true,
+	EventCompanyUpdated:  true,
+	EventCompanyDeleted:  true,
+	EventCompanyRestored: true,
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+const maxWebhookURLLength = 2048
+
+type Webhook struct {
+	ID     string   `json:"id,omitempty"`
+	URL    string   `json:"url"                   binding:"required"`
+	Events []string `json:"events"                binding:"required"`
+	// Secret signs the deliveries, it is only returned when the webhook is created.
+	Secret  string `json:"secret,omitempty"`
+	Enabled bool   `json:"enabled"`
+	// Failures counts the consecutive failed attempts, DisabledAt is set once the webhook is disabled.
+	Failures   int        `json:"failures"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	AuthorID   string     `json:"author_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Validate checks the URL and the events, webhooks are created enabled.
+func (w *Webhook) Validate() error {
+	w.ID = ""
+	w.Secret = ""
+	w.Enabled = true
+	w.Failures = 0
+	w.DisabledAt = nil
+	w.AuthorID = ""
+
+	if err := validateWebhookURL(w.URL); err != nil {
+		return err
+	}
+	events, err := webhookEvents(w.Events)
+	if err != nil {
+		return err
+	}
+	w.Events = events
+
+	return nil
+}
+
+// WebhookUpdate changes the given fields of the webhook, enabling it resets its failures.
+type WebhookUpdate struct {
+	URL     *string  `json:"url"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+func (u *WebhookUpdate) Validate() error {
+	if u.URL == nil && u.Events == nil && u.Enabled == nil {
+		return fmt.Errorf("url, events or enabled should be specified")
+	}
+	if u.URL != nil {
+		if err := validateWebhookURL(*u.URL); err != nil {
+			return err
+		}
+	}
+	if u.Events != nil {
+		events, err := webhookEvents(u.Events)
+		if err != nil {
+			return err
+		}
+		u.Events = events
+	}
+	return nil
+}
+
+// validateWebhookURL checks the form of the URL, the service decides whether plain HTTP is allowed.
+func validateWebhookURL(raw string) error {
+	if len(raw) > maxWebhookURLLength {
+		return fmt.Errorf("url should be at most %d characters long", maxWebhookURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" || u.User != nil {
+		return fmt.Errorf("url should be an absolute HTTPS URL")
+	}
+	return nil
+}
+
+// webhookEvents returns the sorted events without duplicates.
+func webhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("events should not be empty")
+	}
+
+	seen := make(map[string]bool, len(events))
+	unique := make([]string, 0, len(events))
+	for _, event := range events {
+		if !WebhookEvents[event] {
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			unique = append(unique, event)
+		}
+	}
+	sort.Strings(unique)
+	return unique, nil
+}
+
+type WebhookDeliveryListParams struct {
+	Status string `form:"status"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+func (p *WebhookDeliveryListParams) Validate() error {
+	switch p.Status {
+	case "", DeliveryPending, DeliverySucceeded, DeliveryFailed:
+	default:
+		return fmt.Errorf("incorrect status")
+	}
+
+	if p.Limit == 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit < 0 || p.Limit > MaxPageLimit {
+		return fmt.Errorf("limit should be between 1 and %d", MaxPageLimit)
+	}
+	if p.Offset < 0 {
+		return fmt.Errorf("offset should not be negative")
+	}
+	return nil
+}
+
+// WebhookDelivery is an event queued for the webhook along with the outcome of its last attempt.
+// NextAttemptAt is only set for pending deliveries, Payload is only returned for a single delivery.
+type WebhookDelivery struct {
+	ID             string                 `json:"id"`
+	Event          string                 `json:"event"`
+	EventID        string                 `json:"event_id"`
+	Status         string                 `json:"status"`
+	Attempts       int                    `json:"attempts"`
+	NextAttemptAt  *time.Time             `json:"next_attempt_at,omitempty"`
+	ResponseStatus int                    `json:"response_status,omitempty"`
+	ResponseBody   string                 `json:"response_body,omitempty"`
+	Error          string                 `json:"error,omitempty"`
+	AttemptedAt    *time.Time             `json:"attempted_at,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	Payload        map[string]interface{} `json:"payload,omitempty"`
+}
+
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int64             `json:"total"`
+	Limit      int               `json:"limit"`
+	Offset     int               `json:"offset"`
+}
+
+// WebhookEvent is the body of a delivery, Data is the company as it is sent to the Kafka topic of the event.
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
@@ -0,0 +1,88 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"xm-task/conf"
+	"xm-task/log"
+	"xm-task/services"
+)
+
+// feedReconnectDelay is how long the listener waits before connecting again.
+const feedReconnectDelay = time.Second * 5
+
+// FeedListener receives the company events of every instance for the live feed streams of this one
+// and trims the events which are no longer replayed.
+type FeedListener struct {
+	services     services.Service
+	trimInterval time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+func NewFeedListener(cfg conf.Config, s services.Service) *FeedListener {
+	interval := cfg.Feed.TrimInterval
+	if interval <= 0 {
+		interval = conf.DefaultFeedTrimInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &FeedListener{
+		services:     s,
+		trimInterval: interval,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+func (l *FeedListener) Run() error {
+	go l.trim()
+
+	for {
+		err := l.services.ListenCompanyEvents(l.ctx)
+		if l.ctx.Err() != nil {
+			return nil
+		}
+		log.Error("[workers] FeedListener: ListenCompanyEvents", zap.Error(err))
+
+		select {
+		case <-time.After(feedReconnectDelay):
+		case <-l.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Stop closes the open streams as well, so that the API can shut down.
+func (l *FeedListener) Stop() error {
+	l.cancel()
+	return nil
+}
+
+func (l *FeedListener) Title() string {
+	return "Feed listener"
+}
+
+func (l *FeedListener) trim() {
+	ticker := time.NewTicker(l.trimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-l.ctx.Done():
+			return
+		}
+
+		trimmed, err := l.services.TrimCompanyEvents()
+		if err != nil {
+			log.Error("[workers] FeedListener: TrimCompanyEvents", zap.Error(err))
+			continue
+		}
+		if trimmed > 0 {
+			log.Info("[workers] FeedListener: events trimmed", zap.Int64("count", trimmed))
+		}
+	}
+}
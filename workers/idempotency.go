@@ -0,0 +1,65 @@
+package workers
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"xm-task/conf"
+	"xm-task/log"
+	"xm-task/services"
+)
+
+// IdempotencyPurger periodically removes the idempotency keys which outlived their window.
+type IdempotencyPurger struct {
+	services services.Service
+	interval time.Duration
+	stop     chan struct{}
+}
+
+func NewIdempotencyPurger(cfg conf.Config, s services.Service) *IdempotencyPurger {
+	interval := cfg.Idempotency.PurgeInterval
+	if interval <= 0 {
+		interval = conf.DefaultIdempotencyPurgeInterval
+	}
+
+	return &IdempotencyPurger{
+		services: s,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (p *IdempotencyPurger) Run() error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge()
+
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return nil
+		}
+	}
+}
+
+func (p *IdempotencyPurger) Stop() error {
+	close(p.stop)
+	return nil
+}
+
+func (p *IdempotencyPurger) Title() string {
+	return "Idempotency purger"
+}
+
+func (p *IdempotencyPurger) purge() {
+	purged, err := p.services.PurgeIdempotencyKeys()
+	if err != nil {
+		log.Error("[workers] IdempotencyPurger: PurgeIdempotencyKeys", zap.Error(err))
+		return
+	}
+	if purged > 0 {
+		log.Info("[workers] IdempotencyPurger: keys purged", zap.Int64("count", purged))
+	}
+}
@@ -0,0 +1,65 @@
+package workers
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"xm-task/conf"
+	"xm-task/log"
+	"xm-task/services"
+)
+
+// TrashPurger periodically removes soft deleted companies which outlived the retention period.
+type TrashPurger struct {
+	services services.Service
+	interval time.Duration
+	stop     chan struct{}
+}
+
+func NewTrashPurger(cfg conf.Config, s services.Service) *TrashPurger {
+	interval := cfg.Trash.PurgeInterval
+	if interval <= 0 {
+		interval = conf.DefaultTrashPurgeInterval
+	}
+
+	return &TrashPurger{
+		services: s,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (p *TrashPurger) Run() error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge()
+
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return nil
+		}
+	}
+}
+
+func (p *TrashPurger) Stop() error {
+	close(p.stop)
+	return nil
+}
+
+func (p *TrashPurger) Title() string {
+	return "Trash purger"
+}
+
+func (p *TrashPurger) purge() {
+	purged, err := p.services.PurgeTrash()
+	if err != nil {
+		log.Error("[workers] TrashPurger: PurgeTrash", zap.Error(err))
+		return
+	}
+	if purged > 0 {
+		log.Info("[workers] TrashPurger: companies purged", zap.Int64("count", purged))
+	}
+}
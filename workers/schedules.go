@@ -0,0 +1,65 @@
+package workers
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"xm-task/conf"
+	"xm-task/log"
+	"xm-task/services"
+)
+
+// ScheduleApplier periodically applies the scheduled company changes which took effect.
+// Several instances may run at once, every change is claimed by one of them.
+type ScheduleApplier struct {
+	services services.Service
+	interval time.Duration
+	stop     chan struct{}
+}
+
+func NewScheduleApplier(cfg conf.Config, s services.Service) *ScheduleApplier {
+	interval := cfg.Schedules.PollInterval
+	if interval <= 0 {
+		interval = conf.DefaultSchedulesPollInterval
+	}
+
+	return &ScheduleApplier{
+		services: s,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (a *ScheduleApplier) Run() error {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.apply()
+
+		select {
+		case <-ticker.C:
+		case <-a.stop:
+			return nil
+		}
+	}
+}
+
+func (a *ScheduleApplier) Stop() error {
+	close(a.stop)
+	return nil
+}
+
+func (a *ScheduleApplier) Title() string {
+	return "Schedule applier"
+}
+
+func (a *ScheduleApplier) apply() {
+	applied, failed, err := a.services.ApplyDueSchedules()
+	if err != nil {
+		log.Error("[workers] ScheduleApplier: ApplyDueSchedules", zap.Error(err))
+	}
+	if applied > 0 || failed > 0 {
+		log.Info("[workers] ScheduleApplier: scheduled changes applied", zap.Int("applied", applied), zap.Int("failed", failed))
+	}
+}
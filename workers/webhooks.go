@@ -0,0 +1,85 @@
+package workers
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"xm-task/conf"
+	"xm-task/log"
+	"xm-task/services"
+)
+
+// webhookPurgeInterval is how often the delivery log is cleaned up.
+const webhookPurgeInterval = time.Hour
+
+// WebhookDispatcher periodically sends the due webhook deliveries and removes the old ones.
+// Several instances may run at once, every delivery is claimed by one of them.
+type WebhookDispatcher struct {
+	services services.Service
+	interval time.Duration
+	purged   time.Time
+	stop     chan struct{}
+}
+
+func NewWebhookDispatcher(cfg conf.Config, s services.Service) *WebhookDispatcher {
+	interval := cfg.Webhooks.PollInterval
+	if interval <= 0 {
+		interval = conf.DefaultWebhooksPollInterval
+	}
+
+	return &WebhookDispatcher{
+		services: s,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (d *WebhookDispatcher) Run() error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.deliver()
+		if time.Since(d.purged) >= webhookPurgeInterval {
+			d.purge()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-d.stop:
+			return nil
+		}
+	}
+}
+
+func (d *WebhookDispatcher) Stop() error {
+	close(d.stop)
+	return nil
+}
+
+func (d *WebhookDispatcher) Title() string {
+	return "Webhook dispatcher"
+}
+
+func (d *WebhookDispatcher) deliver() {
+	delivered, failed, err := d.services.DeliverDueWebhooks()
+	if err != nil {
+		log.Error("[workers] WebhookDispatcher: DeliverDueWebhooks", zap.Error(err))
+	}
+	if delivered > 0 || failed > 0 {
+		log.Info("[workers] WebhookDispatcher: deliveries sent", zap.Int("delivered", delivered), zap.Int("failed", failed))
+	}
+}
+
+func (d *WebhookDispatcher) purge() {
+	d.purged = time.Now()
+
+	purged, err := d.services.PurgeWebhookDeliveries()
+	if err != nil {
+		log.Error("[workers] WebhookDispatcher: PurgeWebhookDeliveries", zap.Error(err))
+		return
+	}
+	if purged > 0 {
+		log.Info("[workers] WebhookDispatcher: deliveries purged", zap.Int64("count", purged))
+	}
+}